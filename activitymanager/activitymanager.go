@@ -1,8 +1,6 @@
 package activitymanager
 
 import (
-	"fmt"
-
 	"github.com/sephiroth74/go_adb_client/process"
 	"github.com/sephiroth74/go_adb_client/shell"
 	"github.com/sephiroth74/go_adb_client/types"
@@ -13,20 +11,25 @@ type ActivityManager struct {
 }
 
 func (a ActivityManager) Broadcast(intent *types.Intent) (process.OutputResult, error) {
-	cmd := a.Shell.NewCommand().WithArgs("am", "broadcast", intent.String())
+	cmd := a.Shell.NewCommand().WithArgs("am", "broadcast").AddArgs(intent.ShellArgs()...)
 	return process.SimpleOutput(cmd, a.Shell.Conn.Verbose)
 }
 
 func (a ActivityManager) Start(intent *types.Intent) (process.OutputResult, error) {
-	return process.SimpleOutput(a.Shell.NewCommand().WithArgs("am", "start", intent.String()), a.Shell.Conn.Verbose)
+	return process.SimpleOutput(a.Shell.NewCommand().WithArgs("am", "start").AddArgs(intent.ShellArgs()...), a.Shell.Conn.Verbose)
 }
 
 func (a ActivityManager) StartService(intent *types.Intent) (process.OutputResult, error) {
-	return process.SimpleOutput(a.Shell.NewCommand().WithArgs("am", "startservice", intent.String()), a.Shell.Conn.Verbose)
+	return process.SimpleOutput(a.Shell.NewCommand().WithArgs("am", "startservice").AddArgs(intent.ShellArgs()...), a.Shell.Conn.Verbose)
 }
 
 func (a ActivityManager) ForceStop(packageName string) error {
-	result, err := process.SimpleOutput(a.Shell.NewCommand().WithArgs(fmt.Sprintf("am force-stop %s", packageName)), a.Shell.Conn.Verbose)
+	return a.ForceStopWithUser(packageName, "")
+}
+
+func (a ActivityManager) ForceStopWithUser(packageName string, user types.UserId) error {
+	cmd := a.Shell.NewCommand().WithArgs("am", "force-stop").AddArgs(user.Args()...).AddArgs(packageName)
+	result, err := process.SimpleOutput(cmd, a.Shell.Conn.Verbose)
 	if err != nil {
 		return err
 	}
@@ -37,3 +40,8 @@ func (a ActivityManager) ForceStop(packageName string) error {
 
 	return nil
 }
+
+// execute runs the command and returns an error if it fails or its output reports an error
+func (a ActivityManager) execute(args ...string) (process.OutputResult, error) {
+	return process.CheckedOutput(a.Shell.NewCommand().WithArgs(args...), a.Shell.Conn.Verbose)
+}
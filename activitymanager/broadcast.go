@@ -0,0 +1,238 @@
+package activitymanager
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sephiroth74/go_adb_client/process"
+	"github.com/sephiroth74/go_adb_client/types"
+)
+
+var broadcastResultRegexp = regexp.MustCompile(`^Broadcast completed: result=(-?\d+)(.*)$`)
+
+// region BroadcastOptions
+
+type BroadcastOptions struct {
+	// --receiver-permission: require the receivers to hold the given permission
+	ReceiverPermission string
+	// --allow-background-activity-starts: allow the receivers to start activities from the background
+	AllowBackgroundActivityStarts bool
+	// --async: don't wait for the broadcast to complete, no result is returned
+	Async bool
+	// Component is the receiver the broadcast is sent to, it overrides the intent component
+	Component string
+}
+
+func (o *BroadcastOptions) args() []string {
+	var args []string
+	if o != nil {
+		if o.ReceiverPermission != "" {
+			args = append(args, "--receiver-permission", o.ReceiverPermission)
+		}
+		if o.AllowBackgroundActivityStarts {
+			args = append(args, "--allow-background-activity-starts")
+		}
+		if o.Async {
+			args = append(args, "--async")
+		}
+	}
+	return args
+}
+
+// endregion BroadcastOptions
+
+// region Bundle
+
+// Bundle is a decoded android Bundle. Since the bundle is parsed from its string representation the value types are
+// inferred: nil, bool, int64, float64, string, []any and Bundle
+type Bundle map[string]any
+
+// GetString returns the value as a string, regardless of its type
+func (b Bundle) GetString(key string) (string, bool) {
+	value, ok := b[key]
+	if !ok || value == nil {
+		return "", false
+	}
+	if s, ok := value.(string); ok {
+		return s, true
+	}
+	return fmt.Sprintf("%v", value), true
+}
+
+func (b Bundle) GetInt(key string) (int64, bool) {
+	value, ok := b[key].(int64)
+	return value, ok
+}
+
+func (b Bundle) GetBool(key string) (bool, bool) {
+	value, ok := b[key].(bool)
+	return value, ok
+}
+
+func (b Bundle) GetFloat(key string) (float64, bool) {
+	switch value := b[key].(type) {
+	case float64:
+		return value, true
+	case int64:
+		return float64(value), true
+	}
+	return 0, false
+}
+
+// ParseBundle parses the string representation of a bundle: Bundle[{key=value, ...}]
+func ParseBundle(text string) (Bundle, error) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "Bundle[") || !strings.HasSuffix(text, "]") {
+		return nil, fmt.Errorf("not a bundle: %s", text)
+	}
+	content := strings.TrimSuffix(strings.TrimPrefix(text, "Bundle["), "]")
+
+	// parcelled bundles are not unparcelled by toString (e.g. Bundle[mParcelledData.dataSize=120])
+	if !strings.HasPrefix(content, "{") || !strings.HasSuffix(content, "}") {
+		return nil, fmt.Errorf("bundle content not available: %s", text)
+	}
+	content = strings.TrimSuffix(strings.TrimPrefix(content, "{"), "}")
+
+	bundle := Bundle{}
+	for _, item := range splitTopLevel(content) {
+		key, value, found := strings.Cut(item, "=")
+		if !found {
+			continue
+		}
+		bundle[strings.TrimSpace(key)] = parseBundleValue(value)
+	}
+	return bundle, nil
+}
+
+func parseBundleValue(value string) any {
+	value = strings.TrimSpace(value)
+	switch {
+	case value == "null":
+		return nil
+	case value == "true" || value == "false":
+		return value == "true"
+	case strings.HasPrefix(value, "Bundle["):
+		if bundle, err := ParseBundle(value); err == nil {
+			return bundle
+		}
+		return value
+	case strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]"):
+		list := []any{}
+		for _, item := range splitTopLevel(value[1 : len(value)-1]) {
+			list = append(list, parseBundleValue(item))
+		}
+		return list
+	}
+
+	if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil && strings.ContainsAny(value, ".eE") {
+		return f
+	}
+	return value
+}
+
+// splitTopLevel splits the comma separated list, ignoring the commas nested in brackets
+func splitTopLevel(text string) []string {
+	var result []string
+	depth := 0
+	start := 0
+	for i, c := range text {
+		switch c {
+		case '[', '{', '(':
+			depth++
+		case ']', '}', ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				result = append(result, strings.TrimSpace(text[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	if last := strings.TrimSpace(text[start:]); last != "" {
+		result = append(result, last)
+	}
+	return result
+}
+
+// endregion Bundle
+
+// region BroadcastResult
+
+// BroadcastResult is the final result of an ordered broadcast, as set by the receivers
+type BroadcastResult struct {
+	Code int
+	Data string
+	// HasData is false if the result data is null
+	HasData bool
+	Extras  Bundle
+}
+
+func (r BroadcastResult) String() string {
+	return fmt.Sprintf("BroadcastResult{Code:%d, Data:%s, Extras:%v}", r.Code, r.Data, r.Extras)
+}
+
+// ParseBroadcastResult parses the output of "am broadcast":
+// Broadcast completed: result=<code>[, data="<data>"][, extras: Bundle[{...}]]
+func ParseBroadcastResult(data string) (*BroadcastResult, error) {
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "Error:") || strings.HasPrefix(line, "Exception occurred") {
+			return nil, errors.New(line)
+		}
+
+		m := broadcastResultRegexp.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+
+		result := &BroadcastResult{}
+		result.Code, _ = strconv.Atoi(m[1])
+		rest := m[2]
+
+		if index := strings.LastIndex(rest, ", extras: Bundle["); index >= 0 && strings.HasSuffix(rest, "]") {
+			// the extras are left empty if the bundle cannot be decoded
+			result.Extras, _ = ParseBundle(rest[index+len(", extras: "):])
+			rest = rest[:index]
+		}
+
+		if strings.HasPrefix(rest, `, data="`) && strings.HasSuffix(rest, `"`) {
+			result.Data = rest[len(`, data="`) : len(rest)-1]
+			result.HasData = true
+		}
+		return result, nil
+	}
+	return nil, fmt.Errorf("broadcast result not found: %s", data)
+}
+
+// endregion BroadcastResult
+
+// SendBroadcast sends the broadcast with "am broadcast" and waits for its result.
+// With the Async option nil is returned as result
+func (a ActivityManager) SendBroadcast(intent *types.Intent, options *BroadcastOptions) (*BroadcastResult, error) {
+	if options != nil && options.Component != "" {
+		copied := *intent
+		copied.Component = options.Component
+		intent = &copied
+	}
+
+	cmd := a.Shell.NewCommand().WithArgs("am", "broadcast").AddArgs(options.args()...).AddArgs(intent.ShellArgs()...)
+	result, err := process.SimpleOutput(cmd, a.Shell.Conn.Verbose)
+	if err != nil {
+		return nil, err
+	}
+
+	if !result.IsOk() {
+		return nil, result.NewError()
+	}
+
+	if options != nil && options.Async {
+		return nil, nil
+	}
+	return ParseBroadcastResult(result.Output() + "\n" + result.Error())
+}
@@ -0,0 +1,546 @@
+package activitymanager
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	memInfoProcessRegexp  = regexp.MustCompile(`^\*\* MEMINFO in pid (\d+) \[([^\]]+)\] \*\*`)
+	memInfoRowRegexp      = regexp.MustCompile(`^(.*?)\s+((?:-?\d+\s*)+)$`)
+	memInfoPairRegexp     = regexp.MustCompile(`([A-Za-z][A-Za-z ()]*?):\s+(\d+)`)
+	memSummaryValueRegexp = regexp.MustCompile(`\d+`)
+	smapsRegexp           = regexp.MustCompile(`^(\w+):\s+(\d+) kB`)
+)
+
+// region Kilobytes
+
+// Kilobytes is a memory size in kilobytes, the unit used by dumpsys meminfo and smaps
+type Kilobytes int64
+
+func (k Kilobytes) Bytes() int64 {
+	return int64(k) * 1024
+}
+
+func (k Kilobytes) String() string {
+	switch {
+	case k >= 1024*1024:
+		return fmt.Sprintf("%.2f GB", float64(k)/(1024*1024))
+	case k >= 1024:
+		return fmt.Sprintf("%.2f MB", float64(k)/1024)
+	}
+	return fmt.Sprintf("%d kB", int64(k))
+}
+
+// endregion Kilobytes
+
+// region AppMemInfo
+
+// MemInfoRow is a row of the "dumpsys meminfo <process>" table. The columns not reported by
+// the android version (e.g. Rss before android 10) are 0
+type MemInfoRow struct {
+	PssTotal     Kilobytes
+	PrivateDirty Kilobytes
+	PrivateClean Kilobytes
+	SwapPssDirty Kilobytes
+	RssTotal     Kilobytes
+	HeapSize     Kilobytes
+	HeapAlloc    Kilobytes
+	HeapFree     Kilobytes
+}
+
+// MemValue is a value of the app summary
+type MemValue struct {
+	Pss Kilobytes
+	Rss Kilobytes
+}
+
+// AppMemSummary is the "App Summary" section of "dumpsys meminfo <process>"
+type AppMemSummary struct {
+	JavaHeap     MemValue
+	NativeHeap   MemValue
+	Code         MemValue
+	Stack        MemValue
+	Graphics     MemValue
+	PrivateOther MemValue
+	System       MemValue
+	Unknown      MemValue
+	TotalPss     Kilobytes
+	TotalRss     Kilobytes
+	TotalSwapPss Kilobytes
+}
+
+// MemObjects is the "Objects" section of "dumpsys meminfo <process>"
+type MemObjects struct {
+	Views           int
+	ViewRootImpl    int
+	AppContexts     int
+	Activities      int
+	Assets          int
+	AssetManagers   int
+	LocalBinders    int
+	ProxyBinders    int
+	ParcelMemory    Kilobytes
+	ParcelCount     int
+	DeathRecipients int
+	WebViews        int
+}
+
+// AppMemInfo is the memory usage of a process, parsed from "dumpsys meminfo <process>"
+type AppMemInfo struct {
+	Pid         int
+	ProcessName string
+	// Rows are the rows of the table by name (e.g. Native Heap, Dalvik Heap, .so mmap)
+	Rows    map[string]MemInfoRow
+	Total   MemInfoRow
+	Summary AppMemSummary
+	Objects MemObjects
+}
+
+func (m AppMemInfo) String() string {
+	return fmt.Sprintf("AppMemInfo{Pid:%d, ProcessName:%s, Pss:%s, Rss:%s, PrivateDirty:%s, Swap:%s}",
+		m.Pid, m.ProcessName, m.Pss(), m.Rss(), m.Total.PrivateDirty, m.Swap())
+}
+
+// Pss returns the total pss of the process
+func (m AppMemInfo) Pss() Kilobytes {
+	if m.Summary.TotalPss > 0 {
+		return m.Summary.TotalPss
+	}
+	return m.Total.PssTotal
+}
+
+// Rss returns the total rss of the process, 0 if not reported
+func (m AppMemInfo) Rss() Kilobytes {
+	if m.Summary.TotalRss > 0 {
+		return m.Summary.TotalRss
+	}
+	return m.Total.RssTotal
+}
+
+// Swap returns the swapped pss of the process
+func (m AppMemInfo) Swap() Kilobytes {
+	if m.Summary.TotalSwapPss > 0 {
+		return m.Summary.TotalSwapPss
+	}
+	return m.Total.SwapPssDirty
+}
+
+// ParseAppMemInfo parses the output of "dumpsys meminfo <process|pid>", one AppMemInfo for each process found
+func ParseAppMemInfo(data string) ([]AppMemInfo, error) {
+	var result []AppMemInfo
+	var current *AppMemInfo
+	var columns []string
+	var header []string
+	section := ""
+	pssEnd, rssEnd := 0, 0
+
+	for _, line := range strings.Split(data, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		if m := memInfoProcessRegexp.FindStringSubmatch(trimmed); m != nil {
+			pid, _ := strconv.Atoi(m[1])
+			result = append(result, AppMemInfo{Pid: pid, ProcessName: m[2], Rows: map[string]MemInfoRow{}})
+			current = &result[len(result)-1]
+			columns, header, section = nil, nil, "table"
+			pssEnd, rssEnd = 0, 0
+			continue
+		}
+
+		if current == nil || trimmed == "" {
+			continue
+		}
+
+		switch trimmed {
+		case "App Summary", "Objects", "SQL", "DATABASES", "Asset Allocations":
+			section = trimmed
+			continue
+		}
+
+		switch section {
+		case "table":
+			if strings.HasPrefix(trimmed, "---") {
+				continue
+			}
+
+			// the column names are split on two lines, e.g. "Pss Private" and "Total Dirty"
+			if !memInfoRowRegexp.MatchString(trimmed) {
+				if header == nil {
+					header = strings.Fields(trimmed)
+				} else {
+					columns = nil
+					for i, name := range strings.Fields(trimmed) {
+						if i < len(header) {
+							columns = append(columns, header[i]+" "+name)
+						}
+					}
+				}
+				continue
+			}
+
+			m := memInfoRowRegexp.FindStringSubmatch(trimmed)
+			row := parseMemInfoRow(columns, strings.Fields(m[2]))
+			if m[1] == "TOTAL" {
+				current.Total = row
+			} else if m[1] != "" {
+				current.Rows[m[1]] = row
+			}
+
+		case "App Summary":
+			if strings.Contains(line, "Pss(KB)") {
+				pssEnd = strings.Index(line, "Pss(KB)") + len("Pss(KB)")
+				if index := strings.Index(line, "Rss(KB)"); index >= 0 {
+					rssEnd = index + len("Rss(KB)")
+				}
+				continue
+			}
+			if !strings.HasPrefix(trimmed, "---") {
+				parseMemSummary(&current.Summary, line, pssEnd, rssEnd)
+			}
+
+		case "Objects":
+			for _, pair := range memInfoPairRegexp.FindAllStringSubmatch(trimmed, -1) {
+				value, _ := strconv.Atoi(pair[2])
+				switch strings.TrimSpace(pair[1]) {
+				case "Views":
+					current.Objects.Views = value
+				case "ViewRootImpl":
+					current.Objects.ViewRootImpl = value
+				case "AppContexts":
+					current.Objects.AppContexts = value
+				case "Activities":
+					current.Objects.Activities = value
+				case "Assets":
+					current.Objects.Assets = value
+				case "AssetManagers":
+					current.Objects.AssetManagers = value
+				case "Local Binders":
+					current.Objects.LocalBinders = value
+				case "Proxy Binders":
+					current.Objects.ProxyBinders = value
+				case "Parcel memory":
+					current.Objects.ParcelMemory = Kilobytes(value)
+				case "Parcel count":
+					current.Objects.ParcelCount = value
+				case "Death Recipients":
+					current.Objects.DeathRecipients = value
+				case "WebViews":
+					current.Objects.WebViews = value
+				}
+			}
+		}
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("meminfo not found: %s", strings.TrimSpace(data))
+	}
+	return result, nil
+}
+
+func parseMemInfoRow(columns []string, values []string) MemInfoRow {
+	row := MemInfoRow{}
+	for i, text := range values {
+		if i >= len(columns) {
+			break
+		}
+
+		value, _ := strconv.ParseInt(text, 10, 64)
+		size := Kilobytes(value)
+		switch columns[i] {
+		case "Pss Total":
+			row.PssTotal = size
+		case "Private Dirty":
+			row.PrivateDirty = size
+		case "Private Clean":
+			row.PrivateClean = size
+		case "SwapPss Dirty", "Swapped Dirty":
+			row.SwapPssDirty = size
+		case "Rss Total":
+			row.RssTotal = size
+		case "Heap Size":
+			row.HeapSize = size
+		case "Heap Alloc":
+			row.HeapAlloc = size
+		case "Heap Free":
+			row.HeapFree = size
+		}
+	}
+	return row
+}
+
+func parseMemSummary(summary *AppMemSummary, line string, pssEnd int, rssEnd int) {
+	// the totals line has several values: TOTAL PSS: 1 TOTAL RSS: 2 TOTAL SWAP PSS: 3 (legacy: TOTAL: 1)
+	if strings.HasPrefix(strings.TrimSpace(line), "TOTAL") {
+		for _, pair := range memInfoPairRegexp.FindAllStringSubmatch(line, -1) {
+			value, _ := strconv.ParseInt(pair[2], 10, 64)
+			switch strings.TrimSpace(pair[1]) {
+			case "TOTAL PSS", "TOTAL":
+				summary.TotalPss = Kilobytes(value)
+			case "TOTAL RSS":
+				summary.TotalRss = Kilobytes(value)
+			case "TOTAL SWAP PSS", "TOTAL SWAP (KB)":
+				summary.TotalSwapPss = Kilobytes(value)
+			}
+		}
+		return
+	}
+
+	name, values, found := strings.Cut(line, ":")
+	if !found {
+		return
+	}
+
+	// the values are right aligned to the Pss(KB) and Rss(KB) columns, a row can report only one of them
+	value := MemValue{}
+	offset := len(name) + 1
+	for _, m := range memSummaryValueRegexp.FindAllStringIndex(values, -1) {
+		size, _ := strconv.ParseInt(values[m[0]:m[1]], 10, 64)
+		end := offset + m[1]
+		if rssEnd > 0 && abs(end-rssEnd) < abs(end-pssEnd) {
+			value.Rss = Kilobytes(size)
+		} else {
+			value.Pss = Kilobytes(size)
+		}
+	}
+
+	switch strings.TrimSpace(name) {
+	case "Java Heap":
+		summary.JavaHeap = value
+	case "Native Heap":
+		summary.NativeHeap = value
+	case "Code":
+		summary.Code = value
+	case "Stack":
+		summary.Stack = value
+	case "Graphics":
+		summary.Graphics = value
+	case "Private Other":
+		summary.PrivateOther = value
+	case "System":
+		summary.System = value
+	case "Unknown":
+		summary.Unknown = value
+	}
+}
+
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
+
+// endregion AppMemInfo
+
+// region Smaps
+
+// SmapsRollup is the memory usage of a process, parsed from /proc/<pid>/smaps_rollup
+// (or the sum of /proc/<pid>/smaps on older kernels)
+type SmapsRollup struct {
+	Rss          Kilobytes
+	Pss          Kilobytes
+	SharedClean  Kilobytes
+	SharedDirty  Kilobytes
+	PrivateClean Kilobytes
+	PrivateDirty Kilobytes
+	Swap         Kilobytes
+	SwapPss      Kilobytes
+}
+
+// ParseSmaps parses the content of /proc/<pid>/smaps_rollup or /proc/<pid>/smaps, the values of all the mappings are summed
+func ParseSmaps(data string) SmapsRollup {
+	rollup := SmapsRollup{}
+	for _, line := range strings.Split(data, "\n") {
+		m := smapsRegexp.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+
+		value, _ := strconv.ParseInt(m[2], 10, 64)
+		size := Kilobytes(value)
+		switch m[1] {
+		case "Rss":
+			rollup.Rss += size
+		case "Pss":
+			rollup.Pss += size
+		case "Shared_Clean":
+			rollup.SharedClean += size
+		case "Shared_Dirty":
+			rollup.SharedDirty += size
+		case "Private_Clean":
+			rollup.PrivateClean += size
+		case "Private_Dirty":
+			rollup.PrivateDirty += size
+		case "Swap":
+			rollup.Swap += size
+		case "SwapPss":
+			rollup.SwapPss += size
+		}
+	}
+	return rollup
+}
+
+// endregion Smaps
+
+// region MemSampler
+
+// MemSample is a sample of the memory usage of a process. Err is set if the sample failed (e.g. the process is not running)
+type MemSample struct {
+	Time    time.Time
+	MemInfo *AppMemInfo
+	Err     error
+}
+
+// MemSeries is a time series of memory samples
+type MemSeries []MemSample
+
+// Pss returns the pss of the successful samples
+func (s MemSeries) Pss() []Kilobytes {
+	var values []Kilobytes
+	for _, sample := range s {
+		if sample.MemInfo != nil {
+			values = append(values, sample.MemInfo.Pss())
+		}
+	}
+	return values
+}
+
+// PssTrend returns the growth of the pss per minute, computed with a linear regression of the successful samples.
+// A steady positive trend over a long run usually means a leak
+func (s MemSeries) PssTrend() Kilobytes {
+	var n, sumX, sumY, sumXY, sumXX float64
+	var start time.Time
+	for _, sample := range s {
+		if sample.MemInfo == nil {
+			continue
+		}
+		if n == 0 {
+			start = sample.Time
+		}
+		x := sample.Time.Sub(start).Minutes()
+		y := float64(sample.MemInfo.Pss())
+		n++
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+
+	denominator := n*sumXX - sumX*sumX
+	if n < 2 || denominator == 0 {
+		return 0
+	}
+	return Kilobytes((n*sumXY - sumX*sumY) / denominator)
+}
+
+// MemSampler publishes the memory samples of a process.
+// Samples is closed once the sampler is stopped
+type MemSampler struct {
+	Samples chan MemSample
+
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// Stop cancels the sampler and closes the Samples channel
+func (s *MemSampler) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.done)
+	})
+}
+
+// Collect reads the samples until the sampler is stopped or the given duration elapses, then stops the sampler
+func (s *MemSampler) Collect(duration time.Duration) MemSeries {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	defer s.Stop()
+
+	var series MemSeries
+	for {
+		select {
+		case sample, ok := <-s.Samples:
+			if !ok {
+				return series
+			}
+			series = append(series, sample)
+		case <-timer.C:
+			return series
+		}
+	}
+}
+
+// endregion MemSampler
+
+// GetAppMemInfo returns the memory usage of the given process (name or pid) with "dumpsys meminfo".
+// The first process found is returned
+func (a ActivityManager) GetAppMemInfo(process string) (*AppMemInfo, error) {
+	result, err := a.execute("dumpsys", "meminfo", process)
+	if err != nil {
+		return nil, err
+	}
+
+	infos, err := ParseAppMemInfo(result.Output())
+	if err != nil {
+		return nil, err
+	}
+	return &infos[0], nil
+}
+
+// GetSmapsRollup returns the memory usage of the given pid from /proc/<pid>/smaps_rollup,
+// falling back to /proc/<pid>/smaps. Reading the smaps of another app usually requires root
+func (a ActivityManager) GetSmapsRollup(pid int) (*SmapsRollup, error) {
+	var lastErr error
+	for _, name := range []string{"smaps_rollup", "smaps"} {
+		result, err := a.execute("cat", fmt.Sprintf("/proc/%d/%s", pid, name))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		rollup := ParseSmaps(result.Output())
+		return &rollup, nil
+	}
+	return nil, lastErr
+}
+
+// SampleMemInfo polls "dumpsys meminfo <process>" every interval (1 second if not set)
+// and publishes the samples. Call Stop on the returned sampler to cancel it
+func (a ActivityManager) SampleMemInfo(process string, interval time.Duration) *MemSampler {
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	sampler := &MemSampler{
+		Samples: make(chan MemSample),
+		done:    make(chan struct{}),
+	}
+
+	go func() {
+		defer close(sampler.Samples)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			sample := MemSample{Time: time.Now()}
+			sample.MemInfo, sample.Err = a.GetAppMemInfo(process)
+
+			select {
+			case <-sampler.done:
+				return
+			case sampler.Samples <- sample:
+			}
+
+			select {
+			case <-sampler.done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return sampler
+}
@@ -0,0 +1,468 @@
+package activitymanager
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sephiroth74/go_adb_client/types"
+)
+
+var (
+	processRecordRegexp = regexp.MustCompile(`ProcessRecord\{[0-9a-f]+ (\d+):([^/\s]+)/([^}\s]+)\}`)
+	appProcessRegexp    = regexp.MustCompile(`^\*\w+\* UID (\d+)`)
+	lruProcessRegexp    = regexp.MustCompile(`^(Proc|PERS)\s*#\s*\d+:\s+(.+?)\s+(\S)/(\S| )/(\S+)\s+.*?t:\s*\d+\s+(\d+):([^/\s]+)/(\S+)(?:\s+\(([^)]*)\))?`)
+	oomAdjRegexp        = regexp.MustCompile(`^oom(?: adj)?: .*\bcur=(-?\d+)`)
+	pkgListRegexp       = regexp.MustCompile(`^pkgList=\{([^}]*)\}`)
+	uidNameRegexp       = regexp.MustCompile(`^u(\d+)([ais])(\d+)$`)
+	serviceRecordRegexp = regexp.MustCompile(`^\* ServiceRecord\{[0-9a-f]+ u(\d+) ([^\s}]+)\}`)
+	serviceClientRegexp = regexp.MustCompile(`^\* Client AppBindRecord\{`)
+)
+
+// region ProcessState
+
+// ProcessState is the state of a process, as the short name reported by dumpsys (ActivityManager.PROCESS_STATE_*)
+type ProcessState string
+
+const (
+	ProcessStatePersistent             ProcessState = "PER"
+	ProcessStatePersistentUi           ProcessState = "PERU"
+	ProcessStateTop                    ProcessState = "TOP"
+	ProcessStateBoundTop               ProcessState = "BTOP"
+	ProcessStateForegroundService      ProcessState = "FGS"
+	ProcessStateBoundForegroundService ProcessState = "BFGS"
+	ProcessStateImportantForeground    ProcessState = "IMPF"
+	ProcessStateImportantBackground    ProcessState = "IMPB"
+	ProcessStateTransientBackground    ProcessState = "TRNB"
+	ProcessStateBackup                 ProcessState = "BKUP"
+	ProcessStateService                ProcessState = "SVC"
+	ProcessStateReceiver               ProcessState = "RCVR"
+	ProcessStateTopSleeping            ProcessState = "TPSL"
+	ProcessStateHeavyWeight            ProcessState = "HVY"
+	ProcessStateHome                   ProcessState = "HOME"
+	ProcessStateLastActivity           ProcessState = "LAST"
+	ProcessStateCachedActivity         ProcessState = "CAC"
+	ProcessStateCachedActivityClient   ProcessState = "CACC"
+	ProcessStateCachedRecent           ProcessState = "CRE"
+	ProcessStateCachedEmpty            ProcessState = "CEM"
+	ProcessStateNonExistent            ProcessState = "NONE"
+)
+
+// processStates are the process states, from the most to the least important
+var processStates = []ProcessState{
+	ProcessStatePersistent,
+	ProcessStatePersistentUi,
+	ProcessStateTop,
+	ProcessStateBoundTop,
+	ProcessStateForegroundService,
+	ProcessStateBoundForegroundService,
+	ProcessStateImportantForeground,
+	ProcessStateImportantBackground,
+	ProcessStateTransientBackground,
+	ProcessStateBackup,
+	ProcessStateService,
+	ProcessStateReceiver,
+	ProcessStateTopSleeping,
+	ProcessStateHeavyWeight,
+	ProcessStateHome,
+	ProcessStateLastActivity,
+	ProcessStateCachedActivity,
+	ProcessStateCachedActivityClient,
+	ProcessStateCachedRecent,
+	ProcessStateCachedEmpty,
+	ProcessStateNonExistent,
+}
+
+// rank returns the position of the state in processStates, -1 if unknown
+func (s ProcessState) rank() int {
+	for i, state := range processStates {
+		if state == s {
+			return i
+		}
+	}
+	return -1
+}
+
+// Importance returns the importance of the state, as computed by RunningAppProcessInfo.procStateToImportance
+func (s ProcessState) Importance() Importance {
+	rank := s.rank()
+	switch {
+	case rank < 0 || s == ProcessStateNonExistent:
+		return ImportanceGone
+	case rank >= ProcessStateHome.rank():
+		return ImportanceCached
+	case s == ProcessStateHeavyWeight:
+		return ImportanceCantSaveState
+	case rank >= ProcessStateTopSleeping.rank():
+		return ImportanceTopSleeping
+	case rank >= ProcessStateService.rank():
+		return ImportanceService
+	case rank >= ProcessStateTransientBackground.rank():
+		return ImportancePerceptible
+	case rank >= ProcessStateImportantForeground.rank():
+		return ImportanceVisible
+	case rank >= ProcessStateForegroundService.rank():
+		return ImportanceForegroundService
+	}
+	return ImportanceForeground
+}
+
+// endregion ProcessState
+
+// region Importance
+
+// Importance is the importance of a process (RunningAppProcessInfo.IMPORTANCE_*), lower is more important
+type Importance int
+
+const (
+	ImportanceForeground        Importance = 100
+	ImportanceForegroundService Importance = 125
+	ImportanceVisible           Importance = 200
+	ImportancePerceptible       Importance = 230
+	ImportanceService           Importance = 300
+	ImportanceTopSleeping       Importance = 325
+	ImportanceCantSaveState     Importance = 350
+	ImportanceCached            Importance = 400
+	ImportanceGone              Importance = 1000
+)
+
+func (i Importance) String() string {
+	switch i {
+	case ImportanceForeground:
+		return "foreground"
+	case ImportanceForegroundService:
+		return "foreground-service"
+	case ImportanceVisible:
+		return "visible"
+	case ImportancePerceptible:
+		return "perceptible"
+	case ImportanceService:
+		return "service"
+	case ImportanceTopSleeping:
+		return "top-sleeping"
+	case ImportanceCantSaveState:
+		return "cant-save-state"
+	case ImportanceCached:
+		return "cached"
+	case ImportanceGone:
+		return "gone"
+	}
+	return strconv.Itoa(int(i))
+}
+
+// endregion Importance
+
+// region ProcessInfo
+
+// ProcessInfo is a running app process, as reported by "dumpsys activity processes"
+type ProcessInfo struct {
+	Pid  int
+	Name string
+	Uid  int
+	// UserId is the user of the process, derived from the uid
+	UserId types.UserId
+	// Packages are the packages running in the process
+	Packages []string
+	State    ProcessState
+	// OomAdj is the current oom_adj value (0 if the process record is not reported),
+	// OomAdjLabel its label (e.g. fg, vis, prcp, cch+5)
+	OomAdj      int
+	OomAdjLabel string
+	// AdjReason is the reason of the oom_adj (e.g. top-activity, service)
+	AdjReason  string
+	SchedGroup string
+	Persistent bool
+}
+
+func (p ProcessInfo) String() string {
+	return fmt.Sprintf("ProcessInfo{Pid:%d, Name:%s, Uid:%d, State:%s, OomAdj:%d (%s), Importance:%s}",
+		p.Pid, p.Name, p.Uid, p.State, p.OomAdj, p.OomAdjLabel, p.Importance())
+}
+
+func (p ProcessInfo) Importance() Importance {
+	return p.State.Importance()
+}
+
+// HasPackage returns true if the given package runs in the process
+func (p ProcessInfo) HasPackage(packageName string) bool {
+	for _, name := range p.Packages {
+		if name == packageName {
+			return true
+		}
+	}
+	return p.Name == packageName || strings.HasPrefix(p.Name, packageName+":")
+}
+
+// ParseProcesses parses the output of "dumpsys activity processes". The processes are returned
+// in the order of the LRU list (sorted by oom_adj), the details are read from the process records
+func ParseProcesses(data string) []ProcessInfo {
+	var result []ProcessInfo
+	details := map[int]*ProcessInfo{}
+	var current *ProcessInfo
+
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+
+		if m := appProcessRegexp.FindStringSubmatch(line); m != nil {
+			current = nil
+			if p := processRecordRegexp.FindStringSubmatch(line); p != nil {
+				pid, _ := strconv.Atoi(p[1])
+				uid, _ := strconv.Atoi(m[1])
+				current = &ProcessInfo{Pid: pid, Name: p[2], Uid: uid, OomAdj: -10000}
+				details[pid] = current
+			}
+			continue
+		}
+
+		if m := lruProcessRegexp.FindStringSubmatch(line); m != nil {
+			current = nil
+			pid, _ := strconv.Atoi(m[6])
+			result = append(result, ProcessInfo{
+				Pid:         pid,
+				Name:        m[7],
+				Uid:         parseUidName(m[8]),
+				State:       ProcessState(m[5]),
+				OomAdjLabel: strings.ReplaceAll(m[2], " ", ""),
+				AdjReason:   m[9],
+				SchedGroup:  m[3],
+				Persistent:  m[1] == "PERS",
+			})
+			continue
+		}
+
+		if current == nil {
+			continue
+		}
+
+		if m := pkgListRegexp.FindStringSubmatch(line); m != nil {
+			for _, name := range strings.Split(m[1], ",") {
+				if name = strings.TrimSpace(name); name != "" {
+					current.Packages = append(current.Packages, name)
+				}
+			}
+		} else if m := oomAdjRegexp.FindStringSubmatch(line); m != nil {
+			current.OomAdj, _ = strconv.Atoi(m[1])
+		}
+	}
+
+	for i := range result {
+		p := &result[i]
+		if detail, ok := details[p.Pid]; ok {
+			p.Uid = detail.Uid
+			p.Packages = detail.Packages
+			if detail.OomAdj != -10000 {
+				p.OomAdj = detail.OomAdj
+			}
+		}
+		p.UserId = types.NewUserId(p.Uid / 100000)
+	}
+	return result
+}
+
+// parseUidName parses the uid as formatted by UserHandle.formatUid (e.g. u0a123, u10i5, 1000)
+func parseUidName(name string) int {
+	m := uidNameRegexp.FindStringSubmatch(name)
+	if m == nil {
+		uid, _ := strconv.Atoi(name)
+		return uid
+	}
+
+	user, _ := strconv.Atoi(m[1])
+	id, _ := strconv.Atoi(m[3])
+	switch m[2] {
+	case "a":
+		id += 10000
+	case "i":
+		id += 99000
+	}
+	return user*100000 + id
+}
+
+// endregion ProcessInfo
+
+// region ServiceInfo
+
+// ServiceClient is a process bound to a service
+type ServiceClient struct {
+	Pid         int
+	ProcessName string
+}
+
+// ServiceInfo is a running service, as reported by "dumpsys activity services"
+type ServiceInfo struct {
+	Component   string
+	UserId      types.UserId
+	PackageName string
+	ProcessName string
+	// Pid is the pid of the process hosting the service, 0 if the process is not running
+	Pid            int
+	Foreground     bool
+	StartRequested bool
+	// Clients are the processes bound to the service
+	Clients []ServiceClient
+}
+
+func (s ServiceInfo) String() string {
+	return fmt.Sprintf("ServiceInfo{Component:%s, Pid:%d, Foreground:%t, Clients:%d}", s.Component, s.Pid, s.Foreground, len(s.Clients))
+}
+
+// ParseServices parses the output of "dumpsys activity services"
+func ParseServices(data string) []ServiceInfo {
+	var result []ServiceInfo
+	var current *ServiceInfo
+
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+
+		if m := serviceRecordRegexp.FindStringSubmatch(line); m != nil {
+			result = append(result, ServiceInfo{Component: m[2], UserId: types.UserId(m[1])})
+			current = &result[len(result)-1]
+			continue
+		}
+
+		if current == nil {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "packageName="):
+			current.PackageName = strings.TrimPrefix(line, "packageName=")
+		case strings.HasPrefix(line, "processName="):
+			current.ProcessName = strings.TrimPrefix(line, "processName=")
+		case strings.HasPrefix(line, "app="):
+			if m := processRecordRegexp.FindStringSubmatch(line); m != nil {
+				current.Pid, _ = strconv.Atoi(m[1])
+			}
+		case strings.HasPrefix(line, "isForeground="):
+			current.Foreground = strings.HasPrefix(line, "isForeground=true")
+		case strings.HasPrefix(line, "startRequested="):
+			current.StartRequested = strings.HasPrefix(line, "startRequested=true")
+		case serviceClientRegexp.MatchString(line):
+			if m := processRecordRegexp.FindStringSubmatch(line); m != nil {
+				client := ServiceClient{ProcessName: m[2]}
+				client.Pid, _ = strconv.Atoi(m[1])
+				if !containsClient(current.Clients, client) {
+					current.Clients = append(current.Clients, client)
+				}
+			}
+		}
+	}
+	return result
+}
+
+func containsClient(clients []ServiceClient, client ServiceClient) bool {
+	for _, c := range clients {
+		if c == client {
+			return true
+		}
+	}
+	return false
+}
+
+// endregion ServiceInfo
+
+// GetRunningProcesses returns the running app processes, parsed from "dumpsys activity processes"
+func (a ActivityManager) GetRunningProcesses() ([]ProcessInfo, error) {
+	result, err := a.execute("dumpsys", "activity", "processes")
+	if err != nil {
+		return nil, err
+	}
+	return ParseProcesses(result.Output()), nil
+}
+
+// GetPackageProcesses returns the running processes of the given package
+func (a ActivityManager) GetPackageProcesses(packageName string) ([]ProcessInfo, error) {
+	processes, err := a.GetRunningProcesses()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []ProcessInfo
+	for _, p := range processes {
+		if p.HasPackage(packageName) {
+			result = append(result, p)
+		}
+	}
+	return result, nil
+}
+
+// GetRunningServices returns the running services, parsed from "dumpsys activity services".
+// If packageName is not empty only the services of the package are returned
+func (a ActivityManager) GetRunningServices(packageName string) ([]ServiceInfo, error) {
+	args := []string{"dumpsys", "activity", "services"}
+	if packageName != "" {
+		args = append(args, packageName)
+	}
+
+	result, err := a.execute(args...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseServices(result.Output()), nil
+}
+
+// GetPids returns the pids of the given process name with "pidof", an empty list if the process is not running
+func (a ActivityManager) GetPids(processName string) ([]int, error) {
+	result, err := a.execute("pidof", processName)
+	if err != nil {
+		// pidof exits with 1 when no process is found
+		if result.ExitCode == 1 && result.Output() == "" {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var pids []int
+	for _, field := range strings.Fields(result.Output()) {
+		pid, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected pidof output: %s", result.Output())
+		}
+		pids = append(pids, pid)
+	}
+	return pids, nil
+}
+
+// WaitForProcess waits until the given process is running and returns its pids.
+// An error is returned if the process is not started within the timeout
+func (a ActivityManager) WaitForProcess(processName string, timeout time.Duration) ([]int, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		pids, err := a.GetPids(processName)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(pids) > 0 {
+			return pids, nil
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("process %s not started after %s", processName, timeout)
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+}
+
+// WaitForProcessDeath waits until the given process is no longer running.
+// An error is returned if the process is still running after the timeout
+func (a ActivityManager) WaitForProcessDeath(processName string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		pids, err := a.GetPids(processName)
+		if err != nil {
+			return err
+		}
+
+		if len(pids) == 0 {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("process %s still running after %s (pids: %v)", processName, timeout, pids)
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+}
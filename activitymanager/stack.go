@@ -0,0 +1,494 @@
+package activitymanager
+
+import (
+	"fmt"
+	"image"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sephiroth74/go_adb_client/types"
+)
+
+var (
+	activityRecordRegexp = regexp.MustCompile(`ActivityRecord\{([0-9a-f]+) u(\d+) ([^\s}]+)(?: t(-?\d+))?`)
+	windowRegexp         = regexp.MustCompile(`Window\{([0-9a-f]+) u(\d+) ([^}]+)\}`)
+	displayRegexp        = regexp.MustCompile(`^Display #(\d+)`)
+	legacyStackRegexp    = regexp.MustCompile(`^Stack #(\d+):(.*)$`)
+	taskRegexp           = regexp.MustCompile(`^\* (Task|TaskRecord)\{[0-9a-f]+ #(\d+)(.*)\}$`)
+	histRegexp           = regexp.MustCompile(`^\*?\s*Hist\s+#\d+: ActivityRecord\{`)
+	resumedRegexp        = regexp.MustCompile(`^(?:m)?ResumedActivity: ActivityRecord\{`)
+	topResumedRegexp     = regexp.MustCompile(`topResumedActivity=ActivityRecord\{`)
+	rootTaskRegexp       = regexp.MustCompile(`^(?:Stack|RootTask) id=(\d+) bounds=(\S+) displayId=(\d+) userId=(\d+)`)
+	stackTaskRegexp      = regexp.MustCompile(`^taskId=(\d+): (\S+) bounds=(\S+) userId=(\d+) visible=(true|false)(?: topActivity=ComponentInfo\{([^}]+)\})?`)
+	boundsRegexp         = regexp.MustCompile(`^\[(-?\d+),(-?\d+)\]\[(-?\d+),(-?\d+)\]$`)
+	windowDisplayRegexp  = regexp.MustCompile(`mDisplayId=(\d+)`)
+	focusedDisplayRegexp = regexp.MustCompile(`m(?:Top)?FocusedDisplayId=(-?\d+)`)
+)
+
+// region ActivityRecord
+
+// ActivityRecord is an activity instance, as reported by dumpsys (ActivityRecord{token u0 com.example/.Main t12})
+type ActivityRecord struct {
+	Token     string
+	UserId    types.UserId
+	Component string
+	// TaskId is -1 if not reported
+	TaskId int
+}
+
+func (r ActivityRecord) String() string {
+	return fmt.Sprintf("ActivityRecord{Component:%s, TaskId:%d, UserId:%s}", r.Component, r.TaskId, r.UserId)
+}
+
+func (r ActivityRecord) PackageName() string {
+	packageName, _ := splitComponent(r.Component)
+	return packageName
+}
+
+// ClassName returns the fully qualified class name of the activity
+func (r ActivityRecord) ClassName() string {
+	_, className := splitComponent(r.Component)
+	return className
+}
+
+// Matches returns true if the activity is the given component. Both the short (com.example/.Main)
+// and the long form (com.example/com.example.Main) are accepted
+func (r ActivityRecord) Matches(component string) bool {
+	p1, c1 := splitComponent(r.Component)
+	p2, c2 := splitComponent(component)
+	return p1 == p2 && c1 == c2
+}
+
+// splitComponent returns the package and the fully qualified class name of the component
+func splitComponent(component string) (string, string) {
+	packageName, className, found := strings.Cut(component, "/")
+	if !found {
+		return component, ""
+	}
+	if strings.HasPrefix(className, ".") {
+		className = packageName + className
+	}
+	return packageName, className
+}
+
+// parseActivityRecord parses the first ActivityRecord{...} found in the text, nil if not found
+func parseActivityRecord(text string) *ActivityRecord {
+	m := activityRecordRegexp.FindStringSubmatch(text)
+	if m == nil {
+		return nil
+	}
+
+	record := &ActivityRecord{Token: m[1], UserId: types.UserId(m[2]), Component: m[3], TaskId: -1}
+	if m[4] != "" {
+		record.TaskId, _ = strconv.Atoi(m[4])
+	}
+	return record
+}
+
+// endregion ActivityRecord
+
+// region ActivityStack
+
+// Task is a task of the activity stack
+type Task struct {
+	Id        int
+	DisplayId int
+	// StackId is the id of the stack (root task) containing the task
+	StackId  int
+	Type     string
+	Mode     string
+	Affinity string
+	UserId   types.UserId
+	Visible  bool
+	// Activities are the activities of the task, from top to bottom
+	Activities []ActivityRecord
+}
+
+func (t Task) String() string {
+	return fmt.Sprintf("Task{Id:%d, DisplayId:%d, Type:%s, Affinity:%s, Activities:%d}", t.Id, t.DisplayId, t.Type, t.Affinity, len(t.Activities))
+}
+
+// TopActivity returns the top activity of the task, nil if the task is empty
+func (t Task) TopActivity() *ActivityRecord {
+	if len(t.Activities) == 0 {
+		return nil
+	}
+	return &t.Activities[0]
+}
+
+// ActivityStack is the activity stack parsed from "dumpsys activity activities"
+type ActivityStack struct {
+	// Tasks are the tasks containing activities, from top to bottom
+	Tasks           []Task
+	ResumedActivity *ActivityRecord
+}
+
+// FindTask returns the task with the given id, nil if not found
+func (s ActivityStack) FindTask(id int) *Task {
+	for i := range s.Tasks {
+		if s.Tasks[i].Id == id {
+			return &s.Tasks[i]
+		}
+	}
+	return nil
+}
+
+// FindActivity returns the top most instance of the given component, nil if not found
+func (s ActivityStack) FindActivity(component string) *ActivityRecord {
+	for i := range s.Tasks {
+		for j := range s.Tasks[i].Activities {
+			if s.Tasks[i].Activities[j].Matches(component) {
+				return &s.Tasks[i].Activities[j]
+			}
+		}
+	}
+	return nil
+}
+
+// ParseActivityStack parses the output of "dumpsys activity activities".
+// Both the Task{...} (android 10+) and the legacy Stack/TaskRecord{...} formats are supported
+func ParseActivityStack(data string) ActivityStack {
+	stack := ActivityStack{}
+	var task *Task
+	var topResumed *ActivityRecord
+	displayId := 0
+	stackId := -1
+	stackIndent := -1
+	stackType, stackMode := "", ""
+
+	for _, line := range strings.Split(data, "\n") {
+		trimmed := strings.TrimSpace(line)
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+
+		if m := displayRegexp.FindStringSubmatch(trimmed); m != nil {
+			displayId, _ = strconv.Atoi(m[1])
+			task, stackId, stackIndent = nil, -1, -1
+			continue
+		}
+
+		if m := legacyStackRegexp.FindStringSubmatch(trimmed); m != nil {
+			stackId, _ = strconv.Atoi(m[1])
+			attrs := parseAttributes(m[2])
+			stackType, stackMode = attrs["type"], attrs["mode"]
+			stackIndent = indent
+			task = nil
+			continue
+		}
+
+		if m := taskRegexp.FindStringSubmatch(trimmed); m != nil {
+			id, _ := strconv.Atoi(m[2])
+			attrs := parseAttributes(m[3])
+
+			if m[1] == "Task" && indent <= stackIndent {
+				stackId, stackIndent = -1, -1
+			}
+
+			// a root task without affinity contains the leaf tasks
+			if m[1] == "Task" && attrs["A"] == "" {
+				stackId, stackIndent = id, indent
+				stackType, stackMode = attrs["type"], attrs["mode"]
+				task = nil
+				continue
+			}
+
+			stack.Tasks = append(stack.Tasks, Task{
+				Id:        id,
+				DisplayId: displayId,
+				StackId:   id,
+				Type:      attrs["type"],
+				Mode:      attrs["mode"],
+				Affinity:  attrs["A"],
+				UserId:    types.UserId(attrs["U"]),
+				Visible:   attrs["visible"] == "true",
+			})
+			task = &stack.Tasks[len(stack.Tasks)-1]
+
+			// on android 10+ the affinity is prefixed by the uid (A=10123:com.example)
+			if _, affinity, found := strings.Cut(task.Affinity, ":"); found {
+				task.Affinity = affinity
+			}
+			if value, ok := attrs["StackId"]; ok {
+				task.StackId, _ = strconv.Atoi(value)
+			} else if stackId >= 0 {
+				task.StackId = stackId
+			}
+			if task.Type == "" {
+				task.Type = stackType
+			}
+			if task.Mode == "" {
+				task.Mode = stackMode
+			}
+			continue
+		}
+
+		if histRegexp.MatchString(trimmed) {
+			if record := parseActivityRecord(trimmed); record != nil && task != nil {
+				task.Activities = append(task.Activities, *record)
+				if record.TaskId == -1 {
+					task.Activities[len(task.Activities)-1].TaskId = task.Id
+				}
+			}
+			continue
+		}
+
+		if topResumed == nil && topResumedRegexp.MatchString(trimmed) {
+			topResumed = parseActivityRecord(trimmed)
+			continue
+		}
+
+		if stack.ResumedActivity == nil && resumedRegexp.MatchString(trimmed) {
+			stack.ResumedActivity = parseActivityRecord(trimmed)
+		}
+	}
+
+	if topResumed != nil {
+		stack.ResumedActivity = topResumed
+	}
+
+	// legacy format, the visibility is not reported
+	for i := range stack.Tasks {
+		if stack.ResumedActivity != nil && stack.Tasks[i].Id == stack.ResumedActivity.TaskId {
+			stack.Tasks[i].Visible = true
+		}
+	}
+
+	// empty tasks are not returned
+	tasks := stack.Tasks[:0]
+	for _, t := range stack.Tasks {
+		if len(t.Activities) > 0 {
+			tasks = append(tasks, t)
+		}
+	}
+	stack.Tasks = tasks
+	return stack
+}
+
+// parseAttributes parses the key=value attributes separated by spaces
+func parseAttributes(text string) map[string]string {
+	attrs := map[string]string{}
+	for _, field := range strings.Fields(text) {
+		if key, value, found := strings.Cut(field, "="); found {
+			attrs[key] = value
+		}
+	}
+	return attrs
+}
+
+// endregion ActivityStack
+
+// region RootTask
+
+// RootTask is a root task (stack) as reported by "am stack list"
+type RootTask struct {
+	Id        int
+	DisplayId int
+	UserId    types.UserId
+	Bounds    image.Rectangle
+	Tasks     []RootTaskEntry
+}
+
+// RootTaskEntry is a task of a root task, as reported by "am stack list"
+type RootTaskEntry struct {
+	Id int
+	// Component is the base activity of the task
+	Component   string
+	Bounds      image.Rectangle
+	UserId      types.UserId
+	Visible     bool
+	TopActivity string
+}
+
+func (t RootTask) String() string {
+	return fmt.Sprintf("RootTask{Id:%d, DisplayId:%d, Bounds:%s, Tasks:%d}", t.Id, t.DisplayId, t.Bounds, len(t.Tasks))
+}
+
+// ParseRootTasks parses the output of "am stack list"
+func ParseRootTasks(data string) []RootTask {
+	var result []RootTask
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+
+		if m := rootTaskRegexp.FindStringSubmatch(line); m != nil {
+			root := RootTask{UserId: types.UserId(m[4]), Bounds: parseBounds(m[2])}
+			root.Id, _ = strconv.Atoi(m[1])
+			root.DisplayId, _ = strconv.Atoi(m[3])
+			result = append(result, root)
+			continue
+		}
+
+		if m := stackTaskRegexp.FindStringSubmatch(line); m != nil && len(result) > 0 {
+			entry := RootTaskEntry{
+				Component:   m[2],
+				Bounds:      parseBounds(m[3]),
+				UserId:      types.UserId(m[4]),
+				Visible:     m[5] == "true",
+				TopActivity: m[6],
+			}
+			entry.Id, _ = strconv.Atoi(m[1])
+			result[len(result)-1].Tasks = append(result[len(result)-1].Tasks, entry)
+		}
+	}
+	return result
+}
+
+// parseBounds parses bounds in the form [left,top][right,bottom]
+func parseBounds(text string) image.Rectangle {
+	m := boundsRegexp.FindStringSubmatch(text)
+	if m == nil {
+		return image.Rectangle{}
+	}
+
+	var values [4]int
+	for i := range values {
+		values[i], _ = strconv.Atoi(m[i+1])
+	}
+	return image.Rect(values[0], values[1], values[2], values[3])
+}
+
+// endregion RootTask
+
+// region WindowFocus
+
+// WindowFocus is the focus of a display, as reported by "dumpsys window"
+type WindowFocus struct {
+	DisplayId int
+	// CurrentFocus is the title of the focused window, usually the activity component (e.g. com.example/com.example.Main)
+	CurrentFocus string
+	// FocusedApp is the focused activity, nil if not reported
+	FocusedApp *ActivityRecord
+}
+
+func (w WindowFocus) String() string {
+	return fmt.Sprintf("WindowFocus{DisplayId:%d, CurrentFocus:%s, FocusedApp:%v}", w.DisplayId, w.CurrentFocus, w.FocusedApp)
+}
+
+// ParseWindowFocus parses the focus of the focused display from the output of "dumpsys window".
+// Nil is returned if no focus is reported
+func ParseWindowFocus(data string) *WindowFocus {
+	var displays []WindowFocus
+	focusedDisplayId := -1
+	displayId := 0
+
+	get := func() *WindowFocus {
+		for i := range displays {
+			if displays[i].DisplayId == displayId {
+				return &displays[i]
+			}
+		}
+		displays = append(displays, WindowFocus{DisplayId: displayId})
+		return &displays[len(displays)-1]
+	}
+
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+
+		if m := focusedDisplayRegexp.FindStringSubmatch(line); m != nil {
+			focusedDisplayId, _ = strconv.Atoi(m[1])
+			continue
+		}
+
+		if strings.HasPrefix(line, "Display:") || strings.HasPrefix(line, "Display #") {
+			if m := windowDisplayRegexp.FindStringSubmatch(line); m != nil {
+				displayId, _ = strconv.Atoi(m[1])
+			}
+			continue
+		}
+
+		if strings.HasPrefix(line, "mCurrentFocus=") {
+			if m := windowRegexp.FindStringSubmatch(line); m != nil {
+				get().CurrentFocus = m[3]
+			}
+			continue
+		}
+
+		if strings.HasPrefix(line, "mFocusedApp=") {
+			if record := parseActivityRecord(line); record != nil {
+				get().FocusedApp = record
+			}
+		}
+	}
+
+	for i := range displays {
+		if displays[i].DisplayId == focusedDisplayId {
+			return &displays[i]
+		}
+	}
+	for i := range displays {
+		if displays[i].CurrentFocus != "" {
+			return &displays[i]
+		}
+	}
+	if len(displays) > 0 {
+		return &displays[0]
+	}
+	return nil
+}
+
+// endregion WindowFocus
+
+// GetActivityStack returns the activity stack, parsed from "dumpsys activity activities"
+func (a ActivityManager) GetActivityStack() (*ActivityStack, error) {
+	result, err := a.execute("dumpsys", "activity", "activities")
+	if err != nil {
+		return nil, err
+	}
+	stack := ParseActivityStack(result.Output())
+	return &stack, nil
+}
+
+// GetRootTasks returns the root tasks (stacks) with "am stack list"
+func (a ActivityManager) GetRootTasks() ([]RootTask, error) {
+	result, err := a.execute("am", "stack", "list")
+	if err != nil {
+		return nil, err
+	}
+	return ParseRootTasks(result.Output()), nil
+}
+
+// GetWindowFocus returns the focus of the focused display, parsed from "dumpsys window displays".
+// The legacy "dumpsys window windows" is used if the focus is not reported
+func (a ActivityManager) GetWindowFocus() (*WindowFocus, error) {
+	for _, section := range []string{"displays", "windows"} {
+		result, err := a.execute("dumpsys", "window", section)
+		if err != nil {
+			return nil, err
+		}
+
+		if focus := ParseWindowFocus(result.Output()); focus != nil && focus.CurrentFocus != "" {
+			return focus, nil
+		}
+	}
+	return nil, fmt.Errorf("window focus not found")
+}
+
+// GetResumedActivity returns the resumed activity, nil if none
+func (a ActivityManager) GetResumedActivity() (*ActivityRecord, error) {
+	stack, err := a.GetActivityStack()
+	if err != nil {
+		return nil, err
+	}
+	return stack.ResumedActivity, nil
+}
+
+// WaitForActivity waits until the given component is the resumed activity, polling the activity stack.
+// An error is returned if the activity is not resumed within the timeout
+func (a ActivityManager) WaitForActivity(component string, timeout time.Duration) (*ActivityRecord, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		resumed, err := a.GetResumedActivity()
+		if err != nil {
+			return nil, err
+		}
+
+		if resumed != nil && resumed.Matches(component) {
+			return resumed, nil
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("activity %s not resumed after %s (resumed: %v)", component, timeout, resumed)
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+}
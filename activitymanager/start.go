@@ -0,0 +1,280 @@
+package activitymanager
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sephiroth74/go_adb_client/process"
+	"github.com/sephiroth74/go_adb_client/types"
+)
+
+// region LaunchState
+
+// LaunchState is the kind of launch reported by "am start -W"
+type LaunchState string
+
+const (
+	LaunchStateUnknown  LaunchState = "UNKNOWN"
+	LaunchStateCold     LaunchState = "COLD"
+	LaunchStateWarm     LaunchState = "WARM"
+	LaunchStateHot      LaunchState = "HOT"
+	LaunchStateRelaunch LaunchState = "RELAUNCH"
+)
+
+// endregion LaunchState
+
+// region ActivityFlag
+
+// ActivityFlag is one of the --activity-* options of "am start"
+type ActivityFlag string
+
+const (
+	ActivityBroughtToFront      ActivityFlag = "--activity-brought-to-front"
+	ActivityClearTop            ActivityFlag = "--activity-clear-top"
+	ActivityClearWhenTaskReset  ActivityFlag = "--activity-clear-when-task-reset"
+	ActivityExcludeFromRecents  ActivityFlag = "--activity-exclude-from-recents"
+	ActivityLaunchedFromHistory ActivityFlag = "--activity-launched-from-history"
+	ActivityMultipleTask        ActivityFlag = "--activity-multiple-task"
+	ActivityNoAnimation         ActivityFlag = "--activity-no-animation"
+	ActivityNoHistory           ActivityFlag = "--activity-no-history"
+	ActivityNoUserAction        ActivityFlag = "--activity-no-user-action"
+	ActivityPreviousIsTop       ActivityFlag = "--activity-previous-is-top"
+	ActivityReorderToFront      ActivityFlag = "--activity-reorder-to-front"
+	ActivityResetTaskIfNeeded   ActivityFlag = "--activity-reset-task-if-needed"
+	ActivitySingleTop           ActivityFlag = "--activity-single-top"
+	ActivityClearTask           ActivityFlag = "--activity-clear-task"
+	ActivityTaskOnHome          ActivityFlag = "--activity-task-on-home"
+	ActivityMatchExternal       ActivityFlag = "--activity-match-external"
+)
+
+// endregion ActivityFlag
+
+// region StartOptions
+
+type StartOptions struct {
+	// -W: wait for launch to complete, required for the launch timing
+	Wait bool
+	// -S: force stop the target app before starting the activity
+	ForceStop bool
+	// -R: repeat the activity launch the given times. Prior to each repeat, the top activity will be finished
+	Repeat int
+	// -D: enable debugging
+	Debug bool
+	// -N: enable native debugging
+	NativeDebug bool
+	// --start-profiler: start the profiler and send the results to the given file
+	StartProfiler string
+	// -P: like --start-profiler, but profiling stops when the app goes idle
+	Profiler string
+	// --sampling: use sample profiling with the given interval (in microseconds) between samples
+	SamplingInterval int
+	// --streaming: stream the profiling output to the specified file
+	Streaming bool
+	// --attach-agent: attach the given agent before binding
+	AttachAgent string
+	// --windowingMode: the windowing mode to launch the activity into, ignored if 0
+	WindowingMode int
+	// --activityType: the activity type to launch the activity as, ignored if 0
+	ActivityType int
+	// --display: the display to launch the activity into, ignored if 0
+	Display int
+	// --task: the task to launch the activity into, ignored if 0
+	Task int
+	// --task-overlay: launch the activity as the task overlay, requires Task
+	TaskOverlay bool
+	// --lock-task: launch the activity in lock task mode
+	LockTask bool
+	// --activity-* flags
+	ActivityFlags []ActivityFlag
+}
+
+func (o *StartOptions) args() []string {
+	var args []string
+	if o == nil {
+		return args
+	}
+
+	if o.Wait {
+		args = append(args, "-W")
+	}
+	if o.ForceStop {
+		args = append(args, "-S")
+	}
+	if o.Repeat > 0 {
+		args = append(args, "-R", strconv.Itoa(o.Repeat))
+	}
+	if o.Debug {
+		args = append(args, "-D")
+	}
+	if o.NativeDebug {
+		args = append(args, "-N")
+	}
+	if o.StartProfiler != "" {
+		args = append(args, "--start-profiler", o.StartProfiler)
+	}
+	if o.Profiler != "" {
+		args = append(args, "-P", o.Profiler)
+	}
+	if o.SamplingInterval > 0 {
+		args = append(args, "--sampling", strconv.Itoa(o.SamplingInterval))
+	}
+	if o.Streaming {
+		args = append(args, "--streaming")
+	}
+	if o.AttachAgent != "" {
+		args = append(args, "--attach-agent", o.AttachAgent)
+	}
+	if o.WindowingMode > 0 {
+		args = append(args, "--windowingMode", strconv.Itoa(o.WindowingMode))
+	}
+	if o.ActivityType > 0 {
+		args = append(args, "--activityType", strconv.Itoa(o.ActivityType))
+	}
+	if o.Display > 0 {
+		args = append(args, "--display", strconv.Itoa(o.Display))
+	}
+	if o.Task > 0 {
+		args = append(args, "--task", strconv.Itoa(o.Task))
+	}
+	if o.TaskOverlay {
+		args = append(args, "--task-overlay")
+	}
+	if o.LockTask {
+		args = append(args, "--lock-task")
+	}
+	for _, flag := range o.ActivityFlags {
+		args = append(args, string(flag))
+	}
+	return args
+}
+
+// endregion StartOptions
+
+// region StartResult
+
+// StartResult is the result of an activity launch. The launch timing is only reported when waiting for the launch (-W)
+type StartResult struct {
+	Status      string
+	LaunchState LaunchState
+	Activity    string
+	TotalTime   time.Duration
+	WaitTime    time.Duration
+	// ThisTime is only reported by older android versions
+	ThisTime time.Duration
+	Warnings []string
+}
+
+func (r StartResult) String() string {
+	return fmt.Sprintf("StartResult{Status:%s, LaunchState:%s, Activity:%s, TotalTime:%s, WaitTime:%s, ThisTime:%s}",
+		r.Status, r.LaunchState, r.Activity, r.TotalTime, r.WaitTime, r.ThisTime)
+}
+
+// IsOk returns true if the launch status is ok (or not reported)
+func (r StartResult) IsOk() bool {
+	return r.Status == "" || r.Status == "ok"
+}
+
+// ParseStartResults parses the output of "am start", one result for each launch (see StartOptions.Repeat).
+// An error is returned if the output reports an error
+func ParseStartResults(data string) ([]StartResult, error) {
+	var results []StartResult
+	var current *StartResult
+
+	next := func() *StartResult {
+		results = append(results, StartResult{})
+		return &results[len(results)-1]
+	}
+
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "Starting:") {
+			current = next()
+			continue
+		}
+
+		if strings.HasPrefix(line, "Error:") || strings.HasPrefix(line, "Exception occurred") {
+			return results, errors.New(line)
+		}
+
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		value = strings.TrimSpace(value)
+
+		// the "Stopping:" line of -S, and any other line, before the first launch
+		if current == nil {
+			continue
+		}
+
+		switch key {
+		case "Status":
+			current.Status = value
+		case "LaunchState":
+			current.LaunchState = LaunchState(value)
+		case "Activity":
+			current.Activity = value
+		case "TotalTime":
+			current.TotalTime = parseMillis(value)
+		case "WaitTime":
+			current.WaitTime = parseMillis(value)
+		case "ThisTime":
+			current.ThisTime = parseMillis(value)
+		case "Warning":
+			current.Warnings = append(current.Warnings, value)
+		}
+	}
+	return results, nil
+}
+
+func parseMillis(value string) time.Duration {
+	ms, _ := strconv.ParseInt(value, 10, 64)
+	return time.Duration(ms) * time.Millisecond
+}
+
+// endregion StartResult
+
+// StartActivity starts the activity with "am start" and returns the result of the launch.
+// When repeating the launch, the last result is returned
+func (a ActivityManager) StartActivity(intent *types.Intent, options *StartOptions) (*StartResult, error) {
+	results, err := a.StartActivityRepeat(intent, options)
+	if err != nil {
+		return nil, err
+	}
+	return &results[len(results)-1], nil
+}
+
+// StartActivityRepeat starts the activity with "am start" and returns the results of all the launches (see StartOptions.Repeat)
+func (a ActivityManager) StartActivityRepeat(intent *types.Intent, options *StartOptions) ([]StartResult, error) {
+	cmd := a.Shell.NewCommand().WithArgs("am", "start").AddArgs(options.args()...).AddArgs(intent.ShellArgs()...)
+	result, err := process.SimpleOutput(cmd, a.Shell.Conn.Verbose)
+	if err != nil {
+		return nil, err
+	}
+
+	if !result.IsOk() {
+		return nil, result.NewError()
+	}
+
+	results, err := ParseStartResults(result.Output() + "\n" + result.Error())
+	if err != nil {
+		return nil, err
+	}
+
+	if len(results) == 0 {
+		return nil, fmt.Errorf("unexpected output: %s", result.Output())
+	}
+
+	last := results[len(results)-1]
+	if !last.IsOk() {
+		return results, fmt.Errorf("activity not started: %s", last.Status)
+	}
+	return results, nil
+}
@@ -3,9 +3,11 @@ package adbclient_test
 import (
 	"bufio"
 	"bytes"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"image"
 	"io"
 	"net"
 	"os"
@@ -34,12 +36,21 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	adbclient "github.com/sephiroth74/go_adb_client"
+	"github.com/sephiroth74/go_adb_client/activitymanager"
+	"github.com/sephiroth74/go_adb_client/apk"
+	"github.com/sephiroth74/go_adb_client/apks"
 	"github.com/sephiroth74/go_adb_client/connection"
 	"github.com/sephiroth74/go_adb_client/input"
+	"github.com/sephiroth74/go_adb_client/inputmanager"
 	"github.com/sephiroth74/go_adb_client/logging"
+	"github.com/sephiroth74/go_adb_client/macro"
 	"github.com/sephiroth74/go_adb_client/mdns"
 	"github.com/sephiroth74/go_adb_client/packagemanager"
+	"github.com/sephiroth74/go_adb_client/powermanager"
+	"github.com/sephiroth74/go_adb_client/screencap"
+	"github.com/sephiroth74/go_adb_client/telemetry"
 	"github.com/sephiroth74/go_adb_client/types"
+	"github.com/sephiroth74/go_adb_client/ui"
 	"gopkg.in/pipe.v2"
 )
 
@@ -47,6 +58,7 @@ var device_ip2 = net.IPv4(192, 168, 1, 3)
 var device_ip = device_ip2
 
 var local_apk = "~/ArcCustomizeSettings.apk"
+var local_apks = "~/ArcCustomizeSettings.apks"
 
 func init() {
 }
@@ -806,11 +818,11 @@ func TestListPackages(t *testing.T) {
 	assert.Nil(t, err)
 
 	for _, p := range packages {
-		logging.Log.Debugf("%s, uid:%s", p.Name, p.UID)
+		logging.Log.Debugf("%s, uid:%d", p.Name, p.UID)
 		assert.True(t, p.Filename != "")
 		assert.True(t, p.Name != "")
-		assert.True(t, p.VersionCode != "")
-		assert.True(t, p.UID != "")
+		assert.True(t, p.VersionCode > 0)
+		assert.True(t, p.UID > 0)
 	}
 }
 
@@ -830,10 +842,10 @@ func TestFindPackages(t *testing.T) {
 	for _, p := range packages {
 		assert.True(t, p.Filename != "")
 		assert.True(t, strings.HasPrefix(p.Name, "com.google"))
-		assert.True(t, p.VersionCode != "")
-		assert.True(t, p.UID != "")
+		assert.True(t, p.VersionCode > 0)
+		assert.True(t, p.UID > 0)
 		assert.True(t, p.MaybeIsSystem())
-		logging.Log.Debugf("%s, uid:%s", p.Name, p.UID)
+		logging.Log.Debugf("%s, uid:%d", p.Name, p.UID)
 	}
 }
 
@@ -1659,3 +1671,717 @@ func TestChangeVerityStatus(t *testing.T) {
 	assert.NotEqual(t, verity_enabled, verity_enabled2)
 
 }
+
+func TestShellWatch(t *testing.T) {
+	var client = NewClient()
+	AssertClientConnected(t, client)
+
+	dirname := "/sdcard/Download"
+	watcher, err := client.Shell.Watch(dirname, shell.NewWatchOptions())
+	assert.Nil(t, err)
+	defer watcher.Stop()
+
+	logging.Log.Infof("watching %s (polling=%t)", dirname, watcher.Polling)
+
+	go func() {
+		time.Sleep(2 * time.Second)
+		_, _ = client.Shell.Execute("touch", dirname+"/watch_test.txt")
+		time.Sleep(2 * time.Second)
+		_, _ = client.Shell.Remove(dirname+"/watch_test.txt", true)
+	}()
+
+	var received []shell.WatchEvent
+	timeout := time.After(10 * time.Second)
+
+loop:
+	for {
+		select {
+		case event := <-watcher.Events:
+			logging.Log.Infof("%s %s", event.Type, event.Path())
+			received = append(received, event)
+			if event.Type == shell.WatchDelete {
+				break loop
+			}
+		case <-timeout:
+			break loop
+		}
+	}
+
+	assert.NotEmpty(t, received)
+}
+
+func TestGetPackageInfo(t *testing.T) {
+	client := NewClient()
+	AssertClientConnected(t, client)
+
+	pkg := "com.android.tv.settings"
+
+	device := adbclient.NewDevice(client)
+	info, err := device.PackageManager().GetPackageInfo(pkg)
+	assert.Nil(t, err)
+	assert.NotNil(t, info)
+
+	assert.Equal(t, pkg, info.PackageName)
+	assert.Equal(t, 1000, info.UserID)
+	assert.True(t, info.VersionCode > 0)
+	assert.True(t, info.IsSystem())
+	assert.False(t, info.LastUpdateTime.IsZero())
+	assert.True(t, len(info.Users) > 0)
+	assert.True(t, len(info.Activities) > 0)
+
+	logging.Log.Debug(info.String())
+}
+
+func TestInstallMultiple(t *testing.T) {
+	client := NewClient()
+	AssertClientConnected(t, client)
+
+	device := adbclient.NewDevice(client)
+	pm := device.PackageManager()
+
+	apks := []string{local_apk}
+	err := pm.InstallMultiple(apks, &packagemanager.InstallSessionOptions{
+		InstallOptions: packagemanager.InstallOptions{
+			ReplaceExistingApplication: true,
+			GrantPermissions:           true,
+		},
+	})
+
+	var installError *packagemanager.InstallError
+	if errors.As(err, &installError) {
+		logging.Log.Warnf("install failed with code %s", installError.Code)
+	}
+	assert.Nil(t, err)
+}
+
+func TestInstallApks(t *testing.T) {
+	client := NewClient()
+	AssertClientConnected(t, client)
+
+	device := adbclient.NewDevice(client)
+
+	spec, err := apks.GetDeviceSpec(client.Shell)
+	assert.Nil(t, err)
+	logging.Log.Infof("device spec: %s", spec)
+
+	archive, err := apks.Open(local_apks)
+	assert.Nil(t, err)
+	defer archive.Close()
+
+	selected, err := archive.SelectApks(*spec)
+	assert.Nil(t, err)
+	for _, apk := range selected {
+		logging.Log.Infof("selected %s %s", apk.Path, apk.Targeting)
+	}
+
+	err = device.InstallApks(local_apks, nil)
+	assert.Nil(t, err)
+}
+
+func TestApkInspect(t *testing.T) {
+	client := NewClient()
+	AssertClientConnected(t, client)
+
+	local, err := apk.Open(local_apk)
+	assert.Nil(t, err)
+	logging.Log.Info(local.String())
+
+	assert.NotEmpty(t, local.Manifest.Package)
+	assert.True(t, local.Manifest.VersionCode > 0)
+	assert.NotEmpty(t, local.Signing.Certificates)
+
+	device := adbclient.NewDevice(client)
+	err = device.PackageManager().CheckInstall(local, nil)
+	assert.Nil(t, err)
+}
+
+func TestCompareInstall(t *testing.T) {
+	installed := &packagemanager.PackageInfo{
+		VersionCode: 20,
+		Signatures:  []string{apk.SignatureHash([]byte("installed"))},
+	}
+
+	local := &apk.Apk{
+		Manifest: apk.Manifest{Package: "com.example", VersionCode: 10},
+		Signing:  apk.SigningInfo{Certificates: []*x509.Certificate{{Raw: []byte("installed")}}},
+	}
+
+	err := packagemanager.CompareInstall(local, installed, nil)
+	assert.ErrorIs(t, err, packagemanager.NewInstallError(packagemanager.InstallFailedVersionDowngrade))
+
+	err = packagemanager.CompareInstall(local, installed, &packagemanager.InstallOptions{AllowVersionCodeDowngrade: true})
+	assert.Nil(t, err)
+
+	local.Manifest.VersionCode = 30
+	local.Signing.Certificates = []*x509.Certificate{{Raw: []byte("other")}}
+	err = packagemanager.CompareInstall(local, installed, nil)
+
+	var installError *packagemanager.InstallError
+	assert.True(t, errors.As(err, &installError))
+	assert.Equal(t, packagemanager.InstallFailedUpdateIncompatible, installError.Code)
+}
+
+func TestBackupRestore(t *testing.T) {
+	client := NewClient()
+	AssertClientConnected(t, client)
+
+	device := adbclient.NewDevice(client)
+	pm := device.PackageManager()
+
+	paths, err := pm.Paths("com.swisscom.aot.library.sample", "")
+	assert.Nil(t, err)
+	logging.Log.Infof("paths: %s", paths)
+
+	target_file, err := filepath.Abs("./exports/backup.tar")
+	assert.Nil(t, err)
+	os.MkdirAll(filepath.Dir(target_file), 0755)
+
+	manifest, err := pm.Backup("com.swisscom.aot.library.sample", target_file, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, len(paths), len(manifest.Apks))
+	logging.Log.Infof("backup: %#v", manifest)
+
+	restored, err := pm.Restore(target_file, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, manifest.VersionCode, restored.VersionCode)
+}
+
+func TestPermissionProfile(t *testing.T) {
+	client := NewClient()
+	AssertClientConnected(t, client)
+
+	device := adbclient.NewDevice(client)
+	pm := device.PackageManager()
+
+	changes, err := pm.ApplyPermissionProfile("com.swisscom.aot.library.sample", packagemanager.PermissionProfile{
+		Revoked: []string{"android.permission.ACCESS_FINE_LOCATION"},
+		Flags: map[string][]types.PermissionFlag{
+			"android.permission.ACCESS_FINE_LOCATION": {types.PermissionFlagUserSet, types.PermissionFlagUserFixed},
+		},
+		AppOps: map[string]packagemanager.AppOpMode{
+			"RUN_IN_BACKGROUND": packagemanager.AppOpModeIgnore,
+		},
+	}, "")
+	assert.Nil(t, err)
+	logging.Log.Infof("changes: %s", changes)
+
+	permissions, err := pm.GetPermissions("com.swisscom.aot.library.sample", "")
+	assert.Nil(t, err)
+	assert.False(t, permissions.IsGranted("android.permission.ACCESS_FINE_LOCATION"))
+	assert.True(t, permissions.Get("android.permission.ACCESS_FINE_LOCATION").HasFlag(types.PermissionFlagUserFixed))
+	assert.Equal(t, packagemanager.AppOpModeIgnore, permissions.AppOp("RUN_IN_BACKGROUND").Mode)
+
+	assert.Nil(t, pm.ClearPermissionFlags("com.swisscom.aot.library.sample", "android.permission.ACCESS_FINE_LOCATION", "", types.PermissionFlagUserFixed))
+	assert.Nil(t, pm.ResetAppOps("com.swisscom.aot.library.sample", ""))
+}
+
+func TestUserManager(t *testing.T) {
+	client := NewClient()
+	AssertClientConnected(t, client)
+
+	device := adbclient.NewDevice(client)
+	um := device.UserManager()
+
+	users, err := um.ListUsers()
+	assert.Nil(t, err)
+	assert.NotEmpty(t, users)
+	for _, user := range users {
+		logging.Log.Infof("%s", user)
+	}
+
+	current, err := um.GetCurrentUser()
+	assert.Nil(t, err)
+	assert.Equal(t, types.UserSystem, current)
+
+	guest, err := um.CreateGuest("Guest", false)
+	assert.Nil(t, err)
+
+	info, err := um.GetUser(guest)
+	assert.Nil(t, err)
+	assert.True(t, info.IsGuest())
+
+	value, err := client.Shell.GetSettingWithUser("user_setup_complete", types.SettingsSecure, guest)
+	assert.Nil(t, err)
+	logging.Log.Infof("user_setup_complete: %v", value)
+
+	isInstalled, err := device.PackageManager().IsInstalled("com.android.settings", guest)
+	assert.Nil(t, err)
+	assert.True(t, isInstalled)
+
+	assert.Nil(t, um.RemoveUser(guest))
+}
+
+func TestListApexPackages(t *testing.T) {
+	client := NewClient()
+	AssertClientConnected(t, client)
+
+	device := adbclient.NewDevice(client)
+	pm := device.PackageManager()
+
+	packages, err := pm.ListPackages(packagemanager.PackageOptions{ShowOnlyApex: true})
+	assert.Nil(t, err)
+
+	for _, p := range packages {
+		logging.Log.Debugf("%s", p)
+		assert.True(t, p.Apex)
+		assert.True(t, p.MaybeIsSystem())
+	}
+
+	packages, err = pm.ListPackages(packagemanager.PackageOptions{ShowOnly3rdParty: true, User: types.UserSystem, WithEnabledState: true})
+	assert.Nil(t, err)
+
+	for _, p := range packages {
+		logging.Log.Debugf("%s, installer:%s, enabled:%t", p.Name, p.Installer, p.Enabled)
+		assert.False(t, p.Apex)
+		assert.True(t, p.UID >= 10000)
+	}
+}
+
+func TestCompilePackage(t *testing.T) {
+	client := NewClient()
+	AssertClientConnected(t, client)
+
+	device := adbclient.NewDevice(client)
+	pm := device.PackageManager()
+
+	err := pm.Compile("com.android.tv.settings", &packagemanager.CompileOptions{Filter: packagemanager.CompilerFilterSpeed, Force: true})
+	assert.Nil(t, err)
+
+	state, err := pm.GetDexoptState("com.android.tv.settings")
+	assert.Nil(t, err)
+	logging.Log.Infof("%+v", state)
+	assert.Equal(t, string(packagemanager.CompilerFilterSpeed), state.PrimaryStatus().CompilerFilter)
+
+	assert.Nil(t, pm.ResetCompilation("com.android.tv.settings"))
+}
+
+func TestStartActivity(t *testing.T) {
+	var client = NewClient()
+	AssertClientConnected(t, client)
+
+	intent := types.NewIntent()
+	intent.Action = "android.intent.action.MAIN"
+	intent.Component = "com.android.tv.settings/.MainSettings"
+
+	var device = adbclient.NewDevice(client)
+	results, err := device.ActivityManager().StartActivityRepeat(intent, &activitymanager.StartOptions{
+		Wait:          true,
+		ForceStop:     true,
+		Repeat:        3,
+		ActivityFlags: []activitymanager.ActivityFlag{activitymanager.ActivityClearTask},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(results))
+
+	for _, result := range results {
+		logging.Log.Infof("%s", result)
+		assert.True(t, result.TotalTime > 0)
+	}
+	assert.Equal(t, activitymanager.LaunchStateCold, results[0].LaunchState)
+}
+
+func TestParseStartResults(t *testing.T) {
+	// output of "am start -W -S -R 2"
+	output := `Stopping: com.android.tv.settings
+Starting: Intent { act=android.intent.action.MAIN cmp=com.android.tv.settings/.MainSettings }
+Status: ok
+LaunchState: COLD
+Activity: com.android.tv.settings/.MainSettings
+TotalTime: 812
+WaitTime: 815
+Complete
+Stopping: com.android.tv.settings
+Starting: Intent { act=android.intent.action.MAIN cmp=com.android.tv.settings/.MainSettings }
+Status: ok
+LaunchState: COLD
+Activity: com.android.tv.settings/.MainSettings
+TotalTime: 640
+WaitTime: 642
+Complete
+`
+	results, err := activitymanager.ParseStartResults(output)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(results))
+
+	for _, result := range results {
+		assert.Equal(t, "ok", result.Status)
+		assert.Equal(t, activitymanager.LaunchStateCold, result.LaunchState)
+		assert.Equal(t, "com.android.tv.settings/.MainSettings", result.Activity)
+	}
+	assert.Equal(t, 812*time.Millisecond, results[0].TotalTime)
+	assert.Equal(t, 640*time.Millisecond, results[1].TotalTime)
+}
+
+func TestSendOrderedBroadcast(t *testing.T) {
+	var client = NewClient()
+	AssertClientConnected(t, client)
+
+	var device = adbclient.NewDevice(client)
+
+	var intent = types.NewIntent()
+	intent.Action = "androidx.work.diagnostics.REQUEST_DIAGNOSTICS"
+	intent.Package = "com.swisscom.aot.library.standalone"
+
+	result, err := device.ActivityManager().SendBroadcast(intent, &activitymanager.BroadcastOptions{
+		ReceiverPermission:            "android.permission.DUMP",
+		AllowBackgroundActivityStarts: true,
+		Component:                     "com.swisscom.aot.library.standalone/androidx.work.impl.diagnostics.DiagnosticsReceiver",
+	})
+	assert.Nil(t, err)
+	logging.Log.Infof("%s", result)
+	assert.Equal(t, 0, result.Code)
+}
+
+func TestIntentUri(t *testing.T) {
+	var client = NewClient()
+	AssertClientConnected(t, client)
+
+	intent := types.NewIntentBuilder().
+		Action("android.intent.action.VIEW").
+		Data("https://www.google.com/search?q=go adb").
+		AddCategory("android.intent.category.BROWSABLE").
+		AddFlags(types.FlagActivityNewTask, types.FlagActivityClearTop).
+		PutString("title", "hello world").
+		PutDouble("ratio", 1.5).
+		Build()
+
+	uri := intent.ToUri()
+	logging.Log.Infof("uri: %s", uri)
+
+	parsed, err := types.ParseIntentUri(uri)
+	assert.Nil(t, err)
+	assert.Equal(t, intent.Args(), parsed.Args())
+
+	var device = adbclient.NewDevice(client)
+	result, err := device.ActivityManager().StartActivity(parsed, &activitymanager.StartOptions{Wait: true})
+	assert.Nil(t, err)
+	logging.Log.Infof("%s", result)
+}
+
+func TestWaitForActivity(t *testing.T) {
+	var client = NewClient()
+	AssertClientConnected(t, client)
+
+	var device = adbclient.NewDevice(client)
+	am := device.ActivityManager()
+
+	intent := types.NewIntent()
+	intent.Action = "android.intent.action.MAIN"
+	intent.Component = "com.android.tv.settings/.MainSettings"
+
+	_, err := am.StartActivity(intent, nil)
+	assert.Nil(t, err)
+
+	record, err := am.WaitForActivity("com.android.tv.settings/com.android.tv.settings.MainSettings", 5*time.Second)
+	assert.Nil(t, err)
+	logging.Log.Infof("resumed: %s", record)
+
+	stack, err := am.GetActivityStack()
+	assert.Nil(t, err)
+	for _, task := range stack.Tasks {
+		logging.Log.Infof("%s", task)
+	}
+	assert.NotNil(t, stack.FindActivity(intent.Component))
+
+	focus, err := am.GetWindowFocus()
+	assert.Nil(t, err)
+	logging.Log.Infof("%s", focus)
+	assert.True(t, focus.FocusedApp.Matches(intent.Component))
+
+	tasks, err := am.GetRootTasks()
+	assert.Nil(t, err)
+	for _, task := range tasks {
+		logging.Log.Infof("%s", task)
+	}
+}
+
+func TestProcesses(t *testing.T) {
+	var client = NewClient()
+	AssertClientConnected(t, client)
+
+	var device = adbclient.NewDevice(client)
+	am := device.ActivityManager()
+	packageName := "com.android.tv.settings"
+
+	err := am.ForceStop(packageName)
+	assert.Nil(t, err)
+	assert.Nil(t, am.WaitForProcessDeath(packageName, 5*time.Second))
+
+	intent := types.NewIntent()
+	intent.Action = "android.intent.action.MAIN"
+	intent.Component = "com.android.tv.settings/.MainSettings"
+	_, err = am.StartActivity(intent, nil)
+	assert.Nil(t, err)
+
+	pids, err := am.WaitForProcess(packageName, 5*time.Second)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, pids)
+
+	processes, err := am.GetPackageProcesses(packageName)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, processes)
+	for _, p := range processes {
+		logging.Log.Infof("%s packages=%v", p, p.Packages)
+	}
+	assert.Equal(t, activitymanager.ImportanceForeground, processes[0].Importance())
+
+	services, err := am.GetRunningServices("")
+	assert.Nil(t, err)
+	for _, s := range services {
+		logging.Log.Infof("%s", s)
+	}
+}
+
+func TestAppMemInfo(t *testing.T) {
+	var client = NewClient()
+	AssertClientConnected(t, client)
+
+	var device = adbclient.NewDevice(client)
+	am := device.ActivityManager()
+
+	info, err := am.GetAppMemInfo("system")
+	assert.Nil(t, err)
+	logging.Log.Infof("%s", info)
+	logging.Log.Infof("summary: %+v", info.Summary)
+	logging.Log.Infof("objects: %+v", info.Objects)
+	assert.True(t, info.Pss() > 0)
+
+	sampler := am.SampleMemInfo("system", 500*time.Millisecond)
+	series := sampler.Collect(3 * time.Second)
+	assert.True(t, len(series.Pss()) > 1)
+	logging.Log.Infof("pss: %v, trend: %s/min", series.Pss(), series.PssTrend())
+}
+
+func TestTelemetry(t *testing.T) {
+	var client = NewClient()
+	AssertClientConnected(t, client)
+
+	var device = adbclient.NewDevice(client)
+
+	options := telemetry.NewOptions()
+	options.Interval = 500 * time.Millisecond
+	options.Processes = []string{"system_server"}
+	options.CpuInfo = true
+
+	sampler := device.Telemetry().Start(options)
+	time.AfterFunc(3*time.Second, sampler.Stop)
+
+	var buffer bytes.Buffer
+	err := telemetry.Export(sampler.Samples, telemetry.NewCSVExporter(&buffer), telemetry.NewJSONExporter(os.Stdout))
+	assert.Nil(t, err)
+	assert.Contains(t, buffer.String(), "cpu.total")
+	assert.Contains(t, buffer.String(), "process.system_server.")
+}
+
+func TestPowerManager(t *testing.T) {
+	var client = NewClient()
+	AssertClientConnected(t, client)
+
+	var device = adbclient.NewDevice(client)
+	pm := device.PowerManager()
+
+	assert.Nil(t, pm.UnplugBattery())
+	assert.Nil(t, pm.SetBatteryLevel(15))
+	defer pm.ResetBattery()
+
+	battery, err := pm.GetBattery()
+	assert.Nil(t, err)
+	assert.Equal(t, 15, battery.Level)
+	assert.False(t, battery.AcPowered || battery.UsbPowered)
+
+	assert.Nil(t, pm.ForceIdle(powermanager.IdleModeDeep))
+	state, err := pm.GetDeviceIdleState()
+	assert.Nil(t, err)
+	logging.Log.Infof("device idle: %s", state)
+	assert.True(t, state.ForceIdle)
+	assert.Equal(t, powermanager.DeepIdleIdle, state.DeepState)
+	assert.Nil(t, pm.UnforceIdle())
+
+	assert.Nil(t, pm.SetStandbyBucket("com.android.settings", powermanager.StandbyBucketRare, types.UserId("")))
+	bucket, err := pm.GetStandbyBucket("com.android.settings", types.UserId(""))
+	assert.Nil(t, err)
+	assert.Equal(t, powermanager.StandbyBucketRare, bucket)
+	assert.Nil(t, pm.SetStandbyBucket("com.android.settings", powermanager.StandbyBucketActive, types.UserId("")))
+}
+
+func TestGestures(t *testing.T) {
+	var client = NewClient()
+	AssertClientConnected(t, client)
+
+	var device = adbclient.NewDevice(client)
+	touch, err := device.InputManager().GetTouchScreen()
+	assert.Nil(t, err)
+	logging.Log.Infof("touch screen: %s", touch)
+
+	center := image.Pt(int(touch.DisplaySize.Width/2), int(touch.DisplaySize.Height/2))
+	assert.Nil(t, touch.Perform(inputmanager.Swipe(center.Add(image.Pt(0, 300)), center.Sub(image.Pt(0, 300)), 300*time.Millisecond)))
+	assert.Nil(t, touch.Perform(inputmanager.Pinch(center, 200, 600, 45, 500*time.Millisecond)))
+	assert.Nil(t, touch.Perform(inputmanager.Fling(center.Add(image.Pt(0, 400)), center.Sub(image.Pt(0, 400)), 5000)))
+	assert.Nil(t, touch.Perform(inputmanager.MultiSwipe(center, center.Add(image.Pt(0, 500)), 3, 100, 400*time.Millisecond)))
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	var client = NewClient()
+	AssertClientConnected(t, client)
+
+	var device = adbclient.NewDevice(client)
+	im := device.InputManager()
+	touch, err := im.GetTouchScreen()
+	assert.Nil(t, err)
+
+	recorder, err := im.Record(touch.Device.Path)
+	assert.Nil(t, err)
+
+	center := image.Pt(int(touch.DisplaySize.Width/2), int(touch.DisplaySize.Height/2))
+	assert.Nil(t, touch.Perform(inputmanager.Swipe(center.Add(image.Pt(0, 300)), center.Sub(image.Pt(0, 300)), 300*time.Millisecond)))
+	time.Sleep(time.Second)
+
+	recording := recorder.Stop()
+	logging.Log.Infof("recorded %d events in %s", len(recording.Events), recording.Duration())
+	assert.True(t, len(recording.Events) > 0)
+
+	filename := filepath.Join(t.TempDir(), "recording.txt")
+	assert.Nil(t, recording.Save(filename))
+
+	loaded, err := inputmanager.LoadRecording(filename)
+	assert.Nil(t, err)
+	assert.Equal(t, len(recording.Events), len(loaded.Events))
+	assert.Nil(t, im.Replay(*loaded))
+}
+
+func TestTextInput(t *testing.T) {
+	var client = NewClient()
+	AssertClientConnected(t, client)
+
+	var device = adbclient.NewDevice(client)
+	im := device.InputManager()
+
+	imes, err := im.GetImes()
+	assert.Nil(t, err)
+	for _, ime := range imes {
+		logging.Log.Infof("ime: %s", ime)
+	}
+
+	current, err := im.GetCurrentIme()
+	assert.Nil(t, err)
+	assert.NotEmpty(t, current)
+
+	_, err = client.Shell.SendString("it's 50% off, use %s now")
+	assert.Nil(t, err)
+
+	_, err = client.Shell.SendString("héllo")
+	assert.NotNil(t, err)
+
+	if installed, _ := device.PackageManager().IsInstalled("com.android.adbkeyboard", types.UserId("")); installed {
+		assert.Nil(t, im.SendText("héllo 世界", inputmanager.UnicodeAdbKeyboard))
+		after, _ := im.GetCurrentIme()
+		assert.Equal(t, current, after)
+	}
+}
+
+func TestInputDevices(t *testing.T) {
+	var client = NewClient()
+	AssertClientConnected(t, client)
+
+	var device = adbclient.NewDevice(client)
+	devices, err := device.InputManager().InputDevices()
+	assert.Nil(t, err)
+	assert.NotEmpty(t, devices)
+	for _, d := range devices {
+		logging.Log.Infof("%s, key layout: %s", d, d.KeyLayoutFile)
+	}
+
+	code, err := input.ParseKeyCode("dpad_center")
+	assert.Nil(t, err)
+	assert.Equal(t, input.KEYCODE_DPAD_CENTER, code)
+
+	source, err := input.ParseInputSource("DPAD")
+	assert.Nil(t, err)
+	assert.Equal(t, input.DPAD, source)
+
+	assert.Equal(t, "KeyCode(-1)", input.KeyCode(-1).String())
+}
+
+func TestMacro(t *testing.T) {
+	var client = NewClient()
+	AssertClientConnected(t, client)
+
+	script, err := macro.ParseText(strings.NewReader(`# open the settings
+key HOME
+wait 1s
+key DPAD_DOWN repeat=2 source=dpad
+text "hello world"
+swipe 500 1500 500 300 duration=200ms
+screenshot macro/home.png
+`))
+	assert.Nil(t, err)
+	assert.Equal(t, 6, len(script.Steps))
+
+	var device = adbclient.NewDevice(client)
+	runner := macro.NewRunner(device)
+	runner.OutputDir = os.TempDir()
+
+	result := runner.Run(*script)
+	logging.Log.Infof("%s", result)
+	assert.Nil(t, result.Err())
+	assert.Equal(t, len(script.Steps), len(result.Steps))
+}
+
+func TestUiAutomator(t *testing.T) {
+	var client = NewClient()
+	AssertClientConnected(t, client)
+
+	var device = adbclient.NewDevice(client)
+	automator := device.UiAutomator()
+
+	hierarchy, err := automator.Dump(nil)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, hierarchy.Nodes())
+
+	clickable, err := hierarchy.XPath("//node[@clickable='true']")
+	assert.Nil(t, err)
+	for _, node := range clickable {
+		logging.Log.Infof("%s, center: %v", node, node.Center())
+	}
+
+	_, err = automator.WaitForElement(ui.ByText("this text does not exist"), time.Second)
+	assert.ErrorIs(t, err, ui.ErrElementNotFound)
+
+	_, err = automator.ScrollUntilVisible(ui.ByTextContains("about"), ui.ScrollDown, &ui.ScrollOptions{MaxSwipes: 3})
+	if err != nil {
+		assert.ErrorIs(t, err, ui.ErrElementNotFound)
+	}
+}
+
+func TestScreenshot(t *testing.T) {
+	var client = NewClient()
+	AssertClientConnected(t, client)
+
+	var device = adbclient.NewDevice(client)
+	first, err := device.Screenshot()
+	assert.Nil(t, err)
+	assert.False(t, first.Bounds().Empty())
+	logging.Log.Infof("screenshot size: %v", first.Bounds().Size())
+
+	displays, err := device.GetDisplays()
+	assert.Nil(t, err)
+	logging.Log.Infof("displays: %v", displays)
+
+	options := &screencap.Options{Region: image.Rect(0, 0, 200, 100)}
+	if len(displays) > 0 {
+		options.DisplayId = displays[0].Id
+	}
+
+	region, err := device.ScreenshotWithOptions(options)
+	assert.Nil(t, err)
+	assert.Equal(t, image.Pt(200, 100), region.Bounds().Size())
+
+	second, err := device.Screenshot()
+	assert.Nil(t, err)
+
+	// ignore the status bar, where the clock can change
+	result, err := screencap.Diff(first, second, &screencap.DiffOptions{Tolerance: 8, Masks: []image.Rectangle{image.Rect(0, 0, first.Bounds().Dx(), 100)}})
+	assert.Nil(t, err)
+	logging.Log.Infof("%s", result)
+	assert.Greater(t, result.Similarity(), 0.9)
+}
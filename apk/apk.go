@@ -0,0 +1,200 @@
+package apk
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	manifestFilename  = "AndroidManifest.xml"
+	resourcesFilename = "resources.arsc"
+)
+
+// android attribute resource ids, used when the attribute names have been stripped
+const (
+	attrLabel            = 0x01010001
+	attrName             = 0x01010003
+	attrDebuggable       = 0x0101000f
+	attrMinSdkVersion    = 0x0101020c
+	attrVersionCode      = 0x0101021b
+	attrVersionName      = 0x0101021c
+	attrTargetSdkVersion = 0x01010270
+	attrVersionCodeMajor = 0x01010576
+)
+
+// Manifest contains the main information of the AndroidManifest.xml
+type Manifest struct {
+	Package     string
+	VersionCode int64
+	VersionName string
+	MinSdk      int
+	TargetSdk   int
+	// Split is the split name, empty for the base apk
+	Split       string
+	Label       string
+	Debuggable  bool
+	Permissions []string
+	// Root is the decoded manifest document
+	Root *XmlNode
+}
+
+// Apk is a local apk file
+type Apk struct {
+	Filename string
+	Manifest Manifest
+	// Abis are the native abis found under lib/
+	Abis    []string
+	Signing SigningInfo
+}
+
+func (a Apk) String() string {
+	m := a.Manifest
+	return fmt.Sprintf("Apk{Package:%s, VersionCode:%d, VersionName:%s, MinSdk:%d, TargetSdk:%d, Split:%s, Abis:%s, Signing:%s}",
+		m.Package, m.VersionCode, m.VersionName, m.MinSdk, m.TargetSdk, m.Split, a.Abis, a.Signing)
+}
+
+// IsSplit returns true if the apk is a split apk
+func (a Apk) IsSplit() bool {
+	return a.Manifest.Split != ""
+}
+
+// Open reads the manifest, the native abis and the signing certificates of the given apk file
+func Open(filename string) (*Apk, error) {
+	reader, err := zip.OpenReader(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	manifestData, err := readEntry(&reader.Reader, manifestFilename)
+	if err != nil {
+		return nil, err
+	}
+	if manifestData == nil {
+		return nil, fmt.Errorf("%s not found", manifestFilename)
+	}
+
+	root, err := ParseXml(manifestData)
+	if err != nil {
+		return nil, err
+	}
+
+	var table *ResourceTable
+	if data, err := readEntry(&reader.Reader, resourcesFilename); err == nil && data != nil {
+		table, _ = ParseResourceTable(data)
+	}
+
+	result := &Apk{
+		Filename: filename,
+		Manifest: parseManifest(root, table),
+		Abis:     nativeAbis(&reader.Reader),
+	}
+
+	result.Signing, err = readSigningInfo(filename, &reader.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func parseManifest(root *XmlNode, table *ResourceTable) Manifest {
+	resolve := func(attr *XmlAttribute) string {
+		if attr == nil {
+			return ""
+		}
+		if attr.Type == typeReference && table != nil {
+			if value, ok := table.ResolveString(attr.Data); ok {
+				return value
+			}
+		}
+		return attr.Value
+	}
+
+	manifest := Manifest{Root: root}
+	manifest.Package = resolve(root.Attr("package", 0))
+	manifest.Split = resolve(root.Attr("split", 0))
+	manifest.VersionName = resolve(root.Attr("versionName", attrVersionName))
+
+	if attr := root.Attr("versionCode", attrVersionCode); attr != nil {
+		manifest.VersionCode = int64(attr.Data)
+	}
+	if attr := root.Attr("versionCodeMajor", attrVersionCodeMajor); attr != nil {
+		manifest.VersionCode |= int64(attr.Data) << 32
+	}
+
+	for _, sdk := range root.Find("uses-sdk") {
+		manifest.MinSdk = intAttr(sdk.Attr("minSdkVersion", attrMinSdkVersion), 1)
+		manifest.TargetSdk = intAttr(sdk.Attr("targetSdkVersion", attrTargetSdkVersion), manifest.MinSdk)
+	}
+	if manifest.MinSdk == 0 {
+		manifest.MinSdk = 1
+		manifest.TargetSdk = 1
+	}
+
+	for _, tag := range []string{"uses-permission", "uses-permission-sdk-23", "uses-permission-sdk-m"} {
+		for _, permission := range root.Find(tag) {
+			if name := resolve(permission.Attr("name", attrName)); name != "" {
+				manifest.Permissions = append(manifest.Permissions, name)
+			}
+		}
+	}
+
+	for _, application := range root.Find("application") {
+		manifest.Label = resolve(application.Attr("label", attrLabel))
+		if attr := application.Attr("debuggable", attrDebuggable); attr != nil {
+			manifest.Debuggable = attr.Data != 0
+		}
+	}
+
+	return manifest
+}
+
+// intAttr returns the integer value of the attribute. Codenames (string values) are returned as 10000
+func intAttr(attr *XmlAttribute, def int) int {
+	if attr == nil {
+		return def
+	}
+	if attr.Type == typeIntDec || attr.Type == typeIntHex {
+		return int(int32(attr.Data))
+	}
+	if value, err := strconv.Atoi(attr.Value); err == nil {
+		return value
+	}
+	return 10000
+}
+
+func nativeAbis(reader *zip.Reader) []string {
+	set := make(map[string]bool)
+	for _, f := range reader.File {
+		parts := strings.Split(f.Name, "/")
+		if len(parts) == 3 && parts[0] == "lib" && strings.HasSuffix(parts[2], ".so") {
+			set[parts[1]] = true
+		}
+	}
+	var abis []string
+	for abi := range set {
+		abis = append(abis, abi)
+	}
+	sort.Strings(abis)
+	return abis
+}
+
+func readEntry(reader *zip.Reader, name string) ([]byte, error) {
+	for _, f := range reader.File {
+		if f.Name != name {
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return io.ReadAll(r)
+	}
+	return nil, nil
+}
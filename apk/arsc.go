@@ -0,0 +1,190 @@
+package apk
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// ResourceTable is a minimal decoder of resources.arsc, used to resolve
+// the resource references found in the manifest
+type ResourceTable struct {
+	strings stringPool
+	// values indexed by resource id, only the first value found for the default configuration is kept
+	values map[uint32]resourceValue
+}
+
+type resourceValue struct {
+	dataType      uint8
+	data          uint32
+	defaultConfig bool
+}
+
+// ParseResourceTable decodes the given resources.arsc content
+func ParseResourceTable(data []byte) (*ResourceTable, error) {
+	if len(data) < 12 || binary.LittleEndian.Uint16(data) != chunkTable {
+		return nil, errors.New("not a resource table")
+	}
+
+	table := &ResourceTable{values: make(map[uint32]resourceValue)}
+
+	offset := int(binary.LittleEndian.Uint16(data[2:]))
+	for offset+8 <= len(data) {
+		chunkType := binary.LittleEndian.Uint16(data[offset:])
+		size := int(binary.LittleEndian.Uint32(data[offset+4:]))
+		if size < 8 || offset+size > len(data) {
+			return nil, errInvalidChunk
+		}
+		chunk := data[offset : offset+size]
+
+		switch chunkType {
+		case chunkStringPool:
+			pool, err := parseStringPool(chunk)
+			if err != nil {
+				return nil, err
+			}
+			table.strings = pool
+		case chunkTablePackage:
+			if err := table.parsePackage(chunk); err != nil {
+				return nil, err
+			}
+		}
+		offset += size
+	}
+	return table, nil
+}
+
+// ResolveString returns the string value of the given resource id, preferring the default configuration
+func (t *ResourceTable) ResolveString(id uint32) (string, bool) {
+	for i := 0; i < 8; i++ {
+		value, ok := t.values[id]
+		if !ok {
+			return "", false
+		}
+		switch value.dataType {
+		case typeString:
+			return t.strings.get(value.data), true
+		case typeReference:
+			id = value.data
+		default:
+			return formatValue(value.dataType, value.data, t.strings), true
+		}
+	}
+	return "", false
+}
+
+// ResTable_package: header(8), id u32, name u16[128], typeStrings u32, lastPublicType u32, keyStrings u32, lastPublicKey u32
+func (t *ResourceTable) parsePackage(chunk []byte) error {
+	headerSize := int(binary.LittleEndian.Uint16(chunk[2:]))
+	if headerSize < 12 || len(chunk) < headerSize {
+		return errInvalidChunk
+	}
+	packageID := binary.LittleEndian.Uint32(chunk[8:])
+
+	offset := headerSize
+	for offset+8 <= len(chunk) {
+		chunkType := binary.LittleEndian.Uint16(chunk[offset:])
+		size := int(binary.LittleEndian.Uint32(chunk[offset+4:]))
+		if size < 8 || offset+size > len(chunk) {
+			return errInvalidChunk
+		}
+		if chunkType == chunkTableType {
+			t.parseType(packageID, chunk[offset:offset+size])
+		}
+		offset += size
+	}
+	return nil
+}
+
+// ResTable_type: header(8), id u8, flags u8, reserved u16, entryCount u32, entriesStart u32, config (ResTable_config)
+func (t *ResourceTable) parseType(packageID uint32, chunk []byte) {
+	headerSize := int(binary.LittleEndian.Uint16(chunk[2:]))
+	if len(chunk) < 20 || len(chunk) < headerSize {
+		return
+	}
+
+	typeID := uint32(chunk[8])
+	flags := chunk[9]
+	entryCount := int(binary.LittleEndian.Uint32(chunk[12:]))
+	entriesStart := int(binary.LittleEndian.Uint32(chunk[16:]))
+
+	// ResTable_config: size u32, imsi u32, locale (language[2], country[2]), ...
+	defaultConfig := true
+	if len(chunk) >= 32 {
+		defaultConfig = binary.LittleEndian.Uint32(chunk[24:]) == 0 && binary.LittleEndian.Uint32(chunk[28:]) == 0
+	}
+
+	sparse := flags&0x01 != 0
+	offset16 := flags&0x02 != 0
+
+	for i := 0; i < entryCount; i++ {
+		var index, entryOffset int
+		switch {
+		case sparse:
+			// ResTable_sparseTypeEntry: idx u16, offset u16 (in units of 4 bytes)
+			pos := headerSize + i*4
+			if pos+4 > len(chunk) {
+				return
+			}
+			index = int(binary.LittleEndian.Uint16(chunk[pos:]))
+			entryOffset = int(binary.LittleEndian.Uint16(chunk[pos+2:])) * 4
+		case offset16:
+			pos := headerSize + i*2
+			if pos+2 > len(chunk) {
+				return
+			}
+			raw := binary.LittleEndian.Uint16(chunk[pos:])
+			if raw == 0xffff {
+				continue
+			}
+			index = i
+			entryOffset = int(raw) * 4
+		default:
+			pos := headerSize + i*4
+			if pos+4 > len(chunk) {
+				return
+			}
+			raw := binary.LittleEndian.Uint32(chunk[pos:])
+			if raw == noEntry {
+				continue
+			}
+			index = i
+			entryOffset = int(raw)
+		}
+
+		// ResTable_entry: size u16, flags u16, key u32, followed by Res_value (size u16, res0 u8, dataType u8, data u32)
+		pos := entriesStart + entryOffset
+		if pos+16 > len(chunk) {
+			continue
+		}
+		entryFlags := binary.LittleEndian.Uint16(chunk[pos+2:])
+		if entryFlags&0x0001 != 0 {
+			// complex (bag) entries are not supported
+			continue
+		}
+		entrySize := int(binary.LittleEndian.Uint16(chunk[pos:]))
+		valuePos := pos + entrySize
+		if entryFlags&0x0008 != 0 {
+			// compact entry: the key is stored in size, dataType in flags and data in key
+			id := packageID<<24 | typeID<<16 | uint32(index)
+			t.put(id, resourceValue{dataType: uint8(entryFlags >> 8), data: binary.LittleEndian.Uint32(chunk[pos+4:]), defaultConfig: defaultConfig})
+			continue
+		}
+		if valuePos+8 > len(chunk) {
+			continue
+		}
+
+		id := packageID<<24 | typeID<<16 | uint32(index)
+		t.put(id, resourceValue{
+			dataType:      chunk[valuePos+3],
+			data:          binary.LittleEndian.Uint32(chunk[valuePos+4:]),
+			defaultConfig: defaultConfig,
+		})
+	}
+}
+
+func (t *ResourceTable) put(id uint32, value resourceValue) {
+	if current, ok := t.values[id]; ok && (current.defaultConfig || !value.defaultConfig) {
+		return
+	}
+	t.values[id] = value
+}
@@ -0,0 +1,290 @@
+package apk
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"unicode/utf16"
+)
+
+// binary xml (AXML) and resource chunk types
+const (
+	chunkStringPool     = 0x0001
+	chunkTable          = 0x0002
+	chunkXml            = 0x0003
+	chunkXmlStartNs     = 0x0100
+	chunkXmlEndNs       = 0x0101
+	chunkXmlStartTag    = 0x0102
+	chunkXmlEndTag      = 0x0103
+	chunkXmlCData       = 0x0104
+	chunkXmlResourceMap = 0x0180
+	chunkTablePackage   = 0x0200
+	chunkTableType      = 0x0201
+	chunkTableTypeSpec  = 0x0202
+)
+
+// Res_value data types
+const (
+	typeNull      = 0x00
+	typeReference = 0x01
+	typeString    = 0x03
+	typeFloat     = 0x04
+	typeIntDec    = 0x10
+	typeIntHex    = 0x11
+	typeBoolean   = 0x12
+)
+
+const noEntry = 0xffffffff
+
+var errInvalidChunk = errors.New("invalid resource chunk")
+
+// region StringPool
+
+type stringPool struct {
+	strings []string
+}
+
+func (s stringPool) get(index uint32) string {
+	if index == noEntry || int(index) >= len(s.strings) {
+		return ""
+	}
+	return s.strings[index]
+}
+
+func parseStringPool(data []byte) (stringPool, error) {
+	pool := stringPool{}
+	if len(data) < 28 {
+		return pool, errInvalidChunk
+	}
+
+	headerSize := binary.LittleEndian.Uint16(data[2:])
+	count := binary.LittleEndian.Uint32(data[8:])
+	flags := binary.LittleEndian.Uint32(data[16:])
+	stringsStart := binary.LittleEndian.Uint32(data[20:])
+	utf8 := flags&0x100 != 0
+
+	if uint64(headerSize)+uint64(count)*4 > uint64(len(data)) {
+		return pool, errInvalidChunk
+	}
+
+	pool.strings = make([]string, count)
+	for i := uint32(0); i < count; i++ {
+		offset := binary.LittleEndian.Uint32(data[uint32(headerSize)+i*4:])
+		start := int(stringsStart) + int(offset)
+		if start >= len(data) {
+			return pool, errInvalidChunk
+		}
+		if utf8 {
+			pool.strings[i] = decodeUtf8String(data[start:])
+		} else {
+			pool.strings[i] = decodeUtf16String(data[start:])
+		}
+	}
+	return pool, nil
+}
+
+func decodeUtf8String(data []byte) string {
+	// utf16 length, then utf8 length, each encoded in 1 or 2 bytes
+	_, n := decodeLength8(data)
+	length, m := decodeLength8(data[n:])
+	start := n + m
+	if start+length > len(data) {
+		return ""
+	}
+	return string(data[start : start+length])
+}
+
+func decodeLength8(data []byte) (int, int) {
+	if len(data) == 0 {
+		return 0, 0
+	}
+	if data[0]&0x80 != 0 && len(data) > 1 {
+		return int(data[0]&0x7f)<<8 | int(data[1]), 2
+	}
+	return int(data[0]), 1
+}
+
+func decodeUtf16String(data []byte) string {
+	if len(data) < 2 {
+		return ""
+	}
+	length := int(binary.LittleEndian.Uint16(data))
+	start := 2
+	if length&0x8000 != 0 && len(data) >= 4 {
+		length = (length&0x7fff)<<16 | int(binary.LittleEndian.Uint16(data[2:]))
+		start = 4
+	}
+	if start+length*2 > len(data) {
+		return ""
+	}
+	chars := make([]uint16, length)
+	for i := 0; i < length; i++ {
+		chars[i] = binary.LittleEndian.Uint16(data[start+i*2:])
+	}
+	return string(utf16.Decode(chars))
+}
+
+// endregion StringPool
+
+// region XmlNode
+
+// XmlAttribute is an attribute of a binary xml element.
+// Value is the decoded string value, Type and Data the raw typed value
+type XmlAttribute struct {
+	Namespace  string
+	Name       string
+	ResourceID uint32
+	Value      string
+	Type       uint8
+	Data       uint32
+}
+
+// XmlNode is an element of a decoded binary xml document
+type XmlNode struct {
+	Name       string
+	Attributes []XmlAttribute
+	Children   []*XmlNode
+}
+
+// Attr returns the attribute with the given name or resource id, nil if not found
+func (n *XmlNode) Attr(name string, resourceID uint32) *XmlAttribute {
+	for i, attr := range n.Attributes {
+		if attr.Name == name || (resourceID != 0 && attr.ResourceID == resourceID) {
+			return &n.Attributes[i]
+		}
+	}
+	return nil
+}
+
+// Find returns all the direct children with the given name
+func (n *XmlNode) Find(name string) []*XmlNode {
+	var result []*XmlNode
+	for _, child := range n.Children {
+		if child.Name == name {
+			result = append(result, child)
+		}
+	}
+	return result
+}
+
+// endregion XmlNode
+
+// ParseXml decodes a binary xml (AXML) document, such as the AndroidManifest.xml of an apk
+func ParseXml(data []byte) (*XmlNode, error) {
+	if len(data) < 8 || binary.LittleEndian.Uint16(data) != chunkXml {
+		return nil, errors.New("not a binary xml document")
+	}
+
+	var pool stringPool
+	var resourceMap []uint32
+	var stack []*XmlNode
+	var root *XmlNode
+
+	offset := int(binary.LittleEndian.Uint16(data[2:]))
+	for offset+8 <= len(data) {
+		chunkType := binary.LittleEndian.Uint16(data[offset:])
+		headerSize := int(binary.LittleEndian.Uint16(data[offset+2:]))
+		size := int(binary.LittleEndian.Uint32(data[offset+4:]))
+		if size < 8 || offset+size > len(data) {
+			return nil, errInvalidChunk
+		}
+		chunk := data[offset : offset+size]
+
+		switch chunkType {
+		case chunkStringPool:
+			var err error
+			if pool, err = parseStringPool(chunk); err != nil {
+				return nil, err
+			}
+		case chunkXmlResourceMap:
+			for i := headerSize; i+4 <= size; i += 4 {
+				resourceMap = append(resourceMap, binary.LittleEndian.Uint32(chunk[i:]))
+			}
+		case chunkXmlStartTag:
+			node, err := parseStartTag(chunk, headerSize, pool, resourceMap)
+			if err != nil {
+				return nil, err
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, node)
+			} else if root == nil {
+				root = node
+			}
+			stack = append(stack, node)
+		case chunkXmlEndTag:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		}
+		offset += size
+	}
+
+	if root == nil {
+		return nil, errors.New("empty binary xml document")
+	}
+	return root, nil
+}
+
+// ResXMLTree_attrExt: ns u32, name u32, attributeStart u16, attributeSize u16, attributeCount u16, ...
+// ResXMLTree_attribute: ns u32, name u32, rawValue u32, Res_value (size u16, res0 u8, dataType u8, data u32)
+func parseStartTag(chunk []byte, headerSize int, pool stringPool, resourceMap []uint32) (*XmlNode, error) {
+	if len(chunk) < headerSize+20 {
+		return nil, errInvalidChunk
+	}
+	ext := chunk[headerSize:]
+	node := &XmlNode{Name: pool.get(binary.LittleEndian.Uint32(ext[4:]))}
+
+	attributeStart := int(binary.LittleEndian.Uint16(ext[8:]))
+	attributeSize := int(binary.LittleEndian.Uint16(ext[10:]))
+	attributeCount := int(binary.LittleEndian.Uint16(ext[12:]))
+
+	for i := 0; i < attributeCount; i++ {
+		start := attributeStart + i*attributeSize
+		if start+20 > len(ext) {
+			return nil, errInvalidChunk
+		}
+		a := ext[start:]
+		nameIndex := binary.LittleEndian.Uint32(a[4:])
+		attr := XmlAttribute{
+			Namespace: pool.get(binary.LittleEndian.Uint32(a)),
+			Name:      pool.get(nameIndex),
+			Type:      a[15],
+			Data:      binary.LittleEndian.Uint32(a[16:]),
+		}
+		if int(nameIndex) < len(resourceMap) {
+			attr.ResourceID = resourceMap[nameIndex]
+		}
+
+		raw := binary.LittleEndian.Uint32(a[8:])
+		if raw != noEntry {
+			attr.Value = pool.get(raw)
+		} else {
+			attr.Value = formatValue(attr.Type, attr.Data, pool)
+		}
+		node.Attributes = append(node.Attributes, attr)
+	}
+	return node, nil
+}
+
+func formatValue(dataType uint8, data uint32, pool stringPool) string {
+	switch dataType {
+	case typeString:
+		return pool.get(data)
+	case typeIntDec:
+		return fmt.Sprintf("%d", int32(data))
+	case typeIntHex:
+		return fmt.Sprintf("0x%08x", data)
+	case typeBoolean:
+		if data != 0 {
+			return "true"
+		}
+		return "false"
+	case typeReference:
+		return fmt.Sprintf("@0x%08x", data)
+	case typeNull:
+		return ""
+	default:
+		return fmt.Sprintf("0x%08x", data)
+	}
+}
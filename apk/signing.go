@@ -0,0 +1,265 @@
+package apk
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+const (
+	signingBlockMagic = "APK Sig Block 42"
+	signatureSchemeV2 = 0x7109871a
+	signatureSchemeV3 = 0xf05368c0
+	eocdSignature     = 0x06054b50
+)
+
+// SigningInfo contains the signature schemes found in the apk and the signer certificates.
+// Signatures are read but not verified
+type SigningInfo struct {
+	V1           bool
+	V2           bool
+	V3           bool
+	Certificates []*x509.Certificate
+}
+
+// Fingerprints returns the SHA-256 fingerprints of the signer certificates
+func (s SigningInfo) Fingerprints() []string {
+	var result []string
+	for _, cert := range s.Certificates {
+		sum := sha256.Sum256(cert.Raw)
+		result = append(result, hex.EncodeToString(sum[:]))
+	}
+	return result
+}
+
+// SignatureHashes returns the hash of the signer certificates in the same format used
+// by the package manager dump (signatures:[hash, ...])
+func (s SigningInfo) SignatureHashes() []string {
+	var result []string
+	for _, cert := range s.Certificates {
+		result = append(result, SignatureHash(cert.Raw))
+	}
+	return result
+}
+
+func (s SigningInfo) String() string {
+	return fmt.Sprintf("SigningInfo{V1:%t, V2:%t, V3:%t, Fingerprints:%s}", s.V1, s.V2, s.V3, s.Fingerprints())
+}
+
+// SignatureHash returns the java Arrays.hashCode of the encoded certificate, as hex string
+func SignatureHash(der []byte) string {
+	var h int32 = 1
+	for _, b := range der {
+		h = 31*h + int32(int8(b))
+	}
+	return fmt.Sprintf("%x", uint32(h))
+}
+
+func readSigningInfo(filename string, reader *zip.Reader) (SigningInfo, error) {
+	info := SigningInfo{}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return info, err
+	}
+	defer file.Close()
+
+	// v2/v3 certificates take precedence over the jar signature
+	if block, err := findSigningBlock(file); err == nil {
+		if value, ok := block[signatureSchemeV3]; ok {
+			info.V3 = true
+			info.Certificates, _ = parseSchemeCertificates(value)
+		}
+		if value, ok := block[signatureSchemeV2]; ok {
+			info.V2 = true
+			if len(info.Certificates) == 0 {
+				info.Certificates, _ = parseSchemeCertificates(value)
+			}
+		}
+	}
+
+	for _, f := range reader.File {
+		dir, name := path.Split(f.Name)
+		ext := strings.ToUpper(path.Ext(name))
+		if dir != "META-INF/" || (ext != ".RSA" && ext != ".DSA" && ext != ".EC") {
+			continue
+		}
+
+		info.V1 = true
+		if len(info.Certificates) > 0 {
+			break
+		}
+
+		r, err := f.Open()
+		if err != nil {
+			return info, err
+		}
+		data, err := io.ReadAll(r)
+		_ = r.Close()
+		if err != nil {
+			return info, err
+		}
+
+		certificates, err := parsePkcs7Certificates(data)
+		if err != nil {
+			return info, err
+		}
+		info.Certificates = certificates
+		break
+	}
+
+	return info, nil
+}
+
+// findSigningBlock returns the id-value pairs of the APK Signing Block, which is placed just before the zip central directory
+func findSigningBlock(file *os.File) (map[uint32][]byte, error) {
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	// the end of central directory record is at most 22 + 65535 bytes from the end of the file
+	tailSize := int64(22 + 65535)
+	if stat.Size() < tailSize {
+		tailSize = stat.Size()
+	}
+	tail := make([]byte, tailSize)
+	if _, err := file.ReadAt(tail, stat.Size()-tailSize); err != nil {
+		return nil, err
+	}
+
+	eocd := -1
+	for i := len(tail) - 22; i >= 0; i-- {
+		if binary.LittleEndian.Uint32(tail[i:]) == eocdSignature {
+			eocd = i
+			break
+		}
+	}
+	if eocd < 0 {
+		return nil, errors.New("end of central directory not found")
+	}
+
+	centralDirOffset := int64(binary.LittleEndian.Uint32(tail[eocd+16:]))
+	if centralDirOffset < 32 {
+		return nil, errors.New("apk signing block not found")
+	}
+
+	footer := make([]byte, 24)
+	if _, err := file.ReadAt(footer, centralDirOffset-24); err != nil {
+		return nil, err
+	}
+	if string(footer[8:]) != signingBlockMagic {
+		return nil, errors.New("apk signing block not found")
+	}
+
+	// the size excludes its own field at the start of the block, and includes the footer
+	blockSize := int64(binary.LittleEndian.Uint64(footer))
+	if blockSize < 24 || blockSize > centralDirOffset-8 {
+		return nil, errors.New("invalid apk signing block")
+	}
+	blockStart := centralDirOffset - blockSize - 8
+
+	block := make([]byte, blockSize-24)
+	if _, err := file.ReadAt(block, blockStart+8); err != nil {
+		return nil, err
+	}
+
+	pairs := make(map[uint32][]byte)
+	for len(block) >= 12 {
+		size := binary.LittleEndian.Uint64(block)
+		if size < 4 || uint64(len(block)-8) < size {
+			return nil, errors.New("invalid apk signing block")
+		}
+		id := binary.LittleEndian.Uint32(block[8:])
+		pairs[id] = block[12 : 8+size]
+		block = block[8+size:]
+	}
+	return pairs, nil
+}
+
+// parseSchemeCertificates reads the certificates of the first signer of a v2/v3 signature scheme block:
+// signers (length-prefixed sequence) > signer > signed data > digests, certificates
+func parseSchemeCertificates(value []byte) ([]*x509.Certificate, error) {
+	signers, _, err := lengthPrefixed(value)
+	if err != nil {
+		return nil, err
+	}
+	signer, _, err := lengthPrefixed(signers)
+	if err != nil {
+		return nil, err
+	}
+	signedData, _, err := lengthPrefixed(signer)
+	if err != nil {
+		return nil, err
+	}
+	_, rest, err := lengthPrefixed(signedData)
+	if err != nil {
+		return nil, err
+	}
+	certificates, _, err := lengthPrefixed(rest)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*x509.Certificate
+	for len(certificates) > 0 {
+		var der []byte
+		der, certificates, err = lengthPrefixed(certificates)
+		if err != nil {
+			return nil, err
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, cert)
+	}
+	return result, nil
+}
+
+func lengthPrefixed(data []byte) ([]byte, []byte, error) {
+	if len(data) < 4 {
+		return nil, nil, errors.New("invalid length prefixed value")
+	}
+	size := binary.LittleEndian.Uint32(data)
+	if uint64(len(data)-4) < uint64(size) {
+		return nil, nil, errors.New("invalid length prefixed value")
+	}
+	return data[4 : 4+size], data[4+size:], nil
+}
+
+// parsePkcs7Certificates extracts the certificates from a PKCS#7 SignedData (META-INF/*.RSA)
+func parsePkcs7Certificates(data []byte) ([]*x509.Certificate, error) {
+	var contentInfo struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue `asn1:"explicit,tag:0"`
+	}
+	if _, err := asn1.Unmarshal(data, &contentInfo); err != nil {
+		return nil, err
+	}
+
+	var signedData struct {
+		Version          int
+		DigestAlgorithms asn1.RawValue
+		ContentInfo      asn1.RawValue
+		Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	}
+	if _, err := asn1.Unmarshal(contentInfo.Content.Bytes, &signedData); err != nil {
+		return nil, err
+	}
+
+	if len(signedData.Certificates.Bytes) == 0 {
+		return nil, errors.New("no certificates found")
+	}
+	return x509.ParseCertificates(bytes.Clone(signedData.Certificates.Bytes))
+}
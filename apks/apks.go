@@ -0,0 +1,341 @@
+package apks
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sephiroth74/go_adb_client/packagemanager"
+	"github.com/sephiroth74/go_adb_client/shell"
+)
+
+const tocFilename = "toc.pb"
+
+var ErrNoMatchingVariant = errors.New("no variant matches the device spec")
+
+// region DeviceSpec
+
+// DeviceSpec describes the device configuration used to select the apks to install
+type DeviceSpec struct {
+	// SupportedAbis in order of preference
+	SupportedAbis    []string
+	SdkVersion       int
+	ScreenDensity    int
+	SupportedLocales []string
+}
+
+func (d DeviceSpec) String() string {
+	return fmt.Sprintf("DeviceSpec{Abis:%s, Sdk:%d, Density:%d, Locales:%s}", d.SupportedAbis, d.SdkVersion, d.ScreenDensity, d.SupportedLocales)
+}
+
+// GetDeviceSpec reads the device spec from the device properties
+func GetDeviceSpec(s *shell.Shell) (*DeviceSpec, error) {
+	props, err := s.GetProps()
+	if err != nil {
+		return nil, err
+	}
+
+	spec := &DeviceSpec{}
+
+	abis := props.GetString("ro.product.cpu.abilist", props.GetString("ro.product.cpu.abi", ""))
+	for _, abi := range strings.Split(abis, ",") {
+		if abi = strings.TrimSpace(abi); abi != "" {
+			spec.SupportedAbis = append(spec.SupportedAbis, abi)
+		}
+	}
+
+	spec.SdkVersion, err = strconv.Atoi(props.GetString("ro.build.version.sdk", ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid sdk version: %w", err)
+	}
+
+	density := props.GetString("ro.sf.lcd_density", props.GetString("qemu.sf.lcd_density", ""))
+	spec.ScreenDensity, _ = strconv.Atoi(density)
+
+	locale := props.GetString("persist.sys.locale", props.GetString("ro.product.locale", ""))
+	if locale == "" {
+		if language := props.GetString("persist.sys.language", props.GetString("ro.product.locale.language", "")); language != "" {
+			locale = language
+			if country := props.GetString("persist.sys.country", props.GetString("ro.product.locale.region", "")); country != "" {
+				locale += "-" + country
+			}
+		}
+	}
+	if locale != "" {
+		spec.SupportedLocales = append(spec.SupportedLocales, locale)
+	}
+
+	return spec, nil
+}
+
+// endregion DeviceSpec
+
+// region Archive
+
+// Archive is an .apks archive produced by "bundletool build-apks"
+type Archive struct {
+	Toc    *Toc
+	reader *zip.ReadCloser
+}
+
+// Open opens the given .apks archive and reads its table of contents
+func Open(filename string) (*Archive, error) {
+	if strings.EqualFold(filepath.Ext(filename), ".aab") {
+		return nil, errors.New("app bundles must be converted to .apks with bundletool build-apks")
+	}
+
+	reader, err := zip.OpenReader(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	toc, err := readToc(&reader.Reader)
+	if err != nil {
+		_ = reader.Close()
+		return nil, err
+	}
+
+	return &Archive{Toc: toc, reader: reader}, nil
+}
+
+func (a *Archive) Close() error {
+	return a.reader.Close()
+}
+
+// SelectApks returns the apks matching the given device spec.
+// Together with the install-time modules, the optional modules listed are selected as well
+func (a *Archive) SelectApks(spec DeviceSpec, modules ...string) ([]ApkDescription, error) {
+	variant := a.selectVariant(spec)
+	if variant == nil {
+		return nil, ErrNoMatchingVariant
+	}
+
+	var result []ApkDescription
+	for _, set := range variant.ApkSets {
+		if !moduleSelected(set, modules) {
+			continue
+		}
+		for _, apk := range set.Apks {
+			if apkMatches(apk.Targeting, spec) {
+				result = append(result, apk)
+			}
+		}
+	}
+
+	if len(result) == 0 {
+		return nil, ErrNoMatchingVariant
+	}
+	return result, nil
+}
+
+// Install selects the apks matching the device and installs them through a single install session
+func (a *Archive) Install(pm packagemanager.PackageManager, options *packagemanager.InstallSessionOptions, modules ...string) error {
+	spec, err := GetDeviceSpec(pm.Shell)
+	if err != nil {
+		return err
+	}
+
+	apks, err := a.SelectApks(*spec, modules...)
+	if err != nil {
+		return err
+	}
+
+	files := make([]*zip.File, len(apks))
+	sessionOptions := packagemanager.InstallSessionOptions{}
+	if options != nil {
+		sessionOptions = *options
+	}
+	sessionOptions.MultiPackage = false
+	sessionOptions.TotalSize = 0
+
+	for i, apk := range apks {
+		files[i] = a.find(apk.Path)
+		if files[i] == nil {
+			return fmt.Errorf("%s not found in archive", apk.Path)
+		}
+		sessionOptions.TotalSize += int64(files[i].UncompressedSize64)
+	}
+
+	session, err := pm.CreateInstallSession(&sessionOptions)
+	if err != nil {
+		return err
+	}
+
+	for i, file := range files {
+		if err := writeFile(session, fmt.Sprintf("%d_%s", i, path.Base(file.Name)), file); err != nil {
+			_ = session.Abandon()
+			return err
+		}
+	}
+
+	if err := session.Commit(); err != nil {
+		_ = session.Abandon()
+		return err
+	}
+	return nil
+}
+
+func (a *Archive) find(name string) *zip.File {
+	for _, file := range a.reader.File {
+		if file.Name == name {
+			return file
+		}
+	}
+	return nil
+}
+
+func (a *Archive) selectVariant(spec DeviceSpec) *Variant {
+	var best *Variant
+	bestAbiRank := 0
+
+	for i := range a.Toc.Variants {
+		variant := &a.Toc.Variants[i]
+		t := variant.Targeting
+		if t.Unsupported || t.MinSdk > spec.SdkVersion {
+			continue
+		}
+
+		abiRank := 0
+		if len(t.Abis) > 0 {
+			abiRank = bestRank(spec.SupportedAbis, t.Abis)
+			if abiRank < 0 {
+				continue
+			}
+		}
+
+		if best == nil ||
+			t.MinSdk > best.Targeting.MinSdk ||
+			(t.MinSdk == best.Targeting.MinSdk && abiRank < bestAbiRank) ||
+			(t.MinSdk == best.Targeting.MinSdk && abiRank == bestAbiRank && variant.Number > best.Number) {
+			best = variant
+			bestAbiRank = abiRank
+		}
+	}
+	return best
+}
+
+// endregion Archive
+
+func readToc(reader *zip.Reader) (*Toc, error) {
+	for _, file := range reader.File {
+		if file.Name != tocFilename {
+			continue
+		}
+
+		r, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		return parseToc(data)
+	}
+	return nil, fmt.Errorf("%s not found, not a valid .apks archive", tocFilename)
+}
+
+func writeFile(session *packagemanager.InstallSession, name string, file *zip.File) error {
+	r, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return session.WriteReader(name, int64(file.UncompressedSize64), r)
+}
+
+func moduleSelected(set ApkSet, modules []string) bool {
+	if set.ModuleName == "base" || set.DeliveryType == DeliveryInstallTime {
+		return true
+	}
+	for _, module := range modules {
+		if module == set.ModuleName {
+			return true
+		}
+	}
+	return false
+}
+
+func apkMatches(t Targeting, spec DeviceSpec) bool {
+	if t.Unsupported || t.LanguageFallback || t.MinSdk > spec.SdkVersion {
+		return false
+	}
+
+	if len(t.Abis) > 0 {
+		candidates := append(append([]string{}, t.Abis...), t.AbiAlternatives...)
+		rank := bestRank(spec.SupportedAbis, candidates)
+		if rank < 0 || !contains(t.Abis, spec.SupportedAbis[rank]) {
+			return false
+		}
+	}
+
+	if len(t.Densities) > 0 {
+		candidates := append(append([]int{}, t.Densities...), t.DensityAlternative...)
+		best := bestDensity(spec.ScreenDensity, candidates)
+		found := false
+		for _, d := range t.Densities {
+			found = found || d == best
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(t.Languages) > 0 {
+		found := false
+		for _, locale := range spec.SupportedLocales {
+			for _, language := range t.Languages {
+				found = found || localeMatches(locale, language)
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// bestRank returns the index of the first value in preferences found in candidates, -1 if not found
+func bestRank(preferences []string, candidates []string) int {
+	for i, value := range preferences {
+		if contains(candidates, value) {
+			return i
+		}
+	}
+	return -1
+}
+
+// bestDensity returns the smallest density greater or equal to the device one, or the highest available
+func bestDensity(density int, candidates []int) int {
+	sorted := append([]int{}, candidates...)
+	sort.Ints(sorted)
+	for _, d := range sorted {
+		if d >= density {
+			return d
+		}
+	}
+	return sorted[len(sorted)-1]
+}
+
+func localeMatches(locale string, language string) bool {
+	locale = strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+	language = strings.ToLower(strings.ReplaceAll(language, "_", "-"))
+	return locale == language || strings.SplitN(locale, "-", 2)[0] == strings.SplitN(language, "-", 2)[0]
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,128 @@
+package apks
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// minimal protobuf wire format decoder, used to read the toc.pb of an .apks archive
+// without depending on the generated bundletool protos
+
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var errInvalidProto = errors.New("invalid protobuf message")
+
+type protoField struct {
+	Number int
+	Wire   int
+	Varint uint64
+	Bytes  []byte
+}
+
+func decodeProto(data []byte) ([]protoField, error) {
+	var fields []protoField
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, errInvalidProto
+		}
+		data = data[n:]
+
+		field := protoField{Number: int(key >> 3), Wire: int(key & 7)}
+		switch field.Wire {
+		case wireVarint:
+			v, n := binary.Uvarint(data)
+			if n <= 0 {
+				return nil, errInvalidProto
+			}
+			field.Varint = v
+			data = data[n:]
+		case wireFixed64:
+			if len(data) < 8 {
+				return nil, errInvalidProto
+			}
+			field.Varint = binary.LittleEndian.Uint64(data)
+			data = data[8:]
+		case wireBytes:
+			size, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < size {
+				return nil, errInvalidProto
+			}
+			field.Bytes = data[n : n+int(size)]
+			data = data[n+int(size):]
+		case wireFixed32:
+			if len(data) < 4 {
+				return nil, errInvalidProto
+			}
+			field.Varint = uint64(binary.LittleEndian.Uint32(data))
+			data = data[4:]
+		default:
+			return nil, errInvalidProto
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// decodeMessages decodes every embedded message of the given field number
+func decodeMessages(fields []protoField, number int) [][]protoField {
+	var result [][]protoField
+	for _, f := range fields {
+		if f.Number == number && f.Wire == wireBytes {
+			if message, err := decodeProto(f.Bytes); err == nil {
+				result = append(result, message)
+			}
+		}
+	}
+	return result
+}
+
+func decodeMessage(fields []protoField, number int) []protoField {
+	messages := decodeMessages(fields, number)
+	if len(messages) == 0 {
+		return nil
+	}
+	return messages[len(messages)-1]
+}
+
+func decodeStrings(fields []protoField, number int) []string {
+	var result []string
+	for _, f := range fields {
+		if f.Number == number && f.Wire == wireBytes {
+			result = append(result, string(f.Bytes))
+		}
+	}
+	return result
+}
+
+func decodeString(fields []protoField, number int) string {
+	values := decodeStrings(fields, number)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[len(values)-1]
+}
+
+func decodeVarint(fields []protoField, number int) uint64 {
+	var value uint64
+	for _, f := range fields {
+		if f.Number == number && f.Wire == wireVarint {
+			value = f.Varint
+		}
+	}
+	return value
+}
+
+func hasField(fields []protoField, number int) bool {
+	for _, f := range fields {
+		if f.Number == number {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,271 @@
+package apks
+
+import (
+	"fmt"
+	"strings"
+)
+
+// region Targeting
+
+type DeliveryType int
+
+const (
+	DeliveryUnknown DeliveryType = iota
+	DeliveryInstallTime
+	DeliveryOnDemand
+	DeliveryFastFollow
+)
+
+var abiAliases = [...]string{
+	"",
+	"armeabi",
+	"armeabi-v7a",
+	"arm64-v8a",
+	"x86",
+	"x86_64",
+	"mips",
+	"mips64",
+	"riscv64",
+}
+
+var densityAliases = [...]int{
+	0,
+	0,   // NODPI
+	120, // LDPI
+	160, // MDPI
+	213, // TVDPI
+	240, // HDPI
+	320, // XHDPI
+	480, // XXHDPI
+	640, // XXXHDPI
+}
+
+// Targeting is the subset of the bundletool VariantTargeting/ApkTargeting used to select the apks for a device.
+// Values are the targeted values, Alternatives the values targeted by the sibling apks
+type Targeting struct {
+	MinSdk             int
+	SdkAlternatives    []int
+	Abis               []string
+	AbiAlternatives    []string
+	Densities          []int
+	DensityAlternative []int
+	Languages          []string
+	// LanguageFallback is true for the split containing the languages not targeted by any other split
+	LanguageFallback bool
+	// Unsupported is true when the apk uses a targeting dimension not handled by this package
+	// (texture compression format, device tier, ...) with a non fallback value
+	Unsupported bool
+}
+
+func (t Targeting) String() string {
+	var s []string
+	if t.MinSdk > 0 {
+		s = append(s, fmt.Sprintf("sdk=%d", t.MinSdk))
+	}
+	if len(t.Abis) > 0 {
+		s = append(s, fmt.Sprintf("abi=%s", strings.Join(t.Abis, ",")))
+	}
+	if len(t.Densities) > 0 {
+		s = append(s, fmt.Sprintf("density=%v", t.Densities))
+	}
+	if len(t.Languages) > 0 {
+		s = append(s, fmt.Sprintf("language=%s", strings.Join(t.Languages, ",")))
+	}
+	return "{" + strings.Join(s, " ") + "}"
+}
+
+// endregion Targeting
+
+// region Toc
+
+// Toc is the table of contents (toc.pb) of an .apks archive
+type Toc struct {
+	PackageName string
+	Variants    []Variant
+}
+
+type Variant struct {
+	Number    int
+	Targeting Targeting
+	ApkSets   []ApkSet
+}
+
+type ApkSet struct {
+	ModuleName   string
+	DeliveryType DeliveryType
+	Apks         []ApkDescription
+}
+
+type ApkDescription struct {
+	// Path is the path of the apk inside the archive
+	Path        string
+	Targeting   Targeting
+	SplitID     string
+	MasterSplit bool
+	Standalone  bool
+}
+
+// endregion Toc
+
+// BuildApksResult
+// 1: variant, 4: package_name
+func parseToc(data []byte) (*Toc, error) {
+	fields, err := decodeProto(data)
+	if err != nil {
+		return nil, err
+	}
+
+	toc := &Toc{PackageName: decodeString(fields, 4)}
+	for _, v := range decodeMessages(fields, 1) {
+		toc.Variants = append(toc.Variants, parseVariant(v))
+	}
+	return toc, nil
+}
+
+// Variant
+// 1: targeting, 2: apk_set, 3: variant_number
+func parseVariant(fields []protoField) Variant {
+	variant := Variant{
+		Number:    int(decodeVarint(fields, 3)),
+		Targeting: parseVariantTargeting(decodeMessage(fields, 1)),
+	}
+	for _, s := range decodeMessages(fields, 2) {
+		variant.ApkSets = append(variant.ApkSets, parseApkSet(s))
+	}
+	return variant
+}
+
+// ApkSet
+// 1: module_metadata (1: name, 6: delivery_type), 2: apk_description
+func parseApkSet(fields []protoField) ApkSet {
+	metadata := decodeMessage(fields, 1)
+	set := ApkSet{
+		ModuleName:   decodeString(metadata, 1),
+		DeliveryType: DeliveryType(decodeVarint(metadata, 6)),
+	}
+	for _, d := range decodeMessages(fields, 2) {
+		set.Apks = append(set.Apks, parseApkDescription(d))
+	}
+	return set
+}
+
+// ApkDescription
+// 1: targeting, 2: path, 3: split_apk_metadata (1: split_id, 2: is_master_split), 4: standalone_apk_metadata
+func parseApkDescription(fields []protoField) ApkDescription {
+	split := decodeMessage(fields, 3)
+	return ApkDescription{
+		Path:        decodeString(fields, 2),
+		Targeting:   parseApkTargeting(decodeMessage(fields, 1)),
+		SplitID:     decodeString(split, 1),
+		MasterSplit: decodeVarint(split, 2) != 0,
+		Standalone:  hasField(fields, 4),
+	}
+}
+
+// VariantTargeting
+// 1: sdk_version_targeting, 2: abi_targeting, 3: screen_density_targeting, 4: multi_abi_targeting
+func parseVariantTargeting(fields []protoField) Targeting {
+	targeting := Targeting{}
+	parseSdkTargeting(decodeMessage(fields, 1), &targeting)
+	parseAbiTargeting(decodeMessage(fields, 2), &targeting)
+	parseDensityTargeting(decodeMessage(fields, 3), &targeting)
+	parseMultiAbiTargeting(decodeMessage(fields, 4), &targeting)
+	targeting.Unsupported = hasTargetedValue(fields, 5)
+	return targeting
+}
+
+// ApkTargeting
+// 1: abi, 2: graphics_api, 3: language, 4: screen_density, 5: sdk_version, 6: texture_compression_format,
+// 7: multi_abi, 8: sanitizer, 9: device_tier, 10: country_set
+func parseApkTargeting(fields []protoField) Targeting {
+	targeting := Targeting{}
+	parseAbiTargeting(decodeMessage(fields, 1), &targeting)
+	parseLanguageTargeting(decodeMessage(fields, 3), &targeting)
+	parseDensityTargeting(decodeMessage(fields, 4), &targeting)
+	parseSdkTargeting(decodeMessage(fields, 5), &targeting)
+	parseMultiAbiTargeting(decodeMessage(fields, 7), &targeting)
+	for _, number := range []int{2, 6, 8, 9, 10} {
+		if hasTargetedValue(fields, number) {
+			targeting.Unsupported = true
+		}
+	}
+	return targeting
+}
+
+// hasTargetedValue returns true if the targeting message with the given number has at least one value (field 1)
+func hasTargetedValue(fields []protoField, number int) bool {
+	message := decodeMessage(fields, number)
+	return message != nil && hasField(message, 1)
+}
+
+// SdkVersionTargeting 1: value, 2: alternatives. SdkVersion 1: min (Int32Value 1: value)
+func parseSdkTargeting(fields []protoField, targeting *Targeting) {
+	for _, v := range decodeMessages(fields, 1) {
+		targeting.MinSdk = int(decodeVarint(decodeMessage(v, 1), 1))
+	}
+	for _, v := range decodeMessages(fields, 2) {
+		targeting.SdkAlternatives = append(targeting.SdkAlternatives, int(decodeVarint(decodeMessage(v, 1), 1)))
+	}
+}
+
+// AbiTargeting 1: value, 2: alternatives. Abi 1: alias
+func parseAbiTargeting(fields []protoField, targeting *Targeting) {
+	for _, v := range decodeMessages(fields, 1) {
+		targeting.Abis = append(targeting.Abis, abiAlias(decodeVarint(v, 1)))
+	}
+	for _, v := range decodeMessages(fields, 2) {
+		targeting.AbiAlternatives = append(targeting.AbiAlternatives, abiAlias(decodeVarint(v, 1)))
+	}
+}
+
+// MultiAbiTargeting 1: value, 2: alternatives. MultiAbi 1: abi.
+// Only the first abi of each set is considered
+func parseMultiAbiTargeting(fields []protoField, targeting *Targeting) {
+	for _, v := range decodeMessages(fields, 1) {
+		if abis := decodeMessages(v, 1); len(abis) > 0 {
+			targeting.Abis = append(targeting.Abis, abiAlias(decodeVarint(abis[0], 1)))
+		}
+	}
+	for _, v := range decodeMessages(fields, 2) {
+		if abis := decodeMessages(v, 1); len(abis) > 0 {
+			targeting.AbiAlternatives = append(targeting.AbiAlternatives, abiAlias(decodeVarint(abis[0], 1)))
+		}
+	}
+}
+
+// ScreenDensityTargeting 1: value, 2: alternatives. ScreenDensity 1: density_alias, 2: density_dpi
+func parseDensityTargeting(fields []protoField, targeting *Targeting) {
+	for _, v := range decodeMessages(fields, 1) {
+		targeting.Densities = append(targeting.Densities, densityDpi(v))
+	}
+	for _, v := range decodeMessages(fields, 2) {
+		targeting.DensityAlternative = append(targeting.DensityAlternative, densityDpi(v))
+	}
+}
+
+// LanguageTargeting 1: value, 2: alternatives
+func parseLanguageTargeting(fields []protoField, targeting *Targeting) {
+	if fields == nil {
+		return
+	}
+	targeting.Languages = decodeStrings(fields, 1)
+	targeting.LanguageFallback = len(targeting.Languages) == 0 && len(decodeStrings(fields, 2)) > 0
+}
+
+func abiAlias(value uint64) string {
+	if value < uint64(len(abiAliases)) {
+		return abiAliases[value]
+	}
+	return ""
+}
+
+func densityDpi(fields []protoField) int {
+	if hasField(fields, 2) {
+		return int(decodeVarint(fields, 2))
+	}
+	alias := decodeVarint(fields, 1)
+	if alias < uint64(len(densityAliases)) {
+		return densityAliases[alias]
+	}
+	return 0
+}
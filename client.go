@@ -16,10 +16,12 @@ import (
 	streams "github.com/sephiroth74/go_streams"
 
 	"github.com/reactivex/rxgo/v2"
+	"github.com/sephiroth74/go_adb_client/apk"
 	"github.com/sephiroth74/go_adb_client/connection"
 	"github.com/sephiroth74/go_adb_client/events"
 	"github.com/sephiroth74/go_adb_client/logging"
 	"github.com/sephiroth74/go_adb_client/mdns"
+	"github.com/sephiroth74/go_adb_client/packagemanager"
 	"github.com/sephiroth74/go_adb_client/process"
 	"github.com/sephiroth74/go_adb_client/shell"
 	"github.com/sephiroth74/go_adb_client/types"
@@ -203,23 +205,32 @@ func (c Client) Push(src string, dst string) (process.OutputResult, error) {
 	return c.Conn.Push(c.Address.GetSerialAddress(), src, dst)
 }
 
+// Install installs the host apk file src. With options.Check, the apk is compared with the installed package
+// first and the install is rejected with a *packagemanager.InstallError on a version downgrade or a signature mismatch
 func (c Client) Install(src string, options *InstallOptions) (process.OutputResult, error) {
-	var args []string
-	if options != nil {
-		if options.KeepData {
-			args = append(args, "-r")
-		}
-		if options.AllowTestPackages {
-			args = append(args, "-t")
-		}
-		if options.AllowDowngrade {
-			args = append(args, "-d")
+	if options != nil && options.Check {
+		local, err := apk.Open(src)
+		if err != nil {
+			return process.OutputResult{}, err
 		}
-		if options.GrantPermissions {
-			args = append(args, "-g")
+		pm := packagemanager.PackageManager{Shell: c.Shell}
+		if err := pm.CheckInstall(local, &packagemanager.InstallOptions{AllowVersionCodeDowngrade: options.AllowDowngrade}); err != nil {
+			return process.OutputResult{}, err
 		}
 	}
-	return c.Conn.Install(c.Address.GetSerialAddress(), src, args...)
+	return c.Conn.Install(c.Address.GetSerialAddress(), src, options.args()...)
+}
+
+// InstallMultiple installs the base apk and the splits of a single package.
+// srcs are the host apk files
+func (c Client) InstallMultiple(srcs []string, options *InstallOptions) (process.OutputResult, error) {
+	return c.Conn.InstallMultiple(c.Address.GetSerialAddress(), srcs, options.args()...)
+}
+
+// InstallMultiPackage atomically installs multiple packages, each one from a single apk.
+// srcs are the host apk files
+func (c Client) InstallMultiPackage(srcs []string, options *InstallOptions) (process.OutputResult, error) {
+	return c.Conn.InstallMultiPackage(c.Address.GetSerialAddress(), srcs, options.args()...)
 }
 
 func (c Client) Uninstall(packageName string) (process.OutputResult, error) {
@@ -434,4 +445,25 @@ type InstallOptions struct {
 	AllowDowngrade bool
 	// -g grant all runtime permissions
 	GrantPermissions bool
+	// Check compares the apk with the installed package before installing (see packagemanager.CheckInstall)
+	Check bool
+}
+
+func (o *InstallOptions) args() []string {
+	var args []string
+	if o != nil {
+		if o.KeepData {
+			args = append(args, "-r")
+		}
+		if o.AllowTestPackages {
+			args = append(args, "-t")
+		}
+		if o.AllowDowngrade {
+			args = append(args, "-d")
+		}
+		if o.GrantPermissions {
+			args = append(args, "-g")
+		}
+	}
+	return args
 }
@@ -193,6 +193,18 @@ func (c Connection) Install(addr string, src string, args ...string) (process.Ou
 	return process.SimpleOutput(cmd, c.Verbose)
 }
 
+// InstallMultiple installs the given apks (base and splits) of a single package using "adb install-multiple"
+func (c Connection) InstallMultiple(addr string, srcs []string, args ...string) (process.OutputResult, error) {
+	cmd := c.NewAdbCommand().WithCommand("install-multiple").WithSerial(addr).WithArgs(args...).AddArgs(srcs...)
+	return process.SimpleOutput(cmd, c.Verbose)
+}
+
+// InstallMultiPackage atomically installs multiple packages using "adb install-multi-package"
+func (c Connection) InstallMultiPackage(addr string, srcs []string, args ...string) (process.OutputResult, error) {
+	cmd := c.NewAdbCommand().WithCommand("install-multi-package").WithSerial(addr).WithArgs(args...).AddArgs(srcs...)
+	return process.SimpleOutput(cmd, c.Verbose)
+}
+
 func (c Connection) Uninstall(addr string, packageName string, args ...string) (process.OutputResult, error) {
 	cmd := c.NewAdbCommand().WithCommand("uninstall").WithSerial(addr).WithArgs(args...).AddArgs(packageName)
 	return process.SimpleOutput(cmd, c.Verbose)
@@ -2,13 +2,20 @@ package adbclient
 
 import (
 	"bufio"
-	"io"
+	"image"
 	"os"
 
 	"github.com/sephiroth74/go_adb_client/activitymanager"
+	"github.com/sephiroth74/go_adb_client/apks"
 	"github.com/sephiroth74/go_adb_client/input"
+	"github.com/sephiroth74/go_adb_client/inputmanager"
 	"github.com/sephiroth74/go_adb_client/packagemanager"
+	"github.com/sephiroth74/go_adb_client/powermanager"
 	"github.com/sephiroth74/go_adb_client/process"
+	"github.com/sephiroth74/go_adb_client/screencap"
+	"github.com/sephiroth74/go_adb_client/telemetry"
+	"github.com/sephiroth74/go_adb_client/ui"
+	"github.com/sephiroth74/go_adb_client/usermanager"
 )
 
 type Device struct {
@@ -33,6 +40,48 @@ func (d Device) PackageManager() *packagemanager.PackageManager {
 	}
 }
 
+func (d Device) UserManager() *usermanager.UserManager {
+	return &usermanager.UserManager{
+		Shell: d.Client.Shell,
+	}
+}
+
+func (d Device) InputManager() *inputmanager.InputManager {
+	return &inputmanager.InputManager{
+		Shell: d.Client.Shell,
+	}
+}
+
+func (d Device) PowerManager() *powermanager.PowerManager {
+	return &powermanager.PowerManager{
+		Shell: d.Client.Shell,
+	}
+}
+
+func (d Device) UiAutomator() *ui.UiAutomator {
+	return &ui.UiAutomator{
+		Shell: d.Client.Shell,
+	}
+}
+
+func (d Device) Telemetry() *telemetry.Telemetry {
+	return &telemetry.Telemetry{
+		Shell: d.Client.Shell,
+	}
+}
+
+// InstallApks installs the apks matching this device from an .apks archive produced by bundletool.
+// modules are the optional (on-demand) modules to install together with the install-time ones
+func (d Device) InstallApks(src string, options *packagemanager.InstallSessionOptions, modules ...string) error {
+	archive, err := apks.Open(src)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	return archive.Install(*d.PackageManager(), options, modules...)
+}
+
 func (d Device) Name() *string {
 	return d.Client.Shell.GetProp("ro.build.product")
 }
@@ -52,14 +101,51 @@ func (d Device) SaveScreenCap(output string) (process.OutputResult, error) {
 }
 
 func (d Device) WriteScreenCap(output *os.File) (process.OutputResult, error) {
-	var writer io.Writer = bufio.NewWriter(output)
+	writer := bufio.NewWriter(output)
 	cmd := d.Client.NewAdbCommand().WithCommand("exec-out").WithArgs("screencap", "-p").WithStdOut(writer)
-	return process.SimpleOutput(cmd, d.Client.Conn.Verbose)
-	// var pb = d.Client.NewProcess()
-	// pb.WithCommand("exec-out")
-	// pb.WithArgs("screencap", "-p")
-	// pb.WithStdout(&writer)
-	// return pb.Invoke()
+	result, err := process.SimpleOutput(cmd, d.Client.Conn.Verbose)
+	if err != nil {
+		return result, err
+	}
+	return result, writer.Flush()
+}
+
+// Screenshot captures the main display with "exec-out screencap" in the raw format,
+// faster than the png encoding on the device
+func (d Device) Screenshot() (image.Image, error) {
+	return d.ScreenshotWithOptions(nil)
+}
+
+// ScreenshotWithOptions captures the options display and crops the options region
+func (d Device) ScreenshotWithOptions(options *screencap.Options) (image.Image, error) {
+	cmd := d.Client.NewAdbCommand().WithCommand("exec-out").WithArgs("screencap").AddArgs(options.Args()...)
+	result, err := process.SimpleOutput(cmd, d.Client.Conn.Verbose)
+	if err != nil {
+		return nil, err
+	}
+
+	if !result.IsOk() {
+		return nil, result.NewError()
+	}
+
+	img, err := screencap.DecodeRaw(result.StdOut.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return options.Crop(img), nil
+}
+
+// GetDisplays returns the physical displays which can be captured, with "dumpsys SurfaceFlinger --display-id"
+func (d Device) GetDisplays() ([]screencap.Display, error) {
+	result, err := d.Client.Shell.DumpSys("SurfaceFlinger", "--display-id")
+	if err != nil {
+		return nil, err
+	}
+
+	if !result.IsOk() {
+		return nil, result.NewError()
+	}
+	return screencap.ParseDisplays(result.Output()), nil
 }
 
 // PowerOffOn send the power button input key
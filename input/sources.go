@@ -0,0 +1,119 @@
+package input
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SourceFlags are the sources of an input device (InputDevice.SOURCE_*)
+type SourceFlags uint32
+
+const (
+	SOURCE_KEYBOARD         SourceFlags = 0x00000101
+	SOURCE_DPAD             SourceFlags = 0x00000201
+	SOURCE_GAMEPAD          SourceFlags = 0x00000401
+	SOURCE_TOUCHSCREEN      SourceFlags = 0x00001002
+	SOURCE_MOUSE            SourceFlags = 0x00002002
+	SOURCE_STYLUS           SourceFlags = 0x00004002
+	SOURCE_BLUETOOTH_STYLUS SourceFlags = 0x0000c002
+	SOURCE_TRACKBALL        SourceFlags = 0x00010004
+	SOURCE_MOUSE_RELATIVE   SourceFlags = 0x00020004
+	SOURCE_TOUCHPAD         SourceFlags = 0x00100008
+	SOURCE_TOUCH_NAVIGATION SourceFlags = 0x00200000
+	SOURCE_ROTARY_ENCODER   SourceFlags = 0x00400000
+	SOURCE_JOYSTICK         SourceFlags = 0x01000010
+	SOURCE_HDMI             SourceFlags = 0x02000001
+	SOURCE_SENSOR           SourceFlags = 0x04000000
+)
+
+var sourceFlagNames = []struct {
+	flag SourceFlags
+	name string
+}{
+	{SOURCE_KEYBOARD, "KEYBOARD"},
+	{SOURCE_DPAD, "DPAD"},
+	{SOURCE_GAMEPAD, "GAMEPAD"},
+	{SOURCE_TOUCHSCREEN, "TOUCHSCREEN"},
+	{SOURCE_MOUSE, "MOUSE"},
+	{SOURCE_STYLUS, "STYLUS"},
+	{SOURCE_BLUETOOTH_STYLUS, "BLUETOOTH_STYLUS"},
+	{SOURCE_TRACKBALL, "TRACKBALL"},
+	{SOURCE_MOUSE_RELATIVE, "MOUSE_RELATIVE"},
+	{SOURCE_TOUCHPAD, "TOUCHPAD"},
+	{SOURCE_TOUCH_NAVIGATION, "TOUCH_NAVIGATION"},
+	{SOURCE_ROTARY_ENCODER, "ROTARY_ENCODER"},
+	{SOURCE_JOYSTICK, "JOYSTICK"},
+	{SOURCE_HDMI, "HDMI"},
+	{SOURCE_SENSOR, "SENSOR"},
+}
+
+// Has returns true if all the bits of the source are set
+func (f SourceFlags) Has(source SourceFlags) bool {
+	return f&source == source
+}
+
+func (f SourceFlags) String() string {
+	var names []string
+	for _, s := range sourceFlagNames {
+		if f.Has(s.flag) {
+			names = append(names, s.name)
+		}
+	}
+	if len(names) == 0 {
+		return fmt.Sprintf("0x%08x", uint32(f))
+	}
+	return strings.Join(names, " | ")
+}
+
+// ParseSourceFlags parses the sources printed by "dumpsys input", either hex (0x00001002) or names (KEYBOARD | DPAD).
+// The unknown names (e.g. sources added by newer android versions) are skipped
+func ParseSourceFlags(value string) (SourceFlags, error) {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "0x") {
+		flags, err := strconv.ParseUint(value[2:], 16, 32)
+		if err != nil {
+			return 0, fmt.Errorf("invalid sources: %s", value)
+		}
+		return SourceFlags(flags), nil
+	}
+
+	var flags SourceFlags
+	for _, name := range strings.Split(value, "|") {
+		name = strings.TrimSpace(name)
+		for _, s := range sourceFlagNames {
+			if s.name == name {
+				flags |= s.flag
+				break
+			}
+		}
+	}
+	return flags, nil
+}
+
+// Flags returns the source flags of the input source
+func (d InputSource) Flags() SourceFlags {
+	switch d {
+	case DPAD:
+		return SOURCE_DPAD
+	case KEYBOARD:
+		return SOURCE_KEYBOARD
+	case MOUSE:
+		return SOURCE_MOUSE
+	case TOUCHPAD:
+		return SOURCE_TOUCHPAD
+	case GAMEPAD:
+		return SOURCE_GAMEPAD
+	case TOUCHNAVIGATION:
+		return SOURCE_TOUCH_NAVIGATION
+	case JOYSTICK:
+		return SOURCE_JOYSTICK
+	case TOUCHSCREEEN:
+		return SOURCE_TOUCHSCREEN
+	case STYLUS:
+		return SOURCE_STYLUS
+	case TRACKBALL:
+		return SOURCE_TRACKBALL
+	}
+	return 0
+}
@@ -0,0 +1,59 @@
+package input
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// TextChunkSize is the default length of the chunks sent with a single "input text".
+// Long strings are dropped or truncated by some devices
+const TextChunkSize = 100
+
+// IsTypeable returns true if "input text" can type the value: it only types printable ascii, tabs and newlines
+// (the characters of the virtual keyboard key map)
+func IsTypeable(value string) bool {
+	for _, r := range value {
+		if (r < 0x20 || r > 0x7e) && r != '\n' && r != '\t' {
+			return false
+		}
+	}
+	return true
+}
+
+// EscapeText escapes the value for "input text", which converts "%s" to a space.
+// The spaces are sent as "%s" so that they are preserved by older android versions.
+// A literal "%s" cannot be escaped, use TextChunks to split it across two "input text"
+func EscapeText(value string) string {
+	return strings.ReplaceAll(value, " ", "%s")
+}
+
+// TextChunks splits the value in escaped chunks of at most size characters (see EscapeText), each
+// one to be sent with a single "input text". A literal "%s" is split across two chunks
+func TextChunks(value string, size int) []string {
+	if size <= 0 {
+		size = TextChunkSize
+	}
+
+	var chunks []string
+	var current strings.Builder
+	count := 0
+	flush := func() {
+		if current.Len() > 0 {
+			chunks = append(chunks, EscapeText(current.String()))
+			current.Reset()
+			count = 0
+		}
+	}
+
+	for i, r := range value {
+		current.WriteRune(r)
+		count++
+
+		next, _ := utf8.DecodeRuneInString(value[i+utf8.RuneLen(r):])
+		if count >= size || (r == '%' && next == 's') {
+			flush()
+		}
+	}
+	flush()
+	return chunks
+}
@@ -0,0 +1,281 @@
+package inputmanager
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sephiroth74/go_adb_client/input"
+)
+
+var (
+	hubDeviceRegexp    = regexp.MustCompile(`^(-?\d+): (.*)$`)
+	readerDeviceRegexp = regexp.MustCompile(`^Device (-?\d+): (.*)$`)
+	identifierRegexp   = regexp.MustCompile(`(bus|vendor|product|version)=0x([0-9a-fA-F]+)`)
+	hubDevicesRegexp   = regexp.MustCompile(`^EventHub Devices: \[\s*(-?\d+)`)
+	motionRangeRegexp  = regexp.MustCompile(`^(\w+): source=([^,]+), min=(-?[\d.]+), max=(-?[\d.]+), flat=(-?[\d.]+), fuzz=(-?[\d.]+), resolution=(-?[\d.]+)`)
+)
+
+// the device classes (InputDeviceClass), printed as hex by older android versions
+var deviceClassNames = []struct {
+	flag uint32
+	name string
+}{
+	{0x00000001, "KEYBOARD"},
+	{0x00000002, "ALPHAKEY"},
+	{0x00000004, "TOUCH"},
+	{0x00000008, "CURSOR"},
+	{0x00000010, "TOUCH_MT"},
+	{0x00000020, "DPAD"},
+	{0x00000040, "GAMEPAD"},
+	{0x00000080, "SWITCH"},
+	{0x00000100, "JOYSTICK"},
+	{0x00000200, "VIBRATOR"},
+	{0x00000400, "MIC"},
+	{0x00000800, "EXTERNAL_STYLUS"},
+	{0x00001000, "ROTARY_ENCODER"},
+	{0x00002000, "SENSOR"},
+	{0x00004000, "BATTERY"},
+	{0x00008000, "LIGHT"},
+	{0x40000000, "VIRTUAL"},
+	{0x80000000, "EXTERNAL"},
+}
+
+// MotionRange is the range of a motion axis of an input device
+type MotionRange struct {
+	Axis       string
+	Source     input.SourceFlags
+	Min        float64
+	Max        float64
+	Flat       float64
+	Fuzz       float64
+	Resolution float64
+}
+
+// InputDevice is an input device, as reported by "dumpsys input"
+type InputDevice struct {
+	Id   int
+	Name string
+	// Path is the device node (e.g. /dev/input/event3), <virtual> for the virtual keyboard
+	Path       string
+	Descriptor string
+	Location   string
+	Enabled    bool
+	External   bool
+	// Classes are the capabilities found by the EventHub (e.g. KEYBOARD, TOUCH_MT)
+	Classes             []string
+	Sources             input.SourceFlags
+	KeyboardType        int
+	Bus                 uint16
+	Vendor              uint16
+	Product             uint16
+	Version             uint16
+	KeyLayoutFile       string
+	KeyCharacterMapFile string
+	ConfigurationFile   string
+	MotionRanges        []MotionRange
+}
+
+func (d InputDevice) String() string {
+	return fmt.Sprintf("InputDevice{Id:%d, Name:%s, Path:%s, Sources:%s, Classes:%v}", d.Id, d.Name, d.Path, d.Sources, d.Classes)
+}
+
+// HasClass returns true if the device has the given class (e.g. TOUCH_MT)
+func (d InputDevice) HasClass(class string) bool {
+	for _, c := range d.Classes {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// HasSource returns true if the device supports the input source
+func (d InputDevice) HasSource(source input.InputSource) bool {
+	return d.Sources.Has(source.Flags())
+}
+
+// IsVirtual returns true for the virtual keyboard used by "input"
+func (d InputDevice) IsVirtual() bool {
+	return d.Id < 0 || d.HasClass("VIRTUAL")
+}
+
+func parseDeviceClasses(value string) []string {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "0x") {
+		flags, _ := strconv.ParseUint(value[2:], 16, 32)
+		var classes []string
+		for _, c := range deviceClassNames {
+			if uint32(flags)&c.flag != 0 {
+				classes = append(classes, c.name)
+			}
+		}
+		return classes
+	}
+
+	var classes []string
+	for _, name := range strings.Split(value, "|") {
+		if name = strings.TrimSpace(name); name != "" {
+			classes = append(classes, name)
+		}
+	}
+	return classes
+}
+
+// ParseInputDevices parses the output of "dumpsys input". The devices are read from the
+// "Event Hub State" section and completed with the "Input Reader State" section
+func ParseInputDevices(data string) []InputDevice {
+	var devices []*InputDevice
+	byId := map[int]*InputDevice{}
+
+	section := ""
+	var hubDevice *InputDevice
+	var readerDevice *InputDevice
+	inReaderDevice := false
+	readerIndent := 0
+
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimRight(line, "\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+
+		if indent == 0 {
+			section = trimmed
+			hubDevice, readerDevice = nil, nil
+			inReaderDevice = false
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(section, "Event Hub State"):
+			if m := hubDeviceRegexp.FindStringSubmatch(trimmed); m != nil && indent <= 4 {
+				id, _ := strconv.Atoi(m[1])
+				hubDevice = &InputDevice{Id: id, Name: m[2]}
+				devices = append(devices, hubDevice)
+				byId[id] = hubDevice
+				continue
+			}
+
+			if hubDevice == nil || indent <= 4 {
+				hubDevice = nil
+				continue
+			}
+
+			key, value, ok := strings.Cut(trimmed, ":")
+			if !ok {
+				continue
+			}
+			value = strings.TrimSpace(value)
+
+			switch key {
+			case "Classes":
+				hubDevice.Classes = parseDeviceClasses(value)
+			case "Path":
+				hubDevice.Path = value
+			case "Enabled":
+				hubDevice.Enabled = value == "true"
+			case "Descriptor":
+				hubDevice.Descriptor = value
+			case "Location":
+				hubDevice.Location = value
+			case "Identifier":
+				for _, m := range identifierRegexp.FindAllStringSubmatch(value, -1) {
+					number, _ := strconv.ParseUint(m[2], 16, 16)
+					switch m[1] {
+					case "bus":
+						hubDevice.Bus = uint16(number)
+					case "vendor":
+						hubDevice.Vendor = uint16(number)
+					case "product":
+						hubDevice.Product = uint16(number)
+					case "version":
+						hubDevice.Version = uint16(number)
+					}
+				}
+			case "KeyLayoutFile":
+				hubDevice.KeyLayoutFile = value
+			case "KeyCharacterMapFile":
+				hubDevice.KeyCharacterMapFile = value
+			case "ConfigurationFile":
+				hubDevice.ConfigurationFile = value
+			}
+
+		case strings.HasPrefix(section, "Input Reader State"):
+			if m := readerDeviceRegexp.FindStringSubmatch(trimmed); m != nil {
+				id, _ := strconv.Atoi(m[1])
+				readerDevice = byId[id]
+				inReaderDevice = true
+				readerIndent = indent
+				continue
+			}
+
+			if !inReaderDevice || indent <= readerIndent {
+				inReaderDevice = false
+				continue
+			}
+
+			// the reader device id differs from the EventHub id on recent versions
+			if m := hubDevicesRegexp.FindStringSubmatch(trimmed); m != nil {
+				id, _ := strconv.Atoi(m[1])
+				if device, ok := byId[id]; ok {
+					readerDevice = device
+				}
+				continue
+			}
+
+			if readerDevice == nil {
+				continue
+			}
+
+			if m := motionRangeRegexp.FindStringSubmatch(trimmed); m != nil {
+				motionRange := MotionRange{Axis: m[1]}
+				motionRange.Source, _ = input.ParseSourceFlags(m[2])
+				motionRange.Min, _ = strconv.ParseFloat(m[3], 64)
+				motionRange.Max, _ = strconv.ParseFloat(m[4], 64)
+				motionRange.Flat, _ = strconv.ParseFloat(m[5], 64)
+				motionRange.Fuzz, _ = strconv.ParseFloat(m[6], 64)
+				motionRange.Resolution, _ = strconv.ParseFloat(m[7], 64)
+				readerDevice.MotionRanges = append(readerDevice.MotionRanges, motionRange)
+				continue
+			}
+
+			key, value, ok := strings.Cut(trimmed, ":")
+			if !ok {
+				continue
+			}
+			value = strings.TrimSpace(value)
+
+			switch key {
+			case "Sources":
+				readerDevice.Sources, _ = input.ParseSourceFlags(value)
+			case "KeyboardType":
+				readerDevice.KeyboardType, _ = strconv.Atoi(value)
+			case "IsExternal":
+				readerDevice.External = value == "true"
+			}
+		}
+	}
+
+	result := make([]InputDevice, len(devices))
+	for index, device := range devices {
+		result[index] = *device
+	}
+	return result
+}
+
+// InputDevices returns the input devices, parsed from "dumpsys input"
+func (i InputManager) InputDevices() ([]InputDevice, error) {
+	result, err := i.Shell.DumpSys("input")
+	if err != nil {
+		return nil, err
+	}
+
+	if !result.IsOk() {
+		return nil, result.NewError()
+	}
+	return ParseInputDevices(result.Output()), nil
+}
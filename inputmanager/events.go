@@ -0,0 +1,285 @@
+package inputmanager
+
+import (
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	eventDeviceRegexp = regexp.MustCompile(`^add device \d+: (\S+)`)
+	eventNameRegexp   = regexp.MustCompile(`^name:\s*"(.*)"`)
+	eventTypeRegexp   = regexp.MustCompile(`^(\w+) \(([0-9a-fA-F]{4})\):\s*(.*)$`)
+	absInfoRegexp     = regexp.MustCompile(`([0-9a-fA-F]{4})\s*: value (-?\d+), min (-?\d+), max (-?\d+), fuzz (-?\d+), flat (-?\d+), resolution (-?\d+)`)
+)
+
+// region EventType
+
+// EventType is the type of an input event (linux/input-event-codes.h EV_*)
+type EventType uint16
+
+const (
+	EV_SYN EventType = 0x00
+	EV_KEY EventType = 0x01
+	EV_REL EventType = 0x02
+	EV_ABS EventType = 0x03
+	EV_MSC EventType = 0x04
+	EV_SW  EventType = 0x05
+	EV_LED EventType = 0x11
+	EV_SND EventType = 0x12
+	EV_REP EventType = 0x14
+)
+
+// The event codes used by the gestures (linux/input-event-codes.h)
+const (
+	SYN_REPORT uint16 = 0x00
+
+	BTN_TOOL_FINGER uint16 = 0x145
+	BTN_TOUCH       uint16 = 0x14a
+
+	ABS_X              uint16 = 0x00
+	ABS_Y              uint16 = 0x01
+	ABS_MT_SLOT        uint16 = 0x2f
+	ABS_MT_TOUCH_MAJOR uint16 = 0x30
+	ABS_MT_POSITION_X  uint16 = 0x35
+	ABS_MT_POSITION_Y  uint16 = 0x36
+	ABS_MT_TRACKING_ID uint16 = 0x39
+	ABS_MT_PRESSURE    uint16 = 0x3a
+)
+
+// The input properties, as printed by "getevent -p"
+const (
+	INPUT_PROP_POINTER = "INPUT_PROP_POINTER"
+	INPUT_PROP_DIRECT  = "INPUT_PROP_DIRECT"
+)
+
+var eventTypeNames = map[EventType]string{
+	EV_SYN: "EV_SYN",
+	EV_KEY: "EV_KEY",
+	EV_REL: "EV_REL",
+	EV_ABS: "EV_ABS",
+	EV_MSC: "EV_MSC",
+	EV_SW:  "EV_SW",
+	EV_LED: "EV_LED",
+	EV_SND: "EV_SND",
+	EV_REP: "EV_REP",
+}
+
+func (t EventType) String() string {
+	if name, ok := eventTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("%04x", uint16(t))
+}
+
+// endregion EventType
+
+// region InputEvent
+
+// InputEvent is a raw input event, as read by getevent or written by sendevent
+type InputEvent struct {
+	// Time is the timestamp of the event. For the generated events it's relative to the start of the sequence
+	Time   time.Duration
+	Device string
+	Type   EventType
+	Code   uint16
+	Value  int32
+}
+
+func (e InputEvent) String() string {
+	return fmt.Sprintf("[%s] %s: %s %s %08x", e.Time, e.Device, e.Type, CodeName(e.Type, e.Code), uint32(e.Value))
+}
+
+// sendEventScript returns a shell script which writes the events to the device nodes, sleeping between the
+// events to reproduce their timing. A sendevent process for each event is too slow for the gestures, so the
+// events are first written to eventsFile as struct input_event (eventSize bytes, see inputEventSize) and the
+// events with the same time and device are copied to the device node by a single dd, one event per write
+func sendEventScript(events []InputEvent, eventsFile string, eventSize int) string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf(": > %s\n", eventsFile))
+	for start := 0; start < len(events); start += sendEventChunk {
+		end := min(start+sendEventChunk, len(events))
+		builder.WriteString("printf '")
+		for _, event := range events[start:end] {
+			for _, b := range encodeInputEvent(event, eventSize) {
+				builder.WriteString(fmt.Sprintf("\\%03o", b))
+			}
+		}
+		builder.WriteString(fmt.Sprintf("' >> %s\n", eventsFile))
+	}
+
+	for i := 0; i < len(events); {
+		if i > 0 && events[i].Time > events[i-1].Time {
+			builder.WriteString(fmt.Sprintf("sleep %.3f\n", (events[i].Time - events[i-1].Time).Seconds()))
+		}
+
+		end := i + 1
+		for end < len(events) && events[end].Time == events[i].Time && events[end].Device == events[i].Device {
+			end++
+		}
+		builder.WriteString(fmt.Sprintf("dd if=%s of=%s bs=%d skip=%d count=%d 2>/dev/null\n", eventsFile, events[i].Device, eventSize, i, end-i))
+		i = end
+	}
+
+	builder.WriteString(fmt.Sprintf("rm -f %s\n", eventsFile))
+	return builder.String()
+}
+
+// encodeInputEvent returns the struct input_event of the event. The time is left to zero, the kernel sets it
+func encodeInputEvent(event InputEvent, eventSize int) []byte {
+	data := make([]byte, eventSize)
+	binary.LittleEndian.PutUint16(data[eventSize-8:], uint16(event.Type))
+	binary.LittleEndian.PutUint16(data[eventSize-6:], event.Code)
+	binary.LittleEndian.PutUint32(data[eventSize-4:], uint32(event.Value))
+	return data
+}
+
+// endregion InputEvent
+
+// region EventDevice
+
+// AbsInfo is the range of an absolute axis
+type AbsInfo struct {
+	Value      int
+	Min        int
+	Max        int
+	Fuzz       int
+	Flat       int
+	Resolution int
+}
+
+// EventDevice is an input device node (/dev/input/eventX), as reported by "getevent -p"
+type EventDevice struct {
+	Path string
+	Name string
+	// Events are the codes supported by the device for each event type
+	Events map[EventType][]uint16
+	// Abs are the ranges of the absolute axes
+	Abs map[uint16]AbsInfo
+	// Props are the input properties (e.g. INPUT_PROP_DIRECT)
+	Props []string
+}
+
+func (d EventDevice) String() string {
+	return fmt.Sprintf("EventDevice{Path:%s, Name:%s, Props:%v}", d.Path, d.Name, d.Props)
+}
+
+// HasCode returns true if the device supports the given event code
+func (d EventDevice) HasCode(eventType EventType, code uint16) bool {
+	for _, c := range d.Events[eventType] {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// HasProp returns true if the device has the given input property
+func (d EventDevice) HasProp(prop string) bool {
+	for _, p := range d.Props {
+		if p == prop {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTouchScreen returns true if the device is a multi-touch screen
+func (d EventDevice) IsTouchScreen() bool {
+	if !d.HasCode(EV_ABS, ABS_MT_POSITION_X) || !d.HasCode(EV_ABS, ABS_MT_POSITION_Y) {
+		return false
+	}
+	// touchpads report INPUT_PROP_POINTER
+	return !d.HasProp(INPUT_PROP_POINTER)
+}
+
+// ParseEventDevices parses the output of "getevent -p"
+func ParseEventDevices(data string) []EventDevice {
+	var devices []EventDevice
+	var device *EventDevice
+	var eventType *EventType
+	inProps := false
+
+	for _, line := range strings.Split(data, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		if m := eventDeviceRegexp.FindStringSubmatch(trimmed); m != nil {
+			devices = append(devices, EventDevice{Path: m[1], Events: map[EventType][]uint16{}, Abs: map[uint16]AbsInfo{}})
+			device = &devices[len(devices)-1]
+			eventType = nil
+			inProps = false
+			continue
+		}
+
+		if device == nil || trimmed == "" {
+			continue
+		}
+
+		if m := eventNameRegexp.FindStringSubmatch(trimmed); m != nil {
+			device.Name = m[1]
+			continue
+		}
+
+		switch {
+		case trimmed == "events:":
+			inProps = false
+			continue
+		case trimmed == "input props:":
+			eventType = nil
+			inProps = true
+			continue
+		case strings.HasSuffix(trimmed, ":") && !strings.Contains(trimmed, " "):
+			// location:, id:, version:
+			eventType = nil
+			inProps = false
+			continue
+		}
+
+		if inProps {
+			if trimmed != "<none>" {
+				device.Props = append(device.Props, trimmed)
+			}
+			continue
+		}
+
+		values := trimmed
+		if m := eventTypeRegexp.FindStringSubmatch(trimmed); m != nil {
+			value, _ := strconv.ParseUint(m[2], 16, 16)
+			t := EventType(value)
+			eventType = &t
+			values = m[3]
+		}
+
+		if eventType == nil {
+			continue
+		}
+
+		if *eventType == EV_ABS {
+			for _, m := range absInfoRegexp.FindAllStringSubmatch(values, -1) {
+				code, _ := strconv.ParseUint(m[1], 16, 16)
+				info := AbsInfo{}
+				info.Value, _ = strconv.Atoi(m[2])
+				info.Min, _ = strconv.Atoi(m[3])
+				info.Max, _ = strconv.Atoi(m[4])
+				info.Fuzz, _ = strconv.Atoi(m[5])
+				info.Flat, _ = strconv.Atoi(m[6])
+				info.Resolution, _ = strconv.Atoi(m[7])
+				device.Abs[uint16(code)] = info
+				device.Events[EV_ABS] = append(device.Events[EV_ABS], uint16(code))
+			}
+			continue
+		}
+
+		for _, field := range strings.Fields(values) {
+			if code, err := strconv.ParseUint(field, 16, 16); err == nil {
+				device.Events[*eventType] = append(device.Events[*eventType], uint16(code))
+			}
+		}
+	}
+	return devices
+}
+
+// endregion EventDevice
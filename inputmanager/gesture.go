@@ -0,0 +1,338 @@
+package inputmanager
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"time"
+
+	"github.com/sephiroth74/go_adb_client/shell"
+	"github.com/sephiroth74/go_adb_client/types"
+)
+
+// region Gesture
+
+// Stroke is the path of a single finger. The finger goes down on the first point after Delay, stays
+// there for Hold and then moves along the points at constant speed in Duration
+type Stroke struct {
+	Points   []image.Point
+	Delay    time.Duration
+	Hold     time.Duration
+	Duration time.Duration
+}
+
+// End returns the time, since the start of the gesture, when the finger goes up
+func (s Stroke) End() time.Duration {
+	return s.Delay + s.Hold + s.Duration
+}
+
+// Length returns the length of the path, in pixels
+func (s Stroke) Length() float64 {
+	var length float64
+	for i := 1; i < len(s.Points); i++ {
+		length += distance(s.Points[i-1], s.Points[i])
+	}
+	return length
+}
+
+// At returns the position of the finger at the given time since the start of the gesture
+func (s Stroke) At(t time.Duration) image.Point {
+	if len(s.Points) == 0 {
+		return image.Point{}
+	}
+
+	t -= s.Delay + s.Hold
+	if t <= 0 || len(s.Points) == 1 {
+		return s.Points[0]
+	}
+	if t >= s.Duration {
+		return s.Points[len(s.Points)-1]
+	}
+
+	target := s.Length() * float64(t) / float64(s.Duration)
+	for i := 1; i < len(s.Points); i++ {
+		segment := distance(s.Points[i-1], s.Points[i])
+		if target <= segment && segment > 0 {
+			ratio := target / segment
+			return image.Point{
+				X: s.Points[i-1].X + int(math.Round(float64(s.Points[i].X-s.Points[i-1].X)*ratio)),
+				Y: s.Points[i-1].Y + int(math.Round(float64(s.Points[i].Y-s.Points[i-1].Y)*ratio)),
+			}
+		}
+		target -= segment
+	}
+	return s.Points[len(s.Points)-1]
+}
+
+// Gesture is a set of strokes, one for each finger, performed together
+type Gesture struct {
+	Strokes []Stroke
+}
+
+// Duration returns the time when the last finger goes up
+func (g Gesture) Duration() time.Duration {
+	var duration time.Duration
+	for _, stroke := range g.Strokes {
+		if stroke.End() > duration {
+			duration = stroke.End()
+		}
+	}
+	return duration
+}
+
+// Tap is a single finger tap
+func Tap(p image.Point) Gesture {
+	return Gesture{Strokes: []Stroke{{Points: []image.Point{p}, Hold: 50 * time.Millisecond}}}
+}
+
+// LongPress keeps a single finger down on the point for the given duration
+func LongPress(p image.Point, duration time.Duration) Gesture {
+	return Gesture{Strokes: []Stroke{{Points: []image.Point{p}, Hold: duration}}}
+}
+
+// Path moves a single finger along the polyline in the given duration
+func Path(duration time.Duration, points ...image.Point) Gesture {
+	return Gesture{Strokes: []Stroke{{Points: points, Duration: duration}}}
+}
+
+// Swipe moves a single finger from one point to the other in the given duration
+func Swipe(from image.Point, to image.Point, duration time.Duration) Gesture {
+	return Path(duration, from, to)
+}
+
+// MultiSwipe moves the given number of fingers, side by side and spacing pixels apart, from one point to the other
+func MultiSwipe(from image.Point, to image.Point, fingers int, spacing int, duration time.Duration) Gesture {
+	// the fingers are placed on the perpendicular of the swipe direction
+	dx, dy := float64(to.X-from.X), float64(to.Y-from.Y)
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		dx, length = 1, 1
+	}
+	px, py := -dy/length, dx/length
+
+	gesture := Gesture{}
+	for i := 0; i < fingers; i++ {
+		offset := (float64(i) - float64(fingers-1)/2) * float64(spacing)
+		delta := image.Point{X: int(math.Round(px * offset)), Y: int(math.Round(py * offset))}
+		gesture.Strokes = append(gesture.Strokes, Stroke{Points: []image.Point{from.Add(delta), to.Add(delta)}, Duration: duration})
+	}
+	return gesture
+}
+
+// Pinch moves two fingers, symmetric around the center, from the start distance to the end distance.
+// A start distance greater than the end distance zooms out. angle is the direction of the fingers, in degrees
+func Pinch(center image.Point, startDistance int, endDistance int, angle float64, duration time.Duration) Gesture {
+	radians := angle * math.Pi / 180
+	offset := func(d int) image.Point {
+		return image.Point{
+			X: int(math.Round(math.Cos(radians) * float64(d) / 2)),
+			Y: int(math.Round(math.Sin(radians) * float64(d) / 2)),
+		}
+	}
+
+	return Gesture{Strokes: []Stroke{
+		{Points: []image.Point{center.Sub(offset(startDistance)), center.Sub(offset(endDistance))}, Duration: duration},
+		{Points: []image.Point{center.Add(offset(startDistance)), center.Add(offset(endDistance))}, Duration: duration},
+	}}
+}
+
+// Drag long presses the first point for hold, then drags to the other point in the given duration
+func Drag(from image.Point, to image.Point, hold time.Duration, duration time.Duration) Gesture {
+	return Gesture{Strokes: []Stroke{{Points: []image.Point{from, to}, Hold: hold, Duration: duration}}}
+}
+
+// Fling moves a single finger from one point to the other at the given velocity, in pixels per second.
+// The finger is lifted on the frame after the one reaching the end point, soon enough for the view to
+// compute the fling velocity from the last moves
+func Fling(from image.Point, to image.Point, velocity float64) Gesture {
+	if velocity <= 0 {
+		velocity = 1
+	}
+	duration := time.Duration(distance(from, to) / velocity * float64(time.Second))
+	return Swipe(from, to, duration)
+}
+
+func distance(a image.Point, b image.Point) float64 {
+	return math.Hypot(float64(b.X-a.X), float64(b.Y-a.Y))
+}
+
+// endregion Gesture
+
+// region TouchScreen
+
+// TouchScreen performs the gestures writing the raw multi-touch events (protocol B) to the
+// touch screen device node. The gestures coordinates are display coordinates in the
+// natural orientation of the display, scaled to the device axes ranges
+type TouchScreen struct {
+	Device      EventDevice
+	DisplaySize types.Size
+	// FrameInterval is the interval between two moves of the fingers. Default 16ms.
+	// Each frame runs a dd and a sleep process, which add a few milliseconds to the interval
+	FrameInterval time.Duration
+
+	shell *shell.Shell
+}
+
+func NewTouchScreen(shell *shell.Shell, device EventDevice, displaySize types.Size) *TouchScreen {
+	return &TouchScreen{
+		Device:        device,
+		DisplaySize:   displaySize,
+		FrameInterval: 16 * time.Millisecond,
+		shell:         shell,
+	}
+}
+
+func (t TouchScreen) String() string {
+	return fmt.Sprintf("TouchScreen{Device:%s, DisplaySize:%s}", t.Device.Path, t.DisplaySize)
+}
+
+// ToDevice converts the display coordinates to the device axes coordinates
+func (t TouchScreen) ToDevice(p image.Point) (int32, int32) {
+	return scaleAxis(p.X, t.DisplaySize.Width, t.Device.Abs[ABS_MT_POSITION_X]),
+		scaleAxis(p.Y, t.DisplaySize.Height, t.Device.Abs[ABS_MT_POSITION_Y])
+}
+
+func scaleAxis(value int, size uint, info AbsInfo) int32 {
+	if size == 0 || info.Max <= info.Min {
+		return int32(value)
+	}
+
+	raw := info.Min + int(int64(value)*int64(info.Max-info.Min+1)/int64(size))
+	if raw < info.Min {
+		raw = info.Min
+	} else if raw > info.Max {
+		raw = info.Max
+	}
+	return int32(raw)
+}
+
+// Perform performs the gesture and returns once all the fingers are up
+func (t TouchScreen) Perform(gesture Gesture) error {
+	events, err := t.Events(gesture)
+	if err != nil {
+		return err
+	}
+	return InputManager{Shell: t.shell}.sendEvents(events)
+}
+
+// Events returns the input events of the gesture. The time of the events is relative to the start of the gesture
+func (t TouchScreen) Events(gesture Gesture) ([]InputEvent, error) {
+	if len(gesture.Strokes) == 0 {
+		return nil, errors.New("empty gesture")
+	}
+
+	slot, ok := t.Device.Abs[ABS_MT_SLOT]
+	if !ok {
+		return nil, fmt.Errorf("%s doesn't support multi-touch slots", t.Device.Path)
+	}
+	if len(gesture.Strokes) > slot.Max-slot.Min+1 {
+		return nil, fmt.Errorf("%s supports up to %d fingers", t.Device.Path, slot.Max-slot.Min+1)
+	}
+
+	frame := t.FrameInterval
+	if frame <= 0 {
+		frame = 16 * time.Millisecond
+	}
+
+	type finger struct {
+		down, up bool
+		x, y     int32
+	}
+
+	var events []InputEvent
+	fingers := make([]finger, len(gesture.Strokes))
+	currentSlot := -1
+	touching := false
+
+	for at := time.Duration(0); ; at += frame {
+		emit := func(eventType EventType, code uint16, value int32) {
+			events = append(events, InputEvent{Time: at, Device: t.Device.Path, Type: eventType, Code: code, Value: value})
+		}
+		selectSlot := func(index int) {
+			if currentSlot != index {
+				emit(EV_ABS, ABS_MT_SLOT, int32(slot.Min+index))
+				currentSlot = index
+			}
+		}
+
+		changed := false
+		released := 0
+		for index, stroke := range gesture.Strokes {
+			f := &fingers[index]
+			if f.up {
+				released++
+				continue
+			}
+			if at < stroke.Delay {
+				continue
+			}
+
+			x, y := t.ToDevice(stroke.At(at))
+			switch {
+			case !f.down:
+				selectSlot(index)
+				emit(EV_ABS, ABS_MT_TRACKING_ID, int32(index+1))
+				emit(EV_ABS, ABS_MT_POSITION_X, x)
+				emit(EV_ABS, ABS_MT_POSITION_Y, y)
+				if info, ok := t.Device.Abs[ABS_MT_TOUCH_MAJOR]; ok {
+					emit(EV_ABS, ABS_MT_TOUCH_MAJOR, int32(info.Min+(info.Max-info.Min)/8))
+				}
+				if info, ok := t.Device.Abs[ABS_MT_PRESSURE]; ok {
+					emit(EV_ABS, ABS_MT_PRESSURE, int32(info.Min+(info.Max-info.Min)/2))
+				}
+				f.down = true
+			case x != f.x || y != f.y:
+				selectSlot(index)
+				if x != f.x {
+					emit(EV_ABS, ABS_MT_POSITION_X, x)
+				}
+				if y != f.y {
+					emit(EV_ABS, ABS_MT_POSITION_Y, y)
+				}
+			case at >= stroke.End():
+				// the finger is released once it reached the last point
+				selectSlot(index)
+				emit(EV_ABS, ABS_MT_TRACKING_ID, -1)
+				f.up = true
+				released++
+			default:
+				continue
+			}
+
+			f.x, f.y = x, y
+			changed = true
+		}
+
+		down := false
+		for _, f := range fingers {
+			down = down || (f.down && !f.up)
+		}
+
+		if down != touching {
+			value := int32(0)
+			if down {
+				value = 1
+			}
+			if t.Device.HasCode(EV_KEY, BTN_TOUCH) {
+				emit(EV_KEY, BTN_TOUCH, value)
+			}
+			if t.Device.HasCode(EV_KEY, BTN_TOOL_FINGER) {
+				emit(EV_KEY, BTN_TOOL_FINGER, value)
+			}
+			touching = down
+			changed = true
+		}
+
+		if changed {
+			emit(EV_SYN, SYN_REPORT, 0)
+		}
+
+		if released == len(fingers) {
+			break
+		}
+	}
+	return events, nil
+}
+
+// endregion TouchScreen
@@ -0,0 +1,297 @@
+package inputmanager
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sephiroth74/go-processbuilder"
+)
+
+var (
+	// [   12345.678901] /dev/input/event3: EV_ABS ABS_MT_POSITION_X 000001f4
+	// the device is omitted when getevent reads a single device
+	eventLineRegexp     = regexp.MustCompile(`^\[\s*(\d+)\.(\d+)\]\s+(?:(\S+):\s+)?(\S+)\s+(\S+)\s+(\S+)\s*$`)
+	recordingDeviceLine = regexp.MustCompile(`^# device (\S+) "(.*)"$`)
+)
+
+// region EventStream
+
+// ParseEventLine parses a line printed by "getevent -t" or "getevent -lt".
+// device is used when the line doesn't report the device (getevent reading a single device)
+func ParseEventLine(line string, device string) (InputEvent, bool) {
+	m := eventLineRegexp.FindStringSubmatch(strings.TrimSpace(line))
+	if m == nil {
+		return InputEvent{}, false
+	}
+
+	seconds, _ := strconv.ParseInt(m[1], 10, 64)
+	// the fraction is printed in microseconds, parse it as nanoseconds
+	fraction, _ := strconv.ParseInt((m[2] + "000000000")[:9], 10, 64)
+
+	event := InputEvent{
+		Time:   time.Duration(seconds)*time.Second + time.Duration(fraction),
+		Device: device,
+	}
+	if m[3] != "" {
+		event.Device = m[3]
+	}
+
+	var err error
+	if event.Type, err = ParseEventType(m[4]); err != nil {
+		return InputEvent{}, false
+	}
+	if event.Code, err = ParseEventCode(event.Type, m[5]); err != nil {
+		return InputEvent{}, false
+	}
+	if event.Value, err = parseEventValue(event.Type, m[6]); err != nil {
+		return InputEvent{}, false
+	}
+	return event, true
+}
+
+// EventStream publishes the events read by getevent.
+// Events is closed once the stream is stopped
+type EventStream struct {
+	Events chan InputEvent
+
+	done     chan struct{}
+	stopOnce sync.Once
+	pb       *processbuilder.Processbuilder
+}
+
+// Stop cancels the stream and closes the Events channel
+func (s *EventStream) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.done)
+		_ = processbuilder.Cancel(s.pb)
+	})
+}
+
+// StreamEvents streams the events of the given device (e.g. /dev/input/event3), or of all the devices if empty,
+// using "getevent -t". The timestamps are the kernel timestamps of the events.
+// The events are read in the numeric format, so that the codes without a label are not lost (see CodeName).
+// Call Stop on the returned stream to cancel it
+func (i InputManager) StreamEvents(device string) (*EventStream, error) {
+	args := []string{"getevent", "-t"}
+	if device != "" {
+		args = append(args, device)
+	}
+
+	pb, err := processbuilder.PipeOutput(processbuilder.Option{}, i.Shell.NewCommand().WithArgs(args...).ToCommand())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := processbuilder.Start(pb); err != nil {
+		return nil, err
+	}
+
+	stream := &EventStream{
+		Events: make(chan InputEvent),
+		done:   make(chan struct{}),
+		pb:     pb,
+	}
+
+	go func() {
+		defer close(stream.Events)
+		defer stream.Stop()
+
+		scanner := bufio.NewScanner(pb.StdoutPipe)
+		for scanner.Scan() {
+			event, ok := ParseEventLine(scanner.Text(), device)
+			if !ok {
+				continue
+			}
+
+			select {
+			case <-stream.done:
+				return
+			case stream.Events <- event:
+			}
+		}
+		_, _, _ = processbuilder.Wait(pb)
+	}()
+	return stream, nil
+}
+
+// endregion EventStream
+
+// region Recording
+
+// Recording is a sequence of recorded input events.
+// It's saved in the "getevent -t" format, preceded by the names of the devices
+type Recording struct {
+	Events []InputEvent
+	// Devices are the names of the recorded devices, by path
+	Devices map[string]string
+}
+
+// Duration returns the time between the first and the last event
+func (r Recording) Duration() time.Duration {
+	if len(r.Events) == 0 {
+		return 0
+	}
+	return r.Events[len(r.Events)-1].Time - r.Events[0].Time
+}
+
+// Write writes the recording in the "getevent -t" format
+func (r Recording) Write(w io.Writer) error {
+	paths := make([]string, 0, len(r.Devices))
+	for path := range r.Devices {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	writer := bufio.NewWriter(w)
+	for _, path := range paths {
+		if _, err := fmt.Fprintf(writer, "# device %s %q\n", path, r.Devices[path]); err != nil {
+			return err
+		}
+	}
+
+	for _, event := range r.Events {
+		_, err := fmt.Fprintf(writer, "[%6d.%06d] %s: %04x %04x %08x\n",
+			event.Time/time.Second, (event.Time%time.Second)/time.Microsecond, event.Device, uint16(event.Type), event.Code, uint32(event.Value))
+		if err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
+
+// Save writes the recording to the file
+func (r Recording) Save(filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	if err := r.Write(file); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// ReadRecording reads a recording written by Recording.Write, or the output of "getevent -t" or "getevent -lt"
+func ReadRecording(r io.Reader) (*Recording, error) {
+	recording := &Recording{Devices: map[string]string{}}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if m := recordingDeviceLine.FindStringSubmatch(line); m != nil {
+			recording.Devices[m[1]] = m[2]
+			continue
+		}
+
+		if event, ok := ParseEventLine(line, ""); ok {
+			recording.Events = append(recording.Events, event)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return recording, nil
+}
+
+// LoadRecording reads a recording from the file
+func LoadRecording(filename string) (*Recording, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadRecording(file)
+}
+
+// Recorder records the input events until stopped
+type Recorder struct {
+	stream    *EventStream
+	recording *Recording
+	finished  chan struct{}
+}
+
+// Stop stops the recorder and returns the recorded events
+func (r *Recorder) Stop() *Recording {
+	r.stream.Stop()
+	<-r.finished
+	return r.recording
+}
+
+// Record starts recording the events of the given device, or of all the devices if empty.
+// Call Stop on the returned recorder to get the recording
+func (i InputManager) Record(device string) (*Recorder, error) {
+	recording := &Recording{Devices: map[string]string{}}
+	if devices, err := i.GetEventDevices(); err == nil {
+		for _, d := range devices {
+			if device == "" || d.Path == device {
+				recording.Devices[d.Path] = d.Name
+			}
+		}
+	}
+
+	stream, err := i.StreamEvents(device)
+	if err != nil {
+		return nil, err
+	}
+
+	recorder := &Recorder{stream: stream, recording: recording, finished: make(chan struct{})}
+	go func() {
+		defer close(recorder.finished)
+		for event := range stream.Events {
+			recording.Events = append(recording.Events, event)
+		}
+	}()
+	return recorder, nil
+}
+
+// Replay writes the recorded events to the device nodes, reproducing their original timing.
+// The recorded devices are matched by name, so a recording can be replayed on a device where
+// the same input device has a different path. It fails if the device of a recorded event is not found
+func (i InputManager) Replay(recording Recording) error {
+	if len(recording.Events) == 0 {
+		return nil
+	}
+
+	paths := map[string]string{}
+	if len(recording.Devices) > 0 {
+		devices, err := i.GetEventDevices()
+		if err != nil {
+			return err
+		}
+		for path, name := range recording.Devices {
+			for _, d := range devices {
+				if d.Name == name {
+					paths[path] = d.Path
+					break
+				}
+			}
+		}
+	}
+
+	events := make([]InputEvent, len(recording.Events))
+	start := recording.Events[0].Time
+	for index, event := range recording.Events {
+		event.Time -= start
+		if name, ok := recording.Devices[event.Device]; ok {
+			path, found := paths[event.Device]
+			if !found {
+				return fmt.Errorf("input device not found: %q (recorded as %s)", name, event.Device)
+			}
+			event.Device = path
+		}
+		events[index] = event
+	}
+	return i.sendEvents(events)
+}
+
+// endregion Recording
@@ -0,0 +1,328 @@
+package inputmanager
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sephiroth74/go_adb_client/activitymanager"
+	"github.com/sephiroth74/go_adb_client/input"
+	"github.com/sephiroth74/go_adb_client/process"
+	"github.com/sephiroth74/go_adb_client/types"
+)
+
+// AdbKeyboardIme is the id of the ADBKeyBoard IME (https://github.com/senzhk/ADBKeyBoard)
+const AdbKeyboardIme = "com.android.adbkeyboard/.AdbIME"
+
+const (
+	// imeSwitchTimeout is the time given to the input method manager to bind a selected IME
+	imeSwitchTimeout = 5 * time.Second
+	imePollInterval  = 200 * time.Millisecond
+)
+
+var (
+	imeFieldRegexp = regexp.MustCompile(`\b(mId|mSettingsActivityName|packageName)=(\S+)`)
+	imeBoundRegexp = regexp.MustCompile(`\bmCurId=(\S+).*\bmBoundToMethod=(true|false)`)
+)
+
+// region Ime
+
+// Ime is an input method service
+type Ime struct {
+	// Id is the component of the IME service (e.g. com.android.inputmethod.latin/.LatinIME)
+	Id               string
+	PackageName      string
+	SettingsActivity string
+	Enabled          bool
+	// Selected is true for the current IME
+	Selected bool
+}
+
+func (i Ime) String() string {
+	return fmt.Sprintf("Ime{Id:%s, Enabled:%t, Selected:%t}", i.Id, i.Enabled, i.Selected)
+}
+
+// ParseImeList parses the output of "ime list -a"
+func ParseImeList(data string) []Ime {
+	var imes []Ime
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" {
+			continue
+		}
+
+		// each IME starts with its id, not indented
+		if !strings.HasPrefix(line, " ") && strings.HasSuffix(line, ":") {
+			imes = append(imes, Ime{Id: strings.TrimSuffix(line, ":")})
+			continue
+		}
+
+		if len(imes) == 0 {
+			continue
+		}
+
+		ime := &imes[len(imes)-1]
+		for _, m := range imeFieldRegexp.FindAllStringSubmatch(line, -1) {
+			switch m[1] {
+			case "mId":
+				ime.Id = m[2]
+			case "mSettingsActivityName":
+				if m[2] != "null" {
+					ime.SettingsActivity = m[2]
+				}
+			case "packageName":
+				if ime.PackageName == "" {
+					ime.PackageName = m[2]
+				}
+			}
+		}
+	}
+
+	for index := range imes {
+		if imes[index].PackageName == "" {
+			imes[index].PackageName, _, _ = strings.Cut(imes[index].Id, "/")
+		}
+	}
+	return imes
+}
+
+// endregion Ime
+
+// GetImes returns all the installed IMEs, with their enabled and selected state
+func (i InputManager) GetImes() ([]Ime, error) {
+	result, err := i.ime("list", "-a")
+	if err != nil {
+		return nil, err
+	}
+	imes := ParseImeList(result.Output())
+
+	enabled, err := i.GetEnabledImes()
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := i.GetCurrentIme()
+	if err != nil {
+		return nil, err
+	}
+
+	for index := range imes {
+		imes[index].Selected = imes[index].Id == current
+		for _, id := range enabled {
+			if imes[index].Id == id {
+				imes[index].Enabled = true
+				break
+			}
+		}
+	}
+	return imes, nil
+}
+
+// GetEnabledImes returns the ids of the enabled IMEs with "ime list -s"
+func (i InputManager) GetEnabledImes() ([]string, error) {
+	result, err := i.ime("list", "-s")
+	if err != nil {
+		return nil, err
+	}
+	return result.OutputLines(true), nil
+}
+
+// GetCurrentIme returns the id of the current IME (the default_input_method secure setting)
+func (i InputManager) GetCurrentIme() (string, error) {
+	value, err := i.Shell.GetSetting("default_input_method", types.SettingsSecure)
+	if err != nil {
+		return "", err
+	}
+	if value == nil || *value == "null" {
+		return "", nil
+	}
+	return *value, nil
+}
+
+// EnableIme enables the IME with "ime enable"
+func (i InputManager) EnableIme(id string) error {
+	_, err := i.ime("enable", id)
+	return err
+}
+
+// DisableIme disables the IME with "ime disable"
+func (i InputManager) DisableIme(id string) error {
+	_, err := i.ime("disable", id)
+	return err
+}
+
+// SetIme selects the IME with "ime set". The IME must be enabled
+func (i InputManager) SetIme(id string) error {
+	_, err := i.ime("set", id)
+	return err
+}
+
+// ResetImes resets the enabled and selected IMEs to the system defaults with "ime reset"
+func (i InputManager) ResetImes() error {
+	_, err := i.ime("reset")
+	return err
+}
+
+// ime runs the ime command, which reports the errors with exit code 0
+func (i InputManager) ime(args ...string) (process.OutputResult, error) {
+	return i.execute(i.Shell.NewCommand().WithArgs("ime").AddArgs(args...))
+}
+
+// region Text
+
+// UnicodeMethod is how SendText types the text that "input text" cannot type
+type UnicodeMethod int
+
+const (
+	// UnicodeAdbKeyboard sends the text to the ADBKeyBoard IME with the ADB_INPUT_B64 broadcast.
+	// The IME is enabled and selected while typing, then the previous IME is restored
+	UnicodeAdbKeyboard UnicodeMethod = iota
+	// UnicodeClipboard sets the clipboard with the clipper.set broadcast (https://github.com/majido/clipper)
+	// and pastes it in the focused view with KEYCODE_PASTE
+	UnicodeClipboard
+)
+
+func (m UnicodeMethod) String() string {
+	switch m {
+	case UnicodeAdbKeyboard:
+		return "adbkeyboard"
+	case UnicodeClipboard:
+		return "clipboard"
+	}
+	return "unknown"
+}
+
+// SendText types the text in the focused view. The ascii text is typed with "input text", the
+// text containing other characters is typed with the given method
+func (i InputManager) SendText(value string, method UnicodeMethod) error {
+	if input.IsTypeable(value) {
+		_, err := i.Shell.SendString(value)
+		return err
+	}
+
+	switch method {
+	case UnicodeAdbKeyboard:
+		return i.sendAdbKeyboardText(value)
+	case UnicodeClipboard:
+		return i.pasteText(value)
+	}
+	return fmt.Errorf("unsupported method: %s", method)
+}
+
+func (i InputManager) sendAdbKeyboardText(value string) (err error) {
+	current, err := i.GetCurrentIme()
+	if err != nil {
+		return err
+	}
+
+	if current != AdbKeyboardIme {
+		enabled, err := i.GetEnabledImes()
+		if err != nil {
+			return err
+		}
+
+		wasEnabled := false
+		for _, id := range enabled {
+			wasEnabled = wasEnabled || id == AdbKeyboardIme
+		}
+
+		if !wasEnabled {
+			if err := i.EnableIme(AdbKeyboardIme); err != nil {
+				return err
+			}
+			defer func() { err = errors.Join(err, i.DisableIme(AdbKeyboardIme)) }()
+		}
+
+		// restored before disabling ADBKeyBoard, the deferred calls run in reverse order
+		if current != "" {
+			defer func() { err = errors.Join(err, i.SetIme(current)) }()
+		}
+
+		if err := i.SetIme(AdbKeyboardIme); err != nil {
+			return err
+		}
+
+		// the broadcast is dropped until the IME is bound and its receiver registered
+		if err := i.waitForIme(AdbKeyboardIme, imeSwitchTimeout); err != nil {
+			return err
+		}
+	}
+
+	intent := types.NewIntent()
+	intent.Action = "ADB_INPUT_B64"
+	intent.Extra.Es["msg"] = base64.StdEncoding.EncodeToString([]byte(value))
+	return i.broadcast(intent)
+}
+
+// waitForIme waits until the IME is selected and bound: "ime set" only starts the switch
+func (i InputManager) waitForIme(id string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		current, err := i.GetCurrentIme()
+		if err != nil {
+			return err
+		}
+
+		if current == id {
+			bound, err := i.isImeBound(id)
+			if err != nil {
+				return err
+			}
+			if bound {
+				return nil
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("ime %s not bound after %s", id, timeout)
+		}
+		time.Sleep(imePollInterval)
+	}
+}
+
+// isImeBound returns true if the input method manager is bound to the IME. Without the
+// binding state in "dumpsys input_method", the IME is considered bound once selected
+func (i InputManager) isImeBound(id string) (bool, error) {
+	result, err := i.Shell.DumpSys("input_method")
+	if err != nil {
+		return false, err
+	}
+	if !result.IsOk() {
+		return false, result.NewError()
+	}
+
+	m := imeBoundRegexp.FindStringSubmatch(result.Output())
+	if m == nil {
+		return true, nil
+	}
+	return m[1] == id && m[2] == "true", nil
+}
+
+func (i InputManager) pasteText(value string) error {
+	intent := types.NewIntent()
+	intent.Action = "clipper.set"
+	intent.Extra.Es["text"] = value
+	if err := i.broadcast(intent); err != nil {
+		return err
+	}
+
+	result, err := i.Shell.SendKeyEvent(input.KEYBOARD, nil, input.KEYCODE_PASTE)
+	if err != nil {
+		return err
+	}
+	if !result.IsOk() {
+		return result.NewError()
+	}
+	return nil
+}
+
+func (i InputManager) broadcast(intent *types.Intent) error {
+	am := activitymanager.ActivityManager{Shell: i.Shell}
+	_, err := am.SendBroadcast(intent, nil)
+	return err
+}
+
+// endregion Text
@@ -0,0 +1,121 @@
+package inputmanager
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sephiroth74/go_adb_client/process"
+	"github.com/sephiroth74/go_adb_client/shell"
+	"github.com/sephiroth74/go_adb_client/types"
+)
+
+var displaySizeRegexp = regexp.MustCompile(`(Physical|Override) size: (\d+)x(\d+)`)
+
+type InputManager struct {
+	Shell *shell.Shell
+}
+
+// GetEventDevices returns the input device nodes, parsed from "getevent -p"
+func (i InputManager) GetEventDevices() ([]EventDevice, error) {
+	result, err := i.execute(i.Shell.NewCommand().WithArgs("getevent", "-p"))
+	if err != nil {
+		return nil, err
+	}
+	return ParseEventDevices(result.Output()), nil
+}
+
+// GetDisplaySize returns the display size reported by "wm size". The override size is returned if set
+func (i InputManager) GetDisplaySize() (*types.Size, error) {
+	result, err := i.execute(i.Shell.NewCommand().WithArgs("wm", "size"))
+	if err != nil {
+		return nil, err
+	}
+	return ParseDisplaySize(result.Output())
+}
+
+// ParseDisplaySize parses the output of "wm size"
+func ParseDisplaySize(data string) (*types.Size, error) {
+	var size *types.Size
+	for _, m := range displaySizeRegexp.FindAllStringSubmatch(data, -1) {
+		width, _ := strconv.ParseUint(m[2], 10, 32)
+		height, _ := strconv.ParseUint(m[3], 10, 32)
+		if size == nil || m[1] == "Override" {
+			size = &types.Size{Width: uint(width), Height: uint(height)}
+		}
+	}
+
+	if size == nil {
+		return nil, fmt.Errorf("invalid display size: %s", data)
+	}
+	return size, nil
+}
+
+// GetTouchScreen returns the touch screen used to perform the gestures. The touch screen
+// is the first multi-touch device, preferring the ones with INPUT_PROP_DIRECT
+func (i InputManager) GetTouchScreen() (*TouchScreen, error) {
+	devices, err := i.GetEventDevices()
+	if err != nil {
+		return nil, err
+	}
+
+	var device *EventDevice
+	for index := range devices {
+		if !devices[index].IsTouchScreen() {
+			continue
+		}
+		if device == nil || (!device.HasProp(INPUT_PROP_DIRECT) && devices[index].HasProp(INPUT_PROP_DIRECT)) {
+			device = &devices[index]
+		}
+	}
+
+	if device == nil {
+		return nil, errors.New("touch screen not found")
+	}
+
+	size, err := i.GetDisplaySize()
+	if err != nil {
+		return nil, err
+	}
+	return NewTouchScreen(i.Shell, *device, *size), nil
+}
+
+// sendEventsFile is the device file of the events written by sendEvents, $$ is the pid of the script
+const sendEventsFile = "/data/local/tmp/input_events.$$"
+
+// sendEventChunk is the number of events written by each printf of sendEventScript, to keep the lines short
+const sendEventChunk = 256
+
+// sendEvents writes the events to the device nodes, reproducing their timing (see sendEventScript)
+func (i InputManager) sendEvents(events []InputEvent) error {
+	eventSize, err := i.inputEventSize()
+	if err != nil {
+		return err
+	}
+	return i.runScript(sendEventScript(events, sendEventsFile, eventSize))
+}
+
+// inputEventSize returns the size of struct input_event for the device userspace,
+// which has a 16 bytes timeval on 64 bit and a 8 bytes one on 32 bit
+func (i InputManager) inputEventSize() (int, error) {
+	abi, err := i.Shell.GetPropValue("ro.product.cpu.abi")
+	if err != nil {
+		return 0, err
+	}
+	if strings.Contains(abi, "64") {
+		return 24, nil
+	}
+	return 16, nil
+}
+
+// runScript runs the script with "sh", passing it through the standard input
+func (i InputManager) runScript(script string) error {
+	_, err := i.execute(i.Shell.NewCommand().WithArgs("sh").WithStdIn(strings.NewReader(script)))
+	return err
+}
+
+func (i InputManager) execute(cmd *process.ADBCommand) (process.OutputResult, error) {
+	return process.CheckedOutput(cmd, i.Shell.Conn.Verbose)
+}
@@ -0,0 +1,182 @@
+package inputmanager
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// the labels printed by "getevent -l" (linux/input-event-codes.h)
+var eventCodeLabels = map[EventType]map[uint16]string{
+	EV_SYN: {
+		0x00: "SYN_REPORT",
+		0x01: "SYN_CONFIG",
+		0x02: "SYN_MT_REPORT",
+		0x03: "SYN_DROPPED",
+	},
+	EV_KEY: {
+		1: "KEY_ESC", 2: "KEY_1", 3: "KEY_2", 4: "KEY_3", 5: "KEY_4", 6: "KEY_5", 7: "KEY_6", 8: "KEY_7",
+		9: "KEY_8", 10: "KEY_9", 11: "KEY_0", 12: "KEY_MINUS", 13: "KEY_EQUAL", 14: "KEY_BACKSPACE",
+		15: "KEY_TAB", 16: "KEY_Q", 17: "KEY_W", 18: "KEY_E", 19: "KEY_R", 20: "KEY_T", 21: "KEY_Y",
+		22: "KEY_U", 23: "KEY_I", 24: "KEY_O", 25: "KEY_P", 26: "KEY_LEFTBRACE", 27: "KEY_RIGHTBRACE",
+		28: "KEY_ENTER", 29: "KEY_LEFTCTRL", 30: "KEY_A", 31: "KEY_S", 32: "KEY_D", 33: "KEY_F", 34: "KEY_G",
+		35: "KEY_H", 36: "KEY_J", 37: "KEY_K", 38: "KEY_L", 39: "KEY_SEMICOLON", 40: "KEY_APOSTROPHE",
+		41: "KEY_GRAVE", 42: "KEY_LEFTSHIFT", 43: "KEY_BACKSLASH", 44: "KEY_Z", 45: "KEY_X", 46: "KEY_C",
+		47: "KEY_V", 48: "KEY_B", 49: "KEY_N", 50: "KEY_M", 51: "KEY_COMMA", 52: "KEY_DOT", 53: "KEY_SLASH",
+		54: "KEY_RIGHTSHIFT", 55: "KEY_KPASTERISK", 56: "KEY_LEFTALT", 57: "KEY_SPACE", 58: "KEY_CAPSLOCK",
+		59: "KEY_F1", 60: "KEY_F2", 61: "KEY_F3", 62: "KEY_F4", 63: "KEY_F5", 64: "KEY_F6", 65: "KEY_F7",
+		66: "KEY_F8", 67: "KEY_F9", 68: "KEY_F10", 69: "KEY_NUMLOCK", 70: "KEY_SCROLLLOCK", 87: "KEY_F11",
+		88: "KEY_F12", 96: "KEY_KPENTER", 97: "KEY_RIGHTCTRL", 100: "KEY_RIGHTALT", 102: "KEY_HOME",
+		103: "KEY_UP", 104: "KEY_PAGEUP", 105: "KEY_LEFT", 106: "KEY_RIGHT", 107: "KEY_END", 108: "KEY_DOWN",
+		109: "KEY_PAGEDOWN", 110: "KEY_INSERT", 111: "KEY_DELETE", 113: "KEY_MUTE", 114: "KEY_VOLUMEDOWN",
+		115: "KEY_VOLUMEUP", 116: "KEY_POWER", 119: "KEY_PAUSE", 125: "KEY_LEFTMETA", 126: "KEY_RIGHTMETA",
+		127: "KEY_COMPOSE", 128: "KEY_STOP", 139: "KEY_MENU", 142: "KEY_SLEEP", 143: "KEY_WAKEUP",
+		150: "KEY_WWW", 155: "KEY_MAIL", 158: "KEY_BACK", 159: "KEY_FORWARD", 163: "KEY_NEXTSONG",
+		164: "KEY_PLAYPAUSE", 165: "KEY_PREVIOUSSONG", 166: "KEY_STOPCD", 167: "KEY_RECORD",
+		168: "KEY_REWIND", 172: "KEY_HOMEPAGE", 173: "KEY_REFRESH", 174: "KEY_EXIT", 200: "KEY_PLAYCD",
+		201: "KEY_PAUSECD", 207: "KEY_PLAY", 208: "KEY_FASTFORWARD", 212: "KEY_CAMERA", 217: "KEY_SEARCH",
+		224: "KEY_BRIGHTNESSDOWN", 225: "KEY_BRIGHTNESSUP", 226: "KEY_MEDIA",
+		0x110: "BTN_LEFT", 0x111: "BTN_RIGHT", 0x112: "BTN_MIDDLE",
+		0x130: "BTN_A", 0x131: "BTN_B", 0x133: "BTN_X", 0x134: "BTN_Y", 0x136: "BTN_TL", 0x137: "BTN_TR",
+		0x13a: "BTN_SELECT", 0x13b: "BTN_START", 0x13c: "BTN_MODE", 0x13d: "BTN_THUMBL", 0x13e: "BTN_THUMBR",
+		0x140: "BTN_TOOL_PEN", 0x145: "BTN_TOOL_FINGER", 0x14a: "BTN_TOUCH", 0x14b: "BTN_STYLUS",
+		0x14d: "BTN_TOOL_DOUBLETAP", 0x14e: "BTN_TOOL_TRIPLETAP",
+		0x160: "KEY_OK", 0x161: "KEY_SELECT", 0x162: "KEY_GOTO", 0x163: "KEY_CLEAR", 0x165: "KEY_OPTION",
+		0x166: "KEY_INFO", 0x167: "KEY_TIME", 0x16a: "KEY_PROGRAM", 0x16b: "KEY_CHANNEL",
+		0x16c: "KEY_FAVORITES", 0x16d: "KEY_EPG", 0x16e: "KEY_PVR", 0x170: "KEY_LANGUAGE",
+		0x171: "KEY_TITLE", 0x172: "KEY_SUBTITLE", 0x174: "KEY_ZOOM", 0x179: "KEY_TV", 0x17f: "KEY_SAT",
+		0x182: "KEY_RADIO", 0x184: "KEY_TEXT", 0x185: "KEY_DVD", 0x186: "KEY_AUX", 0x188: "KEY_AUDIO",
+		0x189: "KEY_VIDEO", 0x18b: "KEY_LIST", 0x18e: "KEY_RED", 0x18f: "KEY_GREEN", 0x190: "KEY_YELLOW",
+		0x191: "KEY_BLUE", 0x192: "KEY_CHANNELUP", 0x193: "KEY_CHANNELDOWN", 0x194: "KEY_FIRST",
+		0x195: "KEY_LAST", 0x197: "KEY_NEXT", 0x198: "KEY_RESTART", 0x19c: "KEY_PREVIOUS",
+		0x200: "KEY_NUMERIC_0", 0x201: "KEY_NUMERIC_1", 0x202: "KEY_NUMERIC_2", 0x203: "KEY_NUMERIC_3",
+		0x204: "KEY_NUMERIC_4", 0x205: "KEY_NUMERIC_5", 0x206: "KEY_NUMERIC_6", 0x207: "KEY_NUMERIC_7",
+		0x208: "KEY_NUMERIC_8", 0x209: "KEY_NUMERIC_9", 0x20a: "KEY_NUMERIC_STAR", 0x20b: "KEY_NUMERIC_POUND",
+		0x220: "BTN_DPAD_UP", 0x221: "BTN_DPAD_DOWN", 0x222: "BTN_DPAD_LEFT", 0x223: "BTN_DPAD_RIGHT",
+	},
+	EV_REL: {
+		0x00: "REL_X",
+		0x01: "REL_Y",
+		0x02: "REL_Z",
+		0x06: "REL_HWHEEL",
+		0x07: "REL_DIAL",
+		0x08: "REL_WHEEL",
+		0x09: "REL_MISC",
+		0x0b: "REL_WHEEL_HI_RES",
+		0x0c: "REL_HWHEEL_HI_RES",
+	},
+	EV_ABS: {
+		0x00: "ABS_X",
+		0x01: "ABS_Y",
+		0x02: "ABS_Z",
+		0x03: "ABS_RX",
+		0x04: "ABS_RY",
+		0x05: "ABS_RZ",
+		0x06: "ABS_THROTTLE",
+		0x10: "ABS_HAT0X",
+		0x11: "ABS_HAT0Y",
+		0x18: "ABS_PRESSURE",
+		0x19: "ABS_DISTANCE",
+		0x1a: "ABS_TILT_X",
+		0x1b: "ABS_TILT_Y",
+		0x28: "ABS_MISC",
+		0x2f: "ABS_MT_SLOT",
+		0x30: "ABS_MT_TOUCH_MAJOR",
+		0x31: "ABS_MT_TOUCH_MINOR",
+		0x32: "ABS_MT_WIDTH_MAJOR",
+		0x33: "ABS_MT_WIDTH_MINOR",
+		0x34: "ABS_MT_ORIENTATION",
+		0x35: "ABS_MT_POSITION_X",
+		0x36: "ABS_MT_POSITION_Y",
+		0x37: "ABS_MT_TOOL_TYPE",
+		0x38: "ABS_MT_BLOB_ID",
+		0x39: "ABS_MT_TRACKING_ID",
+		0x3a: "ABS_MT_PRESSURE",
+		0x3b: "ABS_MT_DISTANCE",
+	},
+	EV_MSC: {
+		0x00: "MSC_SERIAL",
+		0x01: "MSC_PULSELED",
+		0x02: "MSC_GESTURE",
+		0x03: "MSC_RAW",
+		0x04: "MSC_SCAN",
+		0x05: "MSC_TIMESTAMP",
+	},
+	EV_SW: {
+		0x00: "SW_LID",
+		0x01: "SW_TABLET_MODE",
+		0x02: "SW_HEADPHONE_INSERT",
+		0x04: "SW_MICROPHONE_INSERT",
+		0x06: "SW_LINEOUT_INSERT",
+		0x07: "SW_JACK_PHYSICAL_INSERT",
+	},
+}
+
+// the values of EV_KEY printed by "getevent -l"
+var keyValueLabels = map[string]int32{
+	"UP":     0,
+	"DOWN":   1,
+	"REPEAT": 2,
+}
+
+// eventCodeValues is the reverse of eventCodeLabels
+var eventCodeValues = func() map[EventType]map[string]uint16 {
+	values := map[EventType]map[string]uint16{}
+	for eventType, labels := range eventCodeLabels {
+		values[eventType] = map[string]uint16{}
+		for code, label := range labels {
+			values[eventType][label] = code
+		}
+	}
+	return values
+}()
+
+// CodeName returns the label of the event code (e.g. ABS_MT_POSITION_X), or its hex value if unknown
+func CodeName(eventType EventType, code uint16) string {
+	if label, ok := eventCodeLabels[eventType][code]; ok {
+		return label
+	}
+	return fmt.Sprintf("%04x", code)
+}
+
+// ParseEventType parses an event type, either its label (e.g. EV_KEY) or its hex value
+func ParseEventType(value string) (EventType, error) {
+	for eventType, name := range eventTypeNames {
+		if name == value {
+			return eventType, nil
+		}
+	}
+
+	eventType, err := strconv.ParseUint(value, 16, 16)
+	if err != nil {
+		return 0, fmt.Errorf("unknown event type: %s", value)
+	}
+	return EventType(eventType), nil
+}
+
+// ParseEventCode parses an event code, either its label (e.g. KEY_ENTER) or its hex value
+func ParseEventCode(eventType EventType, value string) (uint16, error) {
+	if code, ok := eventCodeValues[eventType][value]; ok {
+		return code, nil
+	}
+
+	code, err := strconv.ParseUint(value, 16, 16)
+	if err != nil {
+		return 0, fmt.Errorf("unknown %s code: %s", eventType, value)
+	}
+	return uint16(code), nil
+}
+
+// parseEventValue parses an event value, either its label (e.g. DOWN) or its hex value
+func parseEventValue(eventType EventType, value string) (int32, error) {
+	if eventType == EV_KEY {
+		if v, ok := keyValueLabels[value]; ok {
+			return v, nil
+		}
+	}
+
+	v, err := strconv.ParseUint(value, 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid event value: %s", value)
+	}
+	return int32(uint32(v)), nil
+}
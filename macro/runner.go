@@ -0,0 +1,276 @@
+package macro
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	adbclient "github.com/sephiroth74/go_adb_client"
+	"github.com/sephiroth74/go_adb_client/input"
+	"github.com/sephiroth74/go_adb_client/inputmanager"
+	"github.com/sephiroth74/go_adb_client/process"
+	"github.com/sephiroth74/go_adb_client/types"
+)
+
+// logcatPollInterval is the interval between two logcat dumps of assert-logcat
+const logcatPollInterval = 500 * time.Millisecond
+
+// region Result
+
+// StepResult is the result of a single step
+type StepResult struct {
+	Index    int
+	Step     Step
+	Start    time.Time
+	Duration time.Duration
+	// Output is the screenshot file for screenshot, the matched line for assert-logcat
+	// and the resumed activity for wait-for-activity
+	Output string
+	Err    error
+}
+
+func (r StepResult) String() string {
+	status := "ok"
+	if r.Err != nil {
+		status = "FAILED: " + r.Err.Error()
+	}
+	return fmt.Sprintf("#%d %s (%s) %s", r.Index+1, r.Step, r.Duration.Round(time.Millisecond), status)
+}
+
+// Result is the result of a script run. Steps contains the results of the steps that were run
+type Result struct {
+	Script   string
+	Start    time.Time
+	Duration time.Duration
+	Steps    []StepResult
+}
+
+// Failed returns the results of the failed steps
+func (r Result) Failed() []StepResult {
+	var failed []StepResult
+	for _, step := range r.Steps {
+		if step.Err != nil {
+			failed = append(failed, step)
+		}
+	}
+	return failed
+}
+
+// Err returns the errors of the failed steps, nil if all the steps succeeded
+func (r Result) Err() error {
+	var errs []error
+	for _, step := range r.Failed() {
+		errs = append(errs, fmt.Errorf("step %d (%s): %w", step.Index+1, step.Step, step.Err))
+	}
+	return errors.Join(errs...)
+}
+
+func (r Result) String() string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("%s: %d steps, %d failed, %s\n", r.Script, len(r.Steps), len(r.Failed()), r.Duration.Round(time.Millisecond)))
+	for _, step := range r.Steps {
+		builder.WriteString(step.String())
+		builder.WriteString("\n")
+	}
+	return builder.String()
+}
+
+// endregion Result
+
+// region Runner
+
+type Runner struct {
+	Device *adbclient.Device
+	// OutputDir is the directory where the screenshots are saved. Default the current directory
+	OutputDir string
+	// ContinueOnError runs the remaining steps when a step fails
+	ContinueOnError bool
+	// StepDelay is the pause after each step
+	StepDelay time.Duration
+	// UnicodeMethod is used by text for non ascii text
+	UnicodeMethod inputmanager.UnicodeMethod
+}
+
+func NewRunner(device *adbclient.Device) *Runner {
+	return &Runner{
+		Device:        device,
+		OutputDir:     ".",
+		UnicodeMethod: inputmanager.UnicodeAdbKeyboard,
+	}
+}
+
+// Run runs the steps of the script and returns their results. Unless ContinueOnError is set, the run stops at the first failed step
+func (r Runner) Run(script Script) Result {
+	result := Result{Script: script.Name, Start: time.Now()}
+
+	// assert-logcat searches the log since the start of the previous step, in device time
+	clockOffset, clockErr := r.deviceClockOffset()
+	logcatSince := result.Start
+
+	for index, step := range script.Steps {
+		stepResult := StepResult{Index: index, Step: step, Start: time.Now()}
+
+		if err := step.Validate(); err != nil {
+			stepResult.Err = err
+		} else if step.Type == StepAssertLogcat && clockErr != nil {
+			stepResult.Err = clockErr
+		} else {
+			stepResult.Output, stepResult.Err = r.runStep(step, logcatSince.Add(clockOffset))
+		}
+
+		stepResult.Duration = time.Since(stepResult.Start)
+		result.Steps = append(result.Steps, stepResult)
+		logcatSince = stepResult.Start
+
+		if stepResult.Err != nil && !r.ContinueOnError {
+			break
+		}
+
+		if r.StepDelay > 0 {
+			time.Sleep(r.StepDelay)
+		}
+	}
+
+	result.Duration = time.Since(result.Start)
+	return result
+}
+
+// RunFile loads the script from the file (see Load) and runs it
+func (r Runner) RunFile(filename string) (*Result, error) {
+	script, err := Load(filename)
+	if err != nil {
+		return nil, err
+	}
+	result := r.Run(*script)
+	return &result, nil
+}
+
+func (r Runner) runStep(step Step, logcatSince time.Time) (string, error) {
+	shell := r.Device.Client.Shell
+
+	switch step.Type {
+	case StepKey:
+		var eventType *input.KeyEventType
+		if step.LongPress {
+			longPress := input.KEY_EVENTTYPE_LONGPRESS
+			eventType = &longPress
+		} else if step.DoubleTap {
+			doubleTap := input.KEY_EVENTTYPE_DOUBLETAP
+			eventType = &doubleTap
+		}
+
+		var keys []input.KeyCode
+		for i := 0; i < step.Repeat; i++ {
+			keys = append(keys, step.Keys...)
+		}
+		return "", process.Check(shell.SendKeyEvents(step.Source, eventType, keys...))
+
+	case StepText:
+		return "", r.Device.InputManager().SendText(step.Text, r.UnicodeMethod)
+
+	case StepTap:
+		return "", process.Check(shell.Tap(step.Source, types.Pair[int, int]{First: step.From.X, Second: step.From.Y}))
+
+	case StepSwipe:
+		return "", process.Check(shell.Swipe(step.Source, int32(step.Duration.Milliseconds()),
+			types.Pair[int, int]{First: step.From.X, Second: step.From.Y},
+			types.Pair[int, int]{First: step.To.X, Second: step.To.Y}))
+
+	case StepWait:
+		time.Sleep(step.Duration)
+		return "", nil
+
+	case StepWaitForActivity:
+		activity, err := r.Device.ActivityManager().WaitForActivity(step.Component, step.Timeout)
+		if err != nil {
+			return "", err
+		}
+		return activity.Component, nil
+
+	case StepAssertLogcat:
+		return r.waitForLogcat(step.Pattern, logcatSince, step.Timeout)
+
+	case StepScreenshot:
+		return r.screenshot(step.File)
+	}
+	return "", fmt.Errorf("unknown step: %s", step.Type)
+}
+
+// waitForLogcat polls the log dump until a line since the given device time matches the pattern
+func (r Runner) waitForLogcat(pattern string, since time.Time, timeout time.Duration) (string, error) {
+	// logcat accepts the time as seconds since epoch
+	sinceArg := fmt.Sprintf("%d.%03d", since.Unix(), since.Nanosecond()/int(time.Millisecond))
+	deadline := time.Now().Add(timeout)
+
+	for {
+		cmd := r.Device.Client.NewAdbCommand().WithCommand("logcat").WithArgs("-d", "-T", sinceArg, "-e", pattern)
+		result, err := process.SimpleOutput(cmd, r.Device.Client.Conn.Verbose)
+		if err != nil {
+			return "", err
+		}
+		if !result.IsOk() {
+			return "", result.NewError()
+		}
+
+		for _, line := range result.OutputLines(true) {
+			if line != "" && !strings.HasPrefix(line, "--------- beginning of") {
+				return line, nil
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("pattern not found in logcat after %s: %s", timeout, pattern)
+		}
+		time.Sleep(logcatPollInterval)
+	}
+}
+
+func (r Runner) screenshot(file string) (string, error) {
+	filename := file
+	if !filepath.IsAbs(filename) {
+		filename = filepath.Join(r.OutputDir, file)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return "", err
+	}
+
+	output, err := os.Create(filename)
+	if err != nil {
+		return "", err
+	}
+	defer output.Close()
+
+	if err := process.Check(r.Device.WriteScreenCap(output)); err != nil {
+		return "", err
+	}
+	return filename, nil
+}
+
+// deviceClockOffset returns the difference between the device and the host clocks
+func (r Runner) deviceClockOffset() (time.Duration, error) {
+	before := time.Now()
+	result, err := r.Device.Client.Shell.Execute("date", "+%s")
+	if err != nil {
+		return 0, err
+	}
+	if !result.IsOk() {
+		return 0, result.NewError()
+	}
+
+	seconds, err := strconv.ParseInt(result.Output(), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid device time: %s", result.Output())
+	}
+
+	// the device time is truncated to the second: it was read halfway through the command,
+	// when the device clock was on average half a second after the printed second
+	host := before.Add(time.Since(before) / 2)
+	return time.Unix(seconds, 0).Add(time.Second / 2).Sub(host), nil
+}
+
+// endregion Runner
@@ -0,0 +1,603 @@
+package macro
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sephiroth74/go_adb_client/input"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// DefaultTimeout is the timeout of wait-for-activity and assert-logcat when not specified
+	DefaultTimeout = 10 * time.Second
+	// DefaultSwipeDuration is the duration of swipe when not specified
+	DefaultSwipeDuration = 300 * time.Millisecond
+)
+
+// region StepType
+
+type StepType string
+
+const (
+	StepKey             StepType = "key"
+	StepText            StepType = "text"
+	StepTap             StepType = "tap"
+	StepSwipe           StepType = "swipe"
+	StepWait            StepType = "wait"
+	StepWaitForActivity StepType = "wait-for-activity"
+	StepAssertLogcat    StepType = "assert-logcat"
+	StepScreenshot      StepType = "screenshot"
+)
+
+var stepTypes = []StepType{StepKey, StepText, StepTap, StepSwipe, StepWait, StepWaitForActivity, StepAssertLogcat, StepScreenshot}
+
+func parseStepType(value string) (StepType, error) {
+	for _, t := range stepTypes {
+		if string(t) == value {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("unknown step: %s", value)
+}
+
+// endregion StepType
+
+// region Step
+
+// Step is a single macro instruction. Only the fields of its type are used
+type Step struct {
+	Type StepType
+	// Line is the line of the step in the script, 0 if unknown
+	Line int
+
+	// Keys are the keys sent by key, Repeat times
+	Keys      []input.KeyCode
+	Repeat    int
+	LongPress bool
+	DoubleTap bool
+	// Source is the input source of key, tap and swipe
+	Source input.InputSource
+
+	// Text is typed by text
+	Text string
+
+	// From is the point of tap and the start of swipe, To is the end of swipe
+	From image.Point
+	To   image.Point
+
+	// Duration is the duration of wait and swipe
+	Duration time.Duration
+
+	// Component is the activity waited by wait-for-activity
+	Component string
+	// Pattern is the regular expression searched by assert-logcat
+	Pattern string
+	// Timeout of wait-for-activity and assert-logcat
+	Timeout time.Duration
+
+	// File is where screenshot saves the screenshot, relative to the runner OutputDir
+	File string
+}
+
+// String returns the step in the text format
+func (s Step) String() string {
+	var args []string
+	switch s.Type {
+	case StepKey:
+		for _, key := range s.Keys {
+			args = append(args, strings.TrimPrefix(key.String(), "KEYCODE_"))
+		}
+		if s.Repeat > 1 {
+			args = append(args, fmt.Sprintf("repeat=%d", s.Repeat))
+		}
+		if s.LongPress {
+			args = append(args, "longpress")
+		}
+		if s.DoubleTap {
+			args = append(args, "doubletap")
+		}
+		if s.Source != input.KEYBOARD {
+			args = append(args, "source="+s.Source.String())
+		}
+	case StepText:
+		args = append(args, strconv.Quote(s.Text))
+	case StepTap:
+		args = append(args, strconv.Itoa(s.From.X), strconv.Itoa(s.From.Y))
+	case StepSwipe:
+		args = append(args, strconv.Itoa(s.From.X), strconv.Itoa(s.From.Y), strconv.Itoa(s.To.X), strconv.Itoa(s.To.Y), "duration="+s.Duration.String())
+	case StepWait:
+		args = append(args, s.Duration.String())
+	case StepWaitForActivity:
+		args = append(args, s.Component, "timeout="+s.Timeout.String())
+	case StepAssertLogcat:
+		args = append(args, strconv.Quote(s.Pattern), "timeout="+s.Timeout.String())
+	case StepScreenshot:
+		args = append(args, s.File)
+	}
+	return strings.TrimSpace(string(s.Type) + " " + strings.Join(args, " "))
+}
+
+// Validate returns an error if the required fields of the step are missing
+func (s Step) Validate() error {
+	switch s.Type {
+	case StepKey:
+		if len(s.Keys) == 0 {
+			return errors.New("key requires at least one key")
+		}
+		if s.LongPress && s.DoubleTap {
+			return errors.New("key cannot be both longpress and doubletap")
+		}
+		if s.Repeat < 1 {
+			return fmt.Errorf("invalid key repeat: %d", s.Repeat)
+		}
+	case StepText:
+		if s.Text == "" {
+			return errors.New("text requires a text")
+		}
+	case StepWait:
+		if s.Duration <= 0 {
+			return errors.New("wait requires a positive duration")
+		}
+	case StepWaitForActivity:
+		if s.Component == "" {
+			return errors.New("wait-for-activity requires a component")
+		}
+	case StepAssertLogcat:
+		if s.Pattern == "" {
+			return errors.New("assert-logcat requires a pattern")
+		}
+		if _, err := regexp.Compile(s.Pattern); err != nil {
+			return err
+		}
+	case StepScreenshot:
+		if s.File == "" {
+			return errors.New("screenshot requires a file")
+		}
+	case StepTap, StepSwipe:
+	default:
+		return fmt.Errorf("unknown step: %s", s.Type)
+	}
+	return nil
+}
+
+// newStep returns a step with the defaults of its type
+func newStep(stepType StepType, line int) Step {
+	step := Step{Type: stepType, Line: line, Repeat: 1, Source: input.KEYBOARD}
+	switch stepType {
+	case StepTap, StepSwipe:
+		step.Source = input.TOUCHSCREEEN
+		if stepType == StepSwipe {
+			step.Duration = DefaultSwipeDuration
+		}
+	case StepWaitForActivity, StepAssertLogcat:
+		step.Timeout = DefaultTimeout
+	}
+	return step
+}
+
+// endregion Step
+
+// region Script
+
+// Script is a sequence of steps
+type Script struct {
+	Name  string
+	Steps []Step
+}
+
+// Load reads a script from the file. Files with the .yaml or .yml extension are parsed with ParseYAML,
+// the others with ParseText
+func Load(filename string) (*Script, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var script *Script
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		script, err = ParseYAML(data)
+	default:
+		script, err = ParseText(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if script.Name == "" {
+		script.Name = strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	}
+	return script, nil
+}
+
+// endregion Script
+
+// region Text
+
+type token struct {
+	value  string
+	quoted bool
+}
+
+// tokenize splits the line on spaces. Double quoted tokens are unquoted, so they can contain spaces
+func tokenize(line string) ([]token, error) {
+	var tokens []token
+	var current strings.Builder
+	inToken, quoted := false, false
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '"':
+			end := i + 1
+			for ; end < len(line) && line[end] != '"'; end++ {
+				if line[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(line) {
+				return nil, errors.New("unterminated quote")
+			}
+			value, err := strconv.Unquote(line[i : end+1])
+			if err != nil {
+				return nil, err
+			}
+			current.WriteString(value)
+			inToken, quoted = true, true
+			i = end
+		case c == ' ' || c == '\t':
+			if inToken {
+				tokens = append(tokens, token{value: current.String(), quoted: quoted})
+				current.Reset()
+				inToken, quoted = false, false
+			}
+		default:
+			current.WriteByte(c)
+			inToken = true
+		}
+	}
+
+	if inToken {
+		tokens = append(tokens, token{value: current.String(), quoted: quoted})
+	}
+	return tokens, nil
+}
+
+// ParseText parses a script in the text format, one step for each line:
+//
+//	# comment
+//	key DPAD_DOWN repeat=3 source=dpad
+//	key HOME longpress
+//	text "hello world"
+//	tap 500 800
+//	swipe 500 1500 500 300 duration=200ms
+//	wait 2s
+//	wait-for-activity com.example/.MainActivity timeout=5s
+//	assert-logcat "Displayed com.example" timeout=5s
+//	screenshot home.png
+func ParseText(r io.Reader) (*Script, error) {
+	script := &Script{}
+	scanner := bufio.NewScanner(r)
+	line := 0
+
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		step, err := parseTextStep(text, line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		script.Steps = append(script.Steps, step)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return script, nil
+}
+
+func parseTextStep(text string, line int) (Step, error) {
+	tokens, err := tokenize(text)
+	if err != nil {
+		return Step{}, err
+	}
+
+	stepType, err := parseStepType(tokens[0].value)
+	if err != nil {
+		return Step{}, err
+	}
+	step := newStep(stepType, line)
+
+	var args []string
+	for _, t := range tokens[1:] {
+		if t.quoted {
+			args = append(args, t.value)
+			continue
+		}
+
+		name, value, ok := strings.Cut(t.value, "=")
+		switch {
+		case ok:
+			if err := step.setOption(name, value); err != nil {
+				return Step{}, err
+			}
+		case t.value == "longpress" && stepType == StepKey:
+			step.LongPress = true
+		case t.value == "doubletap" && stepType == StepKey:
+			step.DoubleTap = true
+		default:
+			args = append(args, t.value)
+		}
+	}
+
+	if err := step.setArgs(args); err != nil {
+		return Step{}, err
+	}
+	return step, step.Validate()
+}
+
+func (s *Step) setOption(name string, value string) error {
+	var err error
+	switch name {
+	case "repeat":
+		s.Repeat, err = strconv.Atoi(value)
+	case "source":
+		s.Source, err = input.ParseInputSource(value)
+	case "duration":
+		s.Duration, err = time.ParseDuration(value)
+	case "timeout":
+		s.Timeout, err = time.ParseDuration(value)
+	default:
+		err = fmt.Errorf("unknown option: %s", name)
+	}
+	return err
+}
+
+// setArgs sets the positional arguments of the step
+func (s *Step) setArgs(args []string) error {
+	ints := func(count int) ([]int, error) {
+		if len(args) != count {
+			return nil, fmt.Errorf("%s requires %d coordinates", s.Type, count)
+		}
+		values := make([]int, count)
+		for i, arg := range args {
+			value, err := strconv.Atoi(arg)
+			if err != nil {
+				return nil, fmt.Errorf("invalid coordinate: %s", arg)
+			}
+			values[i] = value
+		}
+		return values, nil
+	}
+
+	switch s.Type {
+	case StepKey:
+		for _, arg := range args {
+			key, err := input.ParseKeyCode(arg)
+			if err != nil {
+				return err
+			}
+			s.Keys = append(s.Keys, key)
+		}
+	case StepText:
+		s.Text = strings.Join(args, " ")
+	case StepTap:
+		values, err := ints(2)
+		if err != nil {
+			return err
+		}
+		s.From = image.Pt(values[0], values[1])
+	case StepSwipe:
+		values, err := ints(4)
+		if err != nil {
+			return err
+		}
+		s.From, s.To = image.Pt(values[0], values[1]), image.Pt(values[2], values[3])
+	case StepWait:
+		if len(args) != 1 {
+			return errors.New("wait requires a duration")
+		}
+		duration, err := time.ParseDuration(args[0])
+		if err != nil {
+			return err
+		}
+		s.Duration = duration
+	case StepWaitForActivity:
+		s.Component = strings.Join(args, " ")
+	case StepAssertLogcat:
+		s.Pattern = strings.Join(args, " ")
+	case StepScreenshot:
+		s.File = strings.Join(args, " ")
+	}
+	return nil
+}
+
+// endregion Text
+
+// region YAML
+
+// yamlStep is a step in the yaml format, where the step type is the key of its main argument
+type yamlStep struct {
+	Key             keyList            `yaml:"key"`
+	Text            *string            `yaml:"text"`
+	Tap             []int              `yaml:"tap"`
+	Swipe           *yamlSwipe         `yaml:"swipe"`
+	Wait            *time.Duration     `yaml:"wait"`
+	WaitForActivity *string            `yaml:"wait-for-activity"`
+	AssertLogcat    *string            `yaml:"assert-logcat"`
+	Screenshot      *string            `yaml:"screenshot"`
+	Repeat          *int               `yaml:"repeat"`
+	LongPress       bool               `yaml:"longpress"`
+	DoubleTap       bool               `yaml:"doubletap"`
+	Source          *input.InputSource `yaml:"source"`
+	Duration        *time.Duration     `yaml:"duration"`
+	Timeout         *time.Duration     `yaml:"timeout"`
+}
+
+type yamlSwipe struct {
+	From []int `yaml:"from"`
+	To   []int `yaml:"to"`
+}
+
+// keyList is a single key or a list of keys
+type keyList []input.KeyCode
+
+func (k *keyList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		var key input.KeyCode
+		if err := node.Decode(&key); err != nil {
+			return err
+		}
+		*k = keyList{key}
+		return nil
+	}
+
+	var keys []input.KeyCode
+	if err := node.Decode(&keys); err != nil {
+		return err
+	}
+	*k = keys
+	return nil
+}
+
+type yamlScript struct {
+	Name  string      `yaml:"name"`
+	Steps []yaml.Node `yaml:"steps"`
+}
+
+// ParseYAML parses a script in the yaml format:
+//
+//	name: smoke
+//	steps:
+//	  - key: DPAD_DOWN
+//	    repeat: 3
+//	    source: dpad
+//	  - key: [DPAD_RIGHT, ENTER]
+//	  - text: hello world
+//	  - tap: [500, 800]
+//	  - swipe: {from: [500, 1500], to: [500, 300]}
+//	    duration: 200ms
+//	  - wait: 2s
+//	  - wait-for-activity: com.example/.MainActivity
+//	    timeout: 5s
+//	  - assert-logcat: Displayed com.example
+//	  - screenshot: home.png
+func ParseYAML(data []byte) (*Script, error) {
+	var document yamlScript
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+
+	script := &Script{Name: document.Name}
+	for _, node := range document.Steps {
+		var y yamlStep
+		if err := node.Decode(&y); err != nil {
+			return nil, fmt.Errorf("line %d: %w", node.Line, err)
+		}
+
+		step, err := y.step(node.Line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", node.Line, err)
+		}
+		script.Steps = append(script.Steps, step)
+	}
+	return script, nil
+}
+
+func (y yamlStep) step(line int) (Step, error) {
+	var types []StepType
+	if len(y.Key) > 0 {
+		types = append(types, StepKey)
+	}
+	if y.Text != nil {
+		types = append(types, StepText)
+	}
+	if y.Tap != nil {
+		types = append(types, StepTap)
+	}
+	if y.Swipe != nil {
+		types = append(types, StepSwipe)
+	}
+	if y.Wait != nil {
+		types = append(types, StepWait)
+	}
+	if y.WaitForActivity != nil {
+		types = append(types, StepWaitForActivity)
+	}
+	if y.AssertLogcat != nil {
+		types = append(types, StepAssertLogcat)
+	}
+	if y.Screenshot != nil {
+		types = append(types, StepScreenshot)
+	}
+
+	if len(types) != 1 {
+		return Step{}, fmt.Errorf("a step requires exactly one of %v", stepTypes)
+	}
+
+	step := newStep(types[0], line)
+	point := func(values []int) (image.Point, error) {
+		if len(values) != 2 {
+			return image.Point{}, fmt.Errorf("%s requires [x, y] points", step.Type)
+		}
+		return image.Pt(values[0], values[1]), nil
+	}
+
+	var err error
+	switch step.Type {
+	case StepKey:
+		step.Keys = y.Key
+	case StepText:
+		step.Text = *y.Text
+	case StepTap:
+		step.From, err = point(y.Tap)
+	case StepSwipe:
+		if step.From, err = point(y.Swipe.From); err == nil {
+			step.To, err = point(y.Swipe.To)
+		}
+	case StepWait:
+		step.Duration = *y.Wait
+	case StepWaitForActivity:
+		step.Component = *y.WaitForActivity
+	case StepAssertLogcat:
+		step.Pattern = *y.AssertLogcat
+	case StepScreenshot:
+		step.File = *y.Screenshot
+	}
+	if err != nil {
+		return Step{}, err
+	}
+
+	if y.Repeat != nil {
+		step.Repeat = *y.Repeat
+	}
+	step.LongPress = y.LongPress
+	step.DoubleTap = y.DoubleTap
+	if y.Source != nil {
+		step.Source = *y.Source
+	}
+	if y.Duration != nil {
+		step.Duration = *y.Duration
+	}
+	if y.Timeout != nil {
+		step.Timeout = *y.Timeout
+	}
+	return step, step.Validate()
+}
+
+// endregion YAML
@@ -0,0 +1,200 @@
+package packagemanager
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sephiroth74/go_adb_client/process"
+	"github.com/sephiroth74/go_adb_client/types"
+)
+
+var (
+	appOpRegexp         = regexp.MustCompile(`^(Uid mode: )?([A-Z0-9_]+): ([a-z]+)(?:; (.*))?$`)
+	appOpAccessRegexp   = regexp.MustCompile(`^(Access|Reject):\s*(?:\[[^\]]*\])?\s*(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}\.\d{3})(?:.*duration=(\S+))?`)
+	appOpDurationRegexp = regexp.MustCompile(`(\d+)(d|h|ms|m|s)`)
+)
+
+const appOpTimeLayout = "2006-01-02 15:04:05.000"
+
+// region AppOpMode
+
+type AppOpMode string
+
+const (
+	AppOpModeAllow      AppOpMode = "allow"
+	AppOpModeIgnore     AppOpMode = "ignore"
+	AppOpModeDeny       AppOpMode = "deny"
+	AppOpModeDefault    AppOpMode = "default"
+	AppOpModeForeground AppOpMode = "foreground"
+)
+
+// endregion AppOpMode
+
+// region AppOp
+
+// AppOp is the state of an app operation of a package, as reported by "appops get"
+type AppOp struct {
+	Name string
+	Mode AppOpMode
+	// UidMode is true when the mode is set for the package uid instead of the package
+	UidMode        bool
+	LastAccessTime time.Time
+	LastRejectTime time.Time
+	// Duration of the last access
+	Duration time.Duration
+	// Running is true if the op is in use
+	Running bool
+}
+
+func (a AppOp) String() string {
+	return fmt.Sprintf("AppOp{Name:%s, Mode:%s, UidMode:%t, LastAccessTime:%s, LastRejectTime:%s, Duration:%s, Running:%t}",
+		a.Name, a.Mode, a.UidMode, a.LastAccessTime, a.LastRejectTime, a.Duration, a.Running)
+}
+
+// ParseAppOps parses the output of "appops get <pkg>".
+// Both the legacy format (time=+1h2m ago) and the attributed one (Access: [fg-s] 2023-01-01 10:00:00.000) are supported
+func ParseAppOps(data string) []AppOp {
+	return parseAppOps(data, time.Now())
+}
+
+func parseAppOps(data string, now time.Time) []AppOp {
+	var result []AppOp
+	var current *AppOp
+
+	for _, line := range strings.Split(data, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		if m := appOpRegexp.FindStringSubmatch(trimmed); m != nil {
+			result = append(result, AppOp{Name: m[2], Mode: AppOpMode(m[3]), UidMode: m[1] != ""})
+			current = &result[len(result)-1]
+
+			for _, pair := range strings.Split(m[4], ";") {
+				kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+				if len(kv) != 2 {
+					continue
+				}
+				switch kv[0] {
+				case "time":
+					current.LastAccessTime = now.Add(-parseAppOpDuration(kv[1]))
+				case "rejectTime":
+					current.LastRejectTime = now.Add(-parseAppOpDuration(kv[1]))
+				case "duration":
+					if kv[1] == "running" {
+						current.Running = true
+					} else {
+						current.Duration = parseAppOpDuration(kv[1])
+					}
+				}
+			}
+			continue
+		}
+
+		if current == nil {
+			continue
+		}
+
+		if m := appOpAccessRegexp.FindStringSubmatch(trimmed); m != nil {
+			t, err := time.ParseInLocation(appOpTimeLayout, m[2], time.Local)
+			if err != nil {
+				continue
+			}
+			if m[1] == "Reject" {
+				if t.After(current.LastRejectTime) {
+					current.LastRejectTime = t
+				}
+			} else if t.After(current.LastAccessTime) {
+				current.LastAccessTime = t
+				current.Duration = parseAppOpDuration(m[3])
+			}
+		} else if strings.Contains(trimmed, "Running start at") {
+			current.Running = true
+		}
+	}
+	return result
+}
+
+// parseAppOpDuration parses the durations in the format +1d2h3m4s5ms
+func parseAppOpDuration(value string) time.Duration {
+	value = strings.TrimSuffix(strings.TrimSpace(value), " ago")
+	var result time.Duration
+	for _, m := range appOpDurationRegexp.FindAllStringSubmatch(value, -1) {
+		n, _ := strconv.Atoi(m[1])
+		switch m[2] {
+		case "d":
+			result += time.Duration(n) * 24 * time.Hour
+		case "h":
+			result += time.Duration(n) * time.Hour
+		case "m":
+			result += time.Duration(n) * time.Minute
+		case "s":
+			result += time.Duration(n) * time.Second
+		case "ms":
+			result += time.Duration(n) * time.Millisecond
+		}
+	}
+	return result
+}
+
+// findAppOp returns the op with the given name, the package mode takes precedence over the uid mode
+func findAppOp(ops []AppOp, name string) *AppOp {
+	var result *AppOp
+	for i := range ops {
+		if ops[i].Name == name && (result == nil || result.UidMode) {
+			result = &ops[i]
+		}
+	}
+	return result
+}
+
+// endregion AppOp
+
+// GetAppOps returns the app ops of the given package with "appops get"
+func (p PackageManager) GetAppOps(packageName string, user types.UserId) ([]AppOp, error) {
+	cmd := p.Shell.NewCommand().WithArgs("appops", "get")
+	cmd.AddArgs(user.Args()...)
+	cmd.AddArgs(packageName)
+
+	result, err := process.SimpleOutput(cmd, p.Shell.Conn.Verbose)
+	if err != nil {
+		return nil, err
+	}
+
+	if !result.IsOk() {
+		return nil, result.NewError()
+	}
+
+	return ParseAppOps(result.Output()), nil
+}
+
+// GetAppOp returns the given app op of the package. If the op has never been changed nor used the default mode is returned
+func (p PackageManager) GetAppOp(packageName string, op string, user types.UserId) (*AppOp, error) {
+	ops, err := p.GetAppOps(packageName, user)
+	if err != nil {
+		return nil, err
+	}
+
+	result := findAppOp(ops, op)
+	if result == nil {
+		return &AppOp{Name: op, Mode: AppOpModeDefault}, nil
+	}
+	return result, nil
+}
+
+// SetAppOp sets the mode of the given op with "appops set"
+func (p PackageManager) SetAppOp(packageName string, op string, mode AppOpMode, user types.UserId) error {
+	cmd := p.Shell.NewCommand().WithArgs("appops", "set")
+	cmd.AddArgs(user.Args()...)
+	cmd.AddArgs(packageName, op, string(mode))
+	return p.execCommand(cmd)
+}
+
+// ResetAppOps resets the app ops of the package to their default modes with "appops reset"
+func (p PackageManager) ResetAppOps(packageName string, user types.UserId) error {
+	cmd := p.Shell.NewCommand().WithArgs("appops", "reset")
+	cmd.AddArgs(user.Args()...)
+	cmd.AddArgs(packageName)
+	return p.execCommand(cmd)
+}
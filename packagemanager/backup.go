@@ -0,0 +1,526 @@
+package packagemanager
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sephiroth74/go_adb_client/process"
+	"github.com/sephiroth74/go_adb_client/types"
+)
+
+const (
+	backupManifestName = "manifest.json"
+	backupApksDir      = "apks"
+	backupDataName     = "data.tar"
+)
+
+// externalAppDirs are the app specific dirs on the shared storage
+var externalAppDirs = []string{"/sdcard/Android/data/%s", "/sdcard/Android/media/%s", "/sdcard/Android/obb/%s"}
+
+var packageNameRegexp = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*(\.[A-Za-z0-9_]+)*$`)
+
+// region Backup
+
+type BackupOptions struct {
+	// SkipData does not archive the private data dir of the package
+	SkipData bool
+	// SkipExternal does not archive the app dirs on the shared storage
+	SkipExternal bool
+}
+
+// BackupDir is a device directory saved in the backup archive as a tar entry
+type BackupDir struct {
+	Path  string `json:"path"`
+	Entry string `json:"entry"`
+}
+
+// BackupManifest describes the content of a backup archive
+type BackupManifest struct {
+	PackageName string `json:"packageName"`
+	VersionCode int64  `json:"versionCode"`
+	VersionName string `json:"versionName"`
+	// Apks are the archive entries of the base apk and the splits
+	Apks []string `json:"apks"`
+	// Permissions are the granted runtime permissions
+	Permissions []string    `json:"permissions"`
+	Data        *BackupDir  `json:"data,omitempty"`
+	External    []BackupDir `json:"external,omitempty"`
+	CreatedAt   time.Time   `json:"createdAt"`
+}
+
+// Backup saves the apks, the data and the external dirs of the given package into the dst tar archive.
+// The private data dir is read as root when adbd is running as root, otherwise with "run-as", which
+// requires a debuggable package
+func (p PackageManager) Backup(packageName string, dst string, options *BackupOptions) (*BackupManifest, error) {
+	if options == nil {
+		options = &BackupOptions{}
+	}
+
+	info, err := p.GetPackageInfo(packageName)
+	if err != nil {
+		return nil, err
+	}
+
+	paths, err := p.Paths(packageName, "")
+	if err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.MkdirTemp("", "backup-"+packageName)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	manifest := &BackupManifest{
+		PackageName: packageName,
+		VersionCode: info.VersionCode,
+		VersionName: info.VersionName,
+		CreatedAt:   time.Now(),
+	}
+
+	if user := info.User(types.UserSystem); user != nil {
+		for _, permission := range user.RuntimePermissions {
+			if permission.Granted {
+				manifest.Permissions = append(manifest.Permissions, permission.Name)
+			}
+		}
+	}
+
+	files := make(map[string]string)
+	addr := p.Shell.Address.GetSerialAddress()
+
+	for _, src := range paths {
+		entry := path.Join(backupApksDir, path.Base(src))
+		local := filepath.Join(tmp, filepath.FromSlash(entry))
+		if err := os.MkdirAll(filepath.Dir(local), 0o755); err != nil {
+			return nil, err
+		}
+
+		result, err := p.Shell.Conn.Pull(addr, src, local)
+		if err != nil {
+			return nil, err
+		}
+		if !result.IsOk() {
+			return nil, result.NewError()
+		}
+
+		manifest.Apks = append(manifest.Apks, entry)
+		files[entry] = local
+	}
+
+	if !options.SkipData {
+		root, err := p.isRoot()
+		if err != nil {
+			return nil, err
+		}
+
+		dataDir := info.DataDir
+		if dataDir == "" {
+			dataDir = "/data/data/" + packageName
+		}
+
+		local := filepath.Join(tmp, backupDataName)
+		runAs := ""
+		if !root {
+			runAs = packageName
+		}
+		if err := p.archiveDir(dataDir, runAs, local); err != nil {
+			return nil, err
+		}
+
+		manifest.Data = &BackupDir{Path: dataDir, Entry: backupDataName}
+		files[backupDataName] = local
+	}
+
+	if !options.SkipExternal {
+		for index, format := range externalAppDirs {
+			dir := fmt.Sprintf(format, packageName)
+			if !p.Shell.Exists(dir) {
+				continue
+			}
+
+			entry := fmt.Sprintf("external_%d.tar", index)
+			local := filepath.Join(tmp, entry)
+			if err := p.archiveDir(dir, "", local); err != nil {
+				return nil, err
+			}
+
+			manifest.External = append(manifest.External, BackupDir{Path: dir, Entry: entry})
+			files[entry] = local
+		}
+	}
+
+	if err := writeBackup(dst, manifest, files); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// archiveDir writes the content of the device dir as a tar file to the host dst file
+func (p PackageManager) archiveDir(dir string, runAs string, dst string) error {
+	file, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	args := []string{"tar", "-cf", "-", "-C", dir, ".", "2>/dev/null"}
+	if runAs != "" {
+		args = append([]string{"run-as", runAs}, args...)
+	}
+
+	// exec-out keeps the binary output untouched
+	cmd := p.Shell.Conn.NewAdbCommand().WithSerialAddr(&p.Shell.Address).WithCommand("exec-out").WithArgs(args...).WithStdOut(file)
+	result, err := process.SimpleOutput(cmd, p.Shell.Conn.Verbose)
+	if err != nil {
+		return err
+	}
+	if !result.IsOk() {
+		return result.NewError()
+	}
+
+	// exec-out doesn't return the remote exit code, so check that a valid archive has been written
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := tar.NewReader(file).Next(); err != nil {
+		return fmt.Errorf("unable to archive %s: %w", dir, err)
+	}
+	return nil
+}
+
+func (p PackageManager) isRoot() (bool, error) {
+	result, err := p.Shell.Whoami()
+	if err != nil {
+		return false, err
+	}
+	if !result.IsOk() {
+		return false, result.NewError()
+	}
+	return result.Output() == "root", nil
+}
+
+func writeBackup(dst string, manifest *BackupManifest, files map[string]string) error {
+	file, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := tar.NewWriter(file)
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writer.WriteHeader(&tar.Header{Name: backupManifestName, Mode: 0o644, Size: int64(len(data)), ModTime: manifest.CreatedAt}); err != nil {
+		return err
+	}
+	if _, err := writer.Write(data); err != nil {
+		return err
+	}
+
+	for entry, local := range files {
+		if err := addBackupFile(writer, entry, local); err != nil {
+			return err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+func addBackupFile(writer *tar.Writer, entry string, local string) error {
+	file, err := os.Open(local)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	header, err := tar.FileInfoHeader(stat, "")
+	if err != nil {
+		return err
+	}
+	header.Name = entry
+
+	if err := writer.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = io.Copy(writer, file)
+	return err
+}
+
+// endregion Backup
+
+// region Restore
+
+type RestoreOptions struct {
+	// Install are the options used to install the apks, ReplaceExistingApplication is used when nil
+	Install *InstallSessionOptions
+	// SkipData does not restore the private data dir
+	SkipData bool
+	// SkipExternal does not restore the app dirs on the shared storage
+	SkipExternal bool
+	// SkipPermissions does not grant the runtime permissions saved in the backup
+	SkipPermissions bool
+}
+
+// Restore installs the package saved with Backup, restores its data and grants again its runtime permissions.
+// Permissions which cannot be granted are reported in the returned error, after the restore is completed
+func (p PackageManager) Restore(src string, options *RestoreOptions) (*BackupManifest, error) {
+	if options == nil {
+		options = &RestoreOptions{}
+	}
+
+	tmp, err := os.MkdirTemp("", "restore-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	manifest, err := readBackup(src, tmp)
+	if err != nil {
+		return nil, err
+	}
+
+	installOptions := options.Install
+	if installOptions == nil {
+		installOptions = &InstallSessionOptions{InstallOptions: InstallOptions{ReplaceExistingApplication: true}}
+	}
+
+	var apks []string
+	for _, entry := range manifest.Apks {
+		apks = append(apks, filepath.Join(tmp, filepath.FromSlash(entry)))
+	}
+	if err := p.InstallMultiple(apks, installOptions); err != nil {
+		return manifest, err
+	}
+
+	if (!options.SkipData && manifest.Data != nil) || (!options.SkipExternal && len(manifest.External) > 0) {
+		result, err := process.SimpleOutput(p.Shell.NewCommand().WithArgs("am", "force-stop", manifest.PackageName), p.Shell.Conn.Verbose)
+		if err != nil {
+			return manifest, err
+		}
+		if !result.IsOk() {
+			return manifest, result.NewError()
+		}
+	}
+
+	if !options.SkipData && manifest.Data != nil {
+		if err := p.restoreData(manifest, filepath.Join(tmp, filepath.FromSlash(manifest.Data.Entry))); err != nil {
+			return manifest, err
+		}
+	}
+
+	if !options.SkipExternal {
+		for _, dir := range manifest.External {
+			if err := p.extractDir(path.Clean(dir.Path), "", filepath.Join(tmp, filepath.FromSlash(dir.Entry))); err != nil {
+				return manifest, err
+			}
+		}
+	}
+
+	if options.SkipPermissions {
+		return manifest, nil
+	}
+
+	var errs []error
+	for _, permission := range manifest.Permissions {
+		result, err := p.GrantPermission(manifest.PackageName, permission)
+		if err != nil {
+			errs = append(errs, err)
+		} else if !result.IsOk() {
+			errs = append(errs, fmt.Errorf("unable to grant %s: %s", permission, result.Error()))
+		}
+	}
+	return manifest, errors.Join(errs...)
+}
+
+func (p PackageManager) restoreData(manifest *BackupManifest, local string) error {
+	info, err := p.GetPackageInfo(manifest.PackageName)
+	if err != nil {
+		return err
+	}
+
+	// the data dir can change between devices (e.g. /data/data and /data/user/0),
+	// the dir of the manifest is not trusted
+	dataDir := info.DataDir
+	if dataDir == "" {
+		dataDir = "/data/data/" + manifest.PackageName
+	}
+
+	root, err := p.isRoot()
+	if err != nil {
+		return err
+	}
+
+	if !root {
+		return p.extractDir(dataDir, manifest.PackageName, local)
+	}
+
+	if err := p.extractDir(dataDir, "", local); err != nil {
+		return err
+	}
+
+	// files extracted as root keep the owner of the source device
+	owner := fmt.Sprintf("%d:%d", info.UserID, info.UserID)
+	result, err := p.Shell.Execute("chown", "-R", owner, dataDir, "&&", "restorecon", "-RF", dataDir)
+	if err != nil {
+		return err
+	}
+	if !result.IsOk() {
+		return result.NewError()
+	}
+	return nil
+}
+
+// extractDir extracts the host tar file into the device dir
+func (p PackageManager) extractDir(dir string, runAs string, local string) error {
+	file, err := os.Open(local)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var args []string
+	if runAs != "" {
+		args = append(args, "run-as", runAs)
+	} else {
+		args = append(args, "mkdir", "-p", dir, "&&")
+	}
+	args = append(args, "tar", "-xf", "-", "-C", dir)
+
+	result, err := process.SimpleOutput(p.Shell.NewCommand().WithArgs(args...).WithStdIn(file), p.Shell.Conn.Verbose)
+	if err != nil {
+		return err
+	}
+	if !result.IsOk() {
+		return result.NewError()
+	}
+	return nil
+}
+
+// readBackup extracts the backup archive into dir and returns its manifest
+func readBackup(src string, dir string) (*BackupManifest, error) {
+	file, err := os.Open(src)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var manifest *BackupManifest
+	reader := tar.NewReader(file)
+
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		name := path.Clean(header.Name)
+		if header.Typeflag != tar.TypeReg || !isBackupEntry(name) {
+			continue
+		}
+
+		if name == backupManifestName {
+			manifest = &BackupManifest{}
+			if err := json.NewDecoder(reader).Decode(manifest); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if err := extractBackupFile(reader, filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+			return nil, err
+		}
+	}
+
+	if manifest == nil {
+		return nil, fmt.Errorf("%s not found, not a valid backup", backupManifestName)
+	}
+	if len(manifest.Apks) == 0 {
+		return nil, errors.New("no apk found in backup")
+	}
+	if err := manifest.validate(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// isBackupEntry returns true if the name is a relative path inside the archive
+func isBackupEntry(name string) bool {
+	name = path.Clean(name)
+	return name != "." && name != ".." && !path.IsAbs(name) && !strings.HasPrefix(name, "../")
+}
+
+// validate checks the manifest read from the archive: the entries must be inside the archive
+// and the external dirs must be the app dirs on the shared storage
+func (m *BackupManifest) validate() error {
+	if !packageNameRegexp.MatchString(m.PackageName) {
+		return fmt.Errorf("invalid package name: %q", m.PackageName)
+	}
+
+	for _, entry := range m.Apks {
+		if !isBackupEntry(entry) {
+			return fmt.Errorf("invalid apk entry: %q", entry)
+		}
+	}
+
+	if m.Data != nil && !isBackupEntry(m.Data.Entry) {
+		return fmt.Errorf("invalid data entry: %q", m.Data.Entry)
+	}
+
+	for _, dir := range m.External {
+		if !isBackupEntry(dir.Entry) {
+			return fmt.Errorf("invalid external entry: %q", dir.Entry)
+		}
+
+		allowed := false
+		for _, format := range externalAppDirs {
+			allowed = allowed || path.Clean(dir.Path) == fmt.Sprintf(format, m.PackageName)
+		}
+		if !allowed {
+			return fmt.Errorf("invalid external dir: %q", dir.Path)
+		}
+	}
+	return nil
+}
+
+func extractBackupFile(reader io.Reader, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+
+	file, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(file, reader); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// endregion Restore
@@ -0,0 +1,287 @@
+package packagemanager
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sephiroth74/go_adb_client/process"
+)
+
+var (
+	dexoptPackageRegexp = regexp.MustCompile(`^\[([^\]\s]+)\]$`)
+	dexoptPathRegexp    = regexp.MustCompile(`^path:\s*(\S+)`)
+	dexoptIsaRegexp     = regexp.MustCompile(`^([a-z0-9_]+):\s*(.*)$`)
+	dexoptValueRegexp   = regexp.MustCompile(`\[([^\]]*)\]`)
+	dexoptLocRegexp     = regexp.MustCompile(`^\[location is (.*)\]$`)
+)
+
+// region CompilerFilter
+
+// CompilerFilter is an ART compiler filter, as accepted by "cmd package compile -m"
+type CompilerFilter string
+
+const (
+	CompilerFilterAssumeVerified CompilerFilter = "assume-verified"
+	CompilerFilterExtract        CompilerFilter = "extract"
+	CompilerFilterVerify         CompilerFilter = "verify"
+	CompilerFilterQuicken        CompilerFilter = "quicken"
+	CompilerFilterSpaceProfile   CompilerFilter = "space-profile"
+	CompilerFilterSpace          CompilerFilter = "space"
+	CompilerFilterSpeedProfile   CompilerFilter = "speed-profile"
+	CompilerFilterSpeed          CompilerFilter = "speed"
+	CompilerFilterEverything     CompilerFilter = "everything"
+)
+
+// endregion CompilerFilter
+
+// region CompileOptions
+
+type CompileOptions struct {
+	// -m: the compiler filter
+	Filter CompilerFilter
+	// -r: the compilation reason, used instead of Filter (e.g. install, bg-dexopt, first-boot)
+	Reason string
+	// -f: force compilation even if the current filter is already satisfied
+	Force bool
+	// --check-prof: only compile when the profile has changed (speed-profile)
+	CheckProfile bool
+	// --secondary-dex: compile the secondary dex files of the app
+	SecondaryDex bool
+	// --split: compile only the given split
+	Split string
+}
+
+func (o *CompileOptions) args() []string {
+	var args []string
+	if o != nil {
+		if o.Filter != "" {
+			args = append(args, "-m", string(o.Filter))
+		}
+		if o.Reason != "" {
+			args = append(args, "-r", o.Reason)
+		}
+		if o.Force {
+			args = append(args, "-f")
+		}
+		if o.CheckProfile {
+			args = append(args, "--check-prof", "true")
+		}
+		if o.SecondaryDex {
+			args = append(args, "--secondary-dex")
+		}
+		if o.Split != "" {
+			args = append(args, "--split", o.Split)
+		}
+	}
+	return args
+}
+
+// endregion CompileOptions
+
+// region DexoptState
+
+// DexoptStatus is the compilation status of a code path for an instruction set
+type DexoptStatus struct {
+	Isa string
+	// CompilerFilter is the filter the code has been compiled with, e.g. speed-profile.
+	// It can also be a status such as run-from-apk or error
+	CompilerFilter string
+	// Status is the raw status reported, which is the compiler filter on recent android versions
+	Status     string
+	Reason     string
+	PrimaryAbi bool
+	// Location is the path of the compiled code (odex/vdex), if reported
+	Location string
+}
+
+func (s DexoptStatus) String() string {
+	return fmt.Sprintf("DexoptStatus{Isa:%s, CompilerFilter:%s, Reason:%s, PrimaryAbi:%t}", s.Isa, s.CompilerFilter, s.Reason, s.PrimaryAbi)
+}
+
+// DexoptPath is the compilation status of a code path (base or split apk) of a package
+type DexoptPath struct {
+	Path     string
+	Statuses []DexoptStatus
+}
+
+// PackageDexopt is the compilation state of a package
+type PackageDexopt struct {
+	PackageName string
+	Paths       []DexoptPath
+}
+
+// Status returns the status of the base apk for the given instruction set, nil if not found
+func (p PackageDexopt) Status(isa string) *DexoptStatus {
+	if len(p.Paths) == 0 {
+		return nil
+	}
+	for i, status := range p.Paths[0].Statuses {
+		if status.Isa == isa {
+			return &p.Paths[0].Statuses[i]
+		}
+	}
+	return nil
+}
+
+// PrimaryStatus returns the status of the base apk for the primary abi,
+// or the first status found if the primary abi is not reported
+func (p PackageDexopt) PrimaryStatus() *DexoptStatus {
+	if len(p.Paths) == 0 || len(p.Paths[0].Statuses) == 0 {
+		return nil
+	}
+	for i, status := range p.Paths[0].Statuses {
+		if status.PrimaryAbi {
+			return &p.Paths[0].Statuses[i]
+		}
+	}
+	return &p.Paths[0].Statuses[0]
+}
+
+// ParseDexoptState parses the output of "dumpsys package dexopt".
+// Both the format "arm64: [status=speed-profile] [reason=install]" and the legacy
+// "arm64: /data/app/.../base.odex[status=kOatUpToDate, compilation_filter=speed]" are supported
+func ParseDexoptState(data string) []PackageDexopt {
+	var result []PackageDexopt
+	var pkg *PackageDexopt
+	var path *DexoptPath
+	var status *DexoptStatus
+
+	for _, line := range strings.Split(data, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		if m := dexoptPackageRegexp.FindStringSubmatch(trimmed); m != nil {
+			result = append(result, PackageDexopt{PackageName: m[1]})
+			pkg = &result[len(result)-1]
+			path, status = nil, nil
+			continue
+		}
+
+		if pkg == nil {
+			continue
+		}
+
+		if m := dexoptPathRegexp.FindStringSubmatch(trimmed); m != nil {
+			pkg.Paths = append(pkg.Paths, DexoptPath{Path: m[1]})
+			path = &pkg.Paths[len(pkg.Paths)-1]
+			status = nil
+			continue
+		}
+
+		if path == nil {
+			continue
+		}
+
+		if m := dexoptLocRegexp.FindStringSubmatch(trimmed); m != nil {
+			if status != nil {
+				status.Location = m[1]
+			}
+			continue
+		}
+
+		if m := dexoptIsaRegexp.FindStringSubmatch(trimmed); m != nil && strings.Contains(m[2], "[") {
+			path.Statuses = append(path.Statuses, parseDexoptStatus(m[1], m[2]))
+			status = &path.Statuses[len(path.Statuses)-1]
+			continue
+		}
+
+		// "used by other apps", "known secondary dex files" and similar sections end the path
+		if strings.HasSuffix(trimmed, ":") || strings.Contains(trimmed, ": [") {
+			path, status = nil, nil
+		}
+	}
+	return result
+}
+
+func parseDexoptStatus(isa string, text string) DexoptStatus {
+	status := DexoptStatus{Isa: isa}
+
+	// legacy format, the location comes before the values
+	if index := strings.Index(text, "["); index > 0 {
+		status.Location = strings.TrimSpace(text[:index])
+	}
+
+	for _, m := range dexoptValueRegexp.FindAllStringSubmatch(text, -1) {
+		for _, pair := range strings.Split(m[1], ",") {
+			kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+			if len(kv) == 1 {
+				if kv[0] == "primary-abi" {
+					status.PrimaryAbi = true
+				}
+				continue
+			}
+			switch kv[0] {
+			case "status":
+				status.Status = kv[1]
+			case "compilation_filter", "filter":
+				status.CompilerFilter = kv[1]
+			case "reason", "compilation_reason":
+				status.Reason = kv[1]
+			}
+		}
+	}
+
+	if status.CompilerFilter == "" {
+		status.CompilerFilter = status.Status
+	}
+	return status
+}
+
+// endregion DexoptState
+
+// Compile compiles the given package with "cmd package compile"
+func (p PackageManager) Compile(packageName string, options *CompileOptions) error {
+	cmd := p.Shell.NewCommand().WithArgs("cmd", "package", "compile").AddArgs(options.args()...).AddArgs(packageName)
+	return p.execCommand(cmd)
+}
+
+// CompileAll compiles all the packages with "cmd package compile -a"
+func (p PackageManager) CompileAll(options *CompileOptions) error {
+	cmd := p.Shell.NewCommand().WithArgs("cmd", "package", "compile").AddArgs(options.args()...).AddArgs("-a")
+	return p.execCommand(cmd)
+}
+
+// ResetCompilation resets the compilation state of the package to its install state with "cmd package compile --reset"
+func (p PackageManager) ResetCompilation(packageName string) error {
+	return p.execCommand(p.Shell.NewCommand().WithArgs("cmd", "package", "compile", "--reset", packageName))
+}
+
+// ForceDexopt forces the dexopt of the package with "cmd package force-dex-opt"
+func (p PackageManager) ForceDexopt(packageName string) error {
+	return p.execCommand(p.Shell.NewCommand().WithArgs("cmd", "package", "force-dex-opt", packageName))
+}
+
+// RunBackgroundDexoptJob runs the background dexopt job with "cmd package bg-dexopt-job",
+// for the given packages only if any
+func (p PackageManager) RunBackgroundDexoptJob(packages ...string) error {
+	return p.execCommand(p.Shell.NewCommand().WithArgs("cmd", "package", "bg-dexopt-job").AddArgs(packages...))
+}
+
+// GetDexoptStates returns the compilation state of all the packages, parsed from "dumpsys package dexopt"
+func (p PackageManager) GetDexoptStates() ([]PackageDexopt, error) {
+	result, err := process.SimpleOutput(p.Shell.NewCommand().WithArgs("dumpsys", "package", "dexopt"), p.Shell.Conn.Verbose)
+	if err != nil {
+		return nil, err
+	}
+
+	if !result.IsOk() {
+		return nil, result.NewError()
+	}
+
+	return ParseDexoptState(result.Output()), nil
+}
+
+// GetDexoptState returns the compilation state of the given package
+func (p PackageManager) GetDexoptState(packageName string) (*PackageDexopt, error) {
+	states, err := p.GetDexoptStates()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range states {
+		if states[i].PackageName == packageName {
+			return &states[i], nil
+		}
+	}
+	return nil, fmt.Errorf("dexopt state of %s not found", packageName)
+}
@@ -0,0 +1,451 @@
+package packagemanager
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sephiroth74/go_adb_client/apk"
+	"github.com/sephiroth74/go_adb_client/process"
+)
+
+var (
+	installSessionRegexp = regexp.MustCompile(`created install session \[(\d+)\]`)
+	installFailureRegexp = regexp.MustCompile(`Failure \[([A-Z_]+)(?::\s*([^\]]*))?\]`)
+)
+
+// region InstallError
+
+type InstallErrorCode string
+
+const (
+	InstallFailedAlreadyExists                 InstallErrorCode = "INSTALL_FAILED_ALREADY_EXISTS"
+	InstallFailedInvalidApk                    InstallErrorCode = "INSTALL_FAILED_INVALID_APK"
+	InstallFailedInvalidUri                    InstallErrorCode = "INSTALL_FAILED_INVALID_URI"
+	InstallFailedInsufficientStorage           InstallErrorCode = "INSTALL_FAILED_INSUFFICIENT_STORAGE"
+	InstallFailedDuplicatePackage              InstallErrorCode = "INSTALL_FAILED_DUPLICATE_PACKAGE"
+	InstallFailedNoSharedUser                  InstallErrorCode = "INSTALL_FAILED_NO_SHARED_USER"
+	InstallFailedUpdateIncompatible            InstallErrorCode = "INSTALL_FAILED_UPDATE_INCOMPATIBLE"
+	InstallFailedSharedUserIncompatible        InstallErrorCode = "INSTALL_FAILED_SHARED_USER_INCOMPATIBLE"
+	InstallFailedMissingSharedLibrary          InstallErrorCode = "INSTALL_FAILED_MISSING_SHARED_LIBRARY"
+	InstallFailedReplaceCouldntDelete          InstallErrorCode = "INSTALL_FAILED_REPLACE_COULDNT_DELETE"
+	InstallFailedDexopt                        InstallErrorCode = "INSTALL_FAILED_DEXOPT"
+	InstallFailedOlderSdk                      InstallErrorCode = "INSTALL_FAILED_OLDER_SDK"
+	InstallFailedConflictingProvider           InstallErrorCode = "INSTALL_FAILED_CONFLICTING_PROVIDER"
+	InstallFailedNewerSdk                      InstallErrorCode = "INSTALL_FAILED_NEWER_SDK"
+	InstallFailedTestOnly                      InstallErrorCode = "INSTALL_FAILED_TEST_ONLY"
+	InstallFailedCpuAbiIncompatible            InstallErrorCode = "INSTALL_FAILED_CPU_ABI_INCOMPATIBLE"
+	InstallFailedMissingFeature                InstallErrorCode = "INSTALL_FAILED_MISSING_FEATURE"
+	InstallFailedContainerError                InstallErrorCode = "INSTALL_FAILED_CONTAINER_ERROR"
+	InstallFailedInvalidInstallLocation        InstallErrorCode = "INSTALL_FAILED_INVALID_INSTALL_LOCATION"
+	InstallFailedMediaUnavailable              InstallErrorCode = "INSTALL_FAILED_MEDIA_UNAVAILABLE"
+	InstallFailedVerificationTimeout           InstallErrorCode = "INSTALL_FAILED_VERIFICATION_TIMEOUT"
+	InstallFailedVerificationFailure           InstallErrorCode = "INSTALL_FAILED_VERIFICATION_FAILURE"
+	InstallFailedPackageChanged                InstallErrorCode = "INSTALL_FAILED_PACKAGE_CHANGED"
+	InstallFailedUidChanged                    InstallErrorCode = "INSTALL_FAILED_UID_CHANGED"
+	InstallFailedVersionDowngrade              InstallErrorCode = "INSTALL_FAILED_VERSION_DOWNGRADE"
+	InstallFailedPermissionModelDowngrade      InstallErrorCode = "INSTALL_FAILED_PERMISSION_MODEL_DOWNGRADE"
+	InstallFailedSandboxVersionDowngrade       InstallErrorCode = "INSTALL_FAILED_SANDBOX_VERSION_DOWNGRADE"
+	InstallFailedMissingSplit                  InstallErrorCode = "INSTALL_FAILED_MISSING_SPLIT"
+	InstallFailedDeprecatedSdkVersion          InstallErrorCode = "INSTALL_FAILED_DEPRECATED_SDK_VERSION"
+	InstallFailedInternalError                 InstallErrorCode = "INSTALL_FAILED_INTERNAL_ERROR"
+	InstallFailedUserRestricted                InstallErrorCode = "INSTALL_FAILED_USER_RESTRICTED"
+	InstallFailedDuplicatePermission           InstallErrorCode = "INSTALL_FAILED_DUPLICATE_PERMISSION"
+	InstallFailedNoMatchingAbis                InstallErrorCode = "INSTALL_FAILED_NO_MATCHING_ABIS"
+	InstallFailedAborted                       InstallErrorCode = "INSTALL_FAILED_ABORTED"
+	InstallFailedSessionInvalid                InstallErrorCode = "INSTALL_FAILED_SESSION_INVALID"
+	InstallFailedMultiPackageInconsistency     InstallErrorCode = "INSTALL_FAILED_MULTIPACKAGE_INCONSISTENCY"
+	InstallFailedWrongInstalledVersion         InstallErrorCode = "INSTALL_FAILED_WRONG_INSTALLED_VERSION"
+	InstallFailedProcessNotDefined             InstallErrorCode = "INSTALL_FAILED_PROCESS_NOT_DEFINED"
+	InstallFailedBadSignature                  InstallErrorCode = "INSTALL_FAILED_BAD_SIGNATURE"
+	InstallParseFailedNotApk                   InstallErrorCode = "INSTALL_PARSE_FAILED_NOT_APK"
+	InstallParseFailedBadManifest              InstallErrorCode = "INSTALL_PARSE_FAILED_BAD_MANIFEST"
+	InstallParseFailedUnexpectedException      InstallErrorCode = "INSTALL_PARSE_FAILED_UNEXPECTED_EXCEPTION"
+	InstallParseFailedNoCertificates           InstallErrorCode = "INSTALL_PARSE_FAILED_NO_CERTIFICATES"
+	InstallParseFailedInconsistentCertificates InstallErrorCode = "INSTALL_PARSE_FAILED_INCONSISTENT_CERTIFICATES"
+	InstallParseFailedCertificateEncoding      InstallErrorCode = "INSTALL_PARSE_FAILED_CERTIFICATE_ENCODING"
+	InstallParseFailedBadPackageName           InstallErrorCode = "INSTALL_PARSE_FAILED_BAD_PACKAGE_NAME"
+	InstallParseFailedBadSharedUserId          InstallErrorCode = "INSTALL_PARSE_FAILED_BAD_SHARED_USER_ID"
+	InstallParseFailedManifestMalformed        InstallErrorCode = "INSTALL_PARSE_FAILED_MANIFEST_MALFORMED"
+	InstallParseFailedManifestEmpty            InstallErrorCode = "INSTALL_PARSE_FAILED_MANIFEST_EMPTY"
+	InstallParseFailedSkipped                  InstallErrorCode = "INSTALL_PARSE_FAILED_SKIPPED"
+	InstallFailedUnknown                       InstallErrorCode = "INSTALL_FAILED_UNKNOWN"
+)
+
+// InstallError is returned when the package manager reports a "Failure [CODE: message]" result.
+// Use errors.As to retrieve the failure code
+type InstallError struct {
+	Code    InstallErrorCode
+	Message string
+}
+
+func (e *InstallError) Error() string {
+	if e.Message == "" {
+		return string(e.Code)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// Is reports whether target is an InstallError with the same code
+func (e *InstallError) Is(target error) bool {
+	t, ok := target.(*InstallError)
+	return ok && t.Code == e.Code
+}
+
+// NewInstallError returns an InstallError with the given code, useful with errors.Is
+func NewInstallError(code InstallErrorCode) *InstallError {
+	return &InstallError{Code: code}
+}
+
+// ParseInstallResult returns an *InstallError if the output of an install command reports a failure code,
+// a generic error if the command failed and nil otherwise
+func ParseInstallResult(result process.OutputResult) error {
+	output := strings.TrimSpace(result.Output() + "\n" + result.Error())
+
+	if m := installFailureRegexp.FindStringSubmatch(output); m != nil {
+		return &InstallError{Code: InstallErrorCode(m[1]), Message: strings.TrimSpace(m[2])}
+	}
+
+	if result.IsOk() && !strings.HasPrefix(result.Output(), "Error") {
+		return nil
+	}
+
+	if !result.IsOk() {
+		return result.NewError()
+	}
+	return errors.New(output)
+}
+
+// endregion InstallError
+
+// region InstallSession
+
+type InstallSessionOptions struct {
+	InstallOptions
+	// -t: allow test packages
+	AllowTestPackages bool
+	// -i: specify package name of installer owning the app
+	InstallerPackageName string
+	// -p: partial application install (new split on top of existing pkg)
+	InheritPackage string
+	// -S: size in bytes of the package, required for stdin
+	TotalSize int64
+	// --multi-package: create a parent session for an atomic install of multiple packages
+	MultiPackage bool
+	// --staged: install the package on the next reboot
+	Staged bool
+	// --enable-rollback: enable rollbacks for the upgrade
+	EnableRollback bool
+	// --apex: install an .apex file
+	Apex bool
+	// SkipChecks disables the local version downgrade and signature checks done before installing host apk files
+	SkipChecks bool
+}
+
+func (o *InstallSessionOptions) args() []string {
+	if o == nil {
+		return nil
+	}
+
+	args := o.InstallOptions.args()
+	if o.AllowTestPackages {
+		args = append(args, "-t")
+	}
+	if o.InstallerPackageName != "" {
+		args = append(args, "-i", o.InstallerPackageName)
+	}
+	if o.InheritPackage != "" {
+		args = append(args, "-p", o.InheritPackage)
+	}
+	if o.TotalSize > 0 {
+		args = append(args, "-S", strconv.FormatInt(o.TotalSize, 10))
+	}
+	if o.MultiPackage {
+		args = append(args, "--multi-package")
+	}
+	if o.Staged {
+		args = append(args, "--staged")
+	}
+	if o.EnableRollback {
+		args = append(args, "--enable-rollback")
+	}
+	if o.Apex {
+		args = append(args, "--apex")
+	}
+	return args
+}
+
+// InstallSession is a package installer session created with "pm install-create"
+type InstallSession struct {
+	ID int
+	pm PackageManager
+}
+
+// CreateInstallSession creates a new install session with "pm install-create"
+func (p PackageManager) CreateInstallSession(options *InstallSessionOptions) (*InstallSession, error) {
+	cmd := p.Shell.NewCommand().WithArgs("cmd package install-create").AddArgs(options.args()...)
+	result, err := process.SimpleOutput(cmd, p.Shell.Conn.Verbose)
+	if err != nil {
+		return nil, err
+	}
+
+	if !result.IsOk() {
+		return nil, ParseInstallResult(result)
+	}
+
+	m := installSessionRegexp.FindStringSubmatch(result.Output())
+	if m == nil {
+		return nil, fmt.Errorf("unable to create install session: %s", result.Output())
+	}
+
+	id, err := strconv.Atoi(m[1])
+	if err != nil {
+		return nil, err
+	}
+	return &InstallSession{ID: id, pm: p}, nil
+}
+
+// Write streams the given host apk file into the session with "pm install-write"
+func (s InstallSession) Write(name string, src string) error {
+	file, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	return s.WriteReader(name, stat.Size(), file)
+}
+
+// WriteReader streams size bytes from reader into the session, with the split name given
+func (s InstallSession) WriteReader(name string, size int64, reader io.Reader) error {
+	cmd := s.pm.Shell.NewCommand().
+		WithArgs("cmd package install-write", "-S", strconv.FormatInt(size, 10), strconv.Itoa(s.ID), name, "-").
+		WithStdIn(reader)
+	result, err := process.SimpleOutput(cmd, s.pm.Shell.Conn.Verbose)
+	if err != nil {
+		return err
+	}
+	return ParseInstallResult(result)
+}
+
+// AddSessions adds the child sessions to this multi-package session with "pm install-add-session"
+func (s InstallSession) AddSessions(children ...*InstallSession) error {
+	args := []string{strconv.Itoa(s.ID)}
+	for _, child := range children {
+		args = append(args, strconv.Itoa(child.ID))
+	}
+	return s.exec("install-add-session", args...)
+}
+
+// Commit commits the session with "pm install-commit"
+func (s InstallSession) Commit() error {
+	return s.exec("install-commit", strconv.Itoa(s.ID))
+}
+
+// Abandon abandons the session with "pm install-abandon"
+func (s InstallSession) Abandon() error {
+	return s.exec("install-abandon", strconv.Itoa(s.ID))
+}
+
+func (s InstallSession) exec(command string, args ...string) error {
+	cmd := s.pm.Shell.NewCommand().WithArgs("cmd package " + command).AddArgs(args...)
+	result, err := process.SimpleOutput(cmd, s.pm.Shell.Conn.Verbose)
+	if err != nil {
+		return err
+	}
+	return ParseInstallResult(result)
+}
+
+// InstallMultiple installs the base apk and the splits of a single package in one session.
+// srcs are the host apk files
+func (p PackageManager) InstallMultiple(srcs []string, options *InstallSessionOptions) error {
+	if len(srcs) == 0 {
+		return errors.New("no apk to install")
+	}
+
+	sessionOptions := InstallSessionOptions{}
+	if options != nil {
+		sessionOptions = *options
+	}
+	sessionOptions.MultiPackage = false
+
+	if !sessionOptions.SkipChecks {
+		if err := p.checkLocalApks(srcs, &sessionOptions.InstallOptions); err != nil {
+			return err
+		}
+	}
+
+	totalSize, err := totalFileSize(srcs)
+	if err != nil {
+		return err
+	}
+	sessionOptions.TotalSize = totalSize
+
+	session, err := p.CreateInstallSession(&sessionOptions)
+	if err != nil {
+		return err
+	}
+
+	for index, src := range srcs {
+		if err := session.Write(fmt.Sprintf("%d_%s", index, filepath.Base(src)), src); err != nil {
+			_ = session.Abandon()
+			return err
+		}
+	}
+
+	if err := session.Commit(); err != nil {
+		_ = session.Abandon()
+		return err
+	}
+	return nil
+}
+
+// InstallMultiPackage atomically installs multiple packages.
+// Each element of packages is the list of host apk files (base and splits) of a single package
+func (p PackageManager) InstallMultiPackage(packages [][]string, options *InstallSessionOptions) error {
+	if len(packages) == 0 {
+		return errors.New("no package to install")
+	}
+
+	parentOptions := InstallSessionOptions{}
+	if options != nil {
+		parentOptions = *options
+	}
+	parentOptions.MultiPackage = true
+	parentOptions.TotalSize = 0
+
+	if !parentOptions.SkipChecks {
+		for _, srcs := range packages {
+			if err := p.checkLocalApks(srcs, &parentOptions.InstallOptions); err != nil {
+				return err
+			}
+		}
+	}
+
+	parent, err := p.CreateInstallSession(&parentOptions)
+	if err != nil {
+		return err
+	}
+
+	var children []*InstallSession
+	abandon := func() {
+		for _, child := range children {
+			_ = child.Abandon()
+		}
+		_ = parent.Abandon()
+	}
+
+	for _, srcs := range packages {
+		childOptions := parentOptions
+		childOptions.MultiPackage = false
+		childOptions.TotalSize, err = totalFileSize(srcs)
+		if err != nil {
+			abandon()
+			return err
+		}
+
+		child, err := p.CreateInstallSession(&childOptions)
+		if err != nil {
+			abandon()
+			return err
+		}
+		children = append(children, child)
+
+		for index, src := range srcs {
+			if err := child.Write(fmt.Sprintf("%d_%s", index, filepath.Base(src)), src); err != nil {
+				abandon()
+				return err
+			}
+		}
+	}
+
+	if err := parent.AddSessions(children...); err != nil {
+		abandon()
+		return err
+	}
+
+	if err := parent.Commit(); err != nil {
+		abandon()
+		return err
+	}
+	return nil
+}
+
+// CheckInstall compares a local apk with the installed package, if any.
+// It returns an *InstallError with code InstallFailedVersionDowngrade if the local version code is lower than the
+// installed one (and downgrade is not allowed) or InstallFailedUpdateIncompatible if the signatures don't match.
+// The errors reading the package info are returned, except ErrPackageNotFound
+func (p PackageManager) CheckInstall(local *apk.Apk, options *InstallOptions) error {
+	info, err := p.GetPackageInfo(local.Manifest.Package)
+	if errors.Is(err, ErrPackageNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return CompareInstall(local, info, options)
+}
+
+// CompareInstall compares a local apk with the installed package info and returns the same errors as CheckInstall
+func CompareInstall(local *apk.Apk, info *PackageInfo, options *InstallOptions) error {
+	allowDowngrade := options != nil && options.AllowVersionCodeDowngrade
+	if !allowDowngrade && local.Manifest.VersionCode < info.VersionCode {
+		return &InstallError{
+			Code:    InstallFailedVersionDowngrade,
+			Message: fmt.Sprintf("%s: installed version code %d is newer than %d", local.Manifest.Package, info.VersionCode, local.Manifest.VersionCode),
+		}
+	}
+
+	hashes := local.Signing.SignatureHashes()
+	if len(hashes) > 0 && len(info.Signatures) > 0 {
+		for _, hash := range hashes {
+			for _, signature := range info.Signatures {
+				if hash == signature {
+					return nil
+				}
+			}
+		}
+		return &InstallError{
+			Code:    InstallFailedUpdateIncompatible,
+			Message: fmt.Sprintf("%s: signatures %s do not match the installed package signatures %s", local.Manifest.Package, hashes, info.Signatures),
+		}
+	}
+	return nil
+}
+
+// checkLocalApks runs CheckInstall against the base apk found in srcs.
+// Files which cannot be parsed are left to the device to validate
+func (p PackageManager) checkLocalApks(srcs []string, options *InstallOptions) error {
+	for _, src := range srcs {
+		local, err := apk.Open(src)
+		if err != nil || local.IsSplit() {
+			continue
+		}
+		return p.CheckInstall(local, options)
+	}
+	return nil
+}
+
+func totalFileSize(srcs []string) (int64, error) {
+	var total int64
+	for _, src := range srcs {
+		stat, err := os.Stat(src)
+		if err != nil {
+			return 0, err
+		}
+		total += stat.Size()
+	}
+	return total, nil
+}
+
+// endregion InstallSession
@@ -0,0 +1,573 @@
+package packagemanager
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/repr"
+	"github.com/sephiroth74/go_adb_client/types"
+)
+
+var (
+	packageInfoTimeLayout      = "2006-01-02 15:04:05"
+	packageHeaderRegexp        = regexp.MustCompile(`^Package\s+\[([^\]]+)\]\s+\(([^)]+)\):$`)
+	packageUserRegexp          = regexp.MustCompile(`^User\s+(\d+):\s*(.*)$`)
+	packageKeyValueRegexp      = regexp.MustCompile(`(\w+)=(\S+)`)
+	packageSignaturesRegexp    = regexp.MustCompile(`signatures:\[([^\]]*)\]`)
+	packagePermissionRegexp    = regexp.MustCompile(`^([^:\s]+):\s+granted=(true|false)(?:,\s+flags=\[\s*([^\]]*)\])?`)
+	resolverComponentRegexp    = regexp.MustCompile(`^[0-9a-f]+\s+(\S+/\S+)\s+filter\s+([0-9a-f]+)`)
+	resolverFilterValueRegexp  = regexp.MustCompile(`^(\w+):\s+"([^"]*)"`)
+	resolverFilterPriority     = regexp.MustCompile(`^mPriority=(-?\d+)`)
+	providerRegisteredRegexp   = regexp.MustCompile(`^(\S+/\S+):$`)
+	providerAuthorityRegexp    = regexp.MustCompile(`^\[([^\]]+)\]:$`)
+	providerAuthorityCompRegex = regexp.MustCompile(`^Provider\{[0-9a-f]+\s+(\S+/\S+)\}`)
+)
+
+// region FlagSet
+
+// FlagSet is a set of package flags, as reported by the flags=[ ... ] and privateFlags=[ ... ] lines
+type FlagSet map[string]struct{}
+
+func NewFlagSet(flags ...string) FlagSet {
+	set := make(FlagSet)
+	for _, flag := range flags {
+		if flag != "" {
+			set[flag] = struct{}{}
+		}
+	}
+	return set
+}
+
+func (f FlagSet) Has(flag string) bool {
+	_, ok := f[flag]
+	return ok
+}
+
+// List returns the sorted list of flags
+func (f FlagSet) List() []string {
+	var list []string
+	for k := range f {
+		list = append(list, k)
+	}
+	sort.Strings(list)
+	return list
+}
+
+func (f FlagSet) String() string {
+	return "[" + strings.Join(f.List(), " ") + "]"
+}
+
+// endregion FlagSet
+
+// region EnabledState
+
+// EnabledState is the value of the enabled= field for a package user
+type EnabledState int
+
+const (
+	EnabledStateDefault EnabledState = iota
+	EnabledStateEnabled
+	EnabledStateDisabled
+	EnabledStateDisabledUser
+	EnabledStateDisabledUntilUsed
+)
+
+func (d EnabledState) String() string {
+	if d < 0 || int(d) >= len(enabledStateNames) {
+		return strconv.Itoa(int(d))
+	}
+	return enabledStateNames[d]
+}
+
+var enabledStateNames = [...]string{
+	"DEFAULT",
+	"ENABLED",
+	"DISABLED",
+	"DISABLED_USER",
+	"DISABLED_UNTIL_USED",
+}
+
+// endregion EnabledState
+
+// region PackageInfo
+
+type IntentFilter struct {
+	Actions     []string
+	Categories  []string
+	Schemes     []string
+	Authorities []string
+	Paths       []string
+	Types       []string
+	Priority    int
+}
+
+// Component is an activity, service, receiver or provider declared by a package.
+// Only components registered in the package manager resolver tables are reported
+type Component struct {
+	Name          string
+	IntentFilters []IntentFilter
+	// Authorities is only used by providers
+	Authorities []string
+}
+
+// PackageUserState is the per-user state of a package
+type PackageUserState struct {
+	UserID             types.UserId
+	Installed          bool
+	Hidden             bool
+	Suspended          bool
+	Stopped            bool
+	NotLaunched        bool
+	Instant            bool
+	Enabled            EnabledState
+	FirstInstallTime   time.Time
+	Gids               []int
+	RuntimePermissions []types.PackagePermission
+}
+
+func (u PackageUserState) IsEnabled() bool {
+	return u.Enabled == EnabledStateDefault || u.Enabled == EnabledStateEnabled
+}
+
+// PackageInfo contains the information of a package, parsed from the output of "dumpsys package <pkg>"
+type PackageInfo struct {
+	PackageName            string
+	UserID                 int
+	CodePath               string
+	ResourcePath           string
+	DataDir                string
+	LegacyNativeLibraryDir string
+	PrimaryCpuAbi          string
+	SecondaryCpuAbi        string
+	VersionName            string
+	VersionCode            int64
+	MinSdk                 int
+	TargetSdk              int
+	TimeStamp              time.Time
+	FirstInstallTime       time.Time
+	LastUpdateTime         time.Time
+	InstallerPackageName   string
+	Flags                  FlagSet
+	PrivateFlags           FlagSet
+	ApkSigningVersion      int
+	Signatures             []string
+	Splits                 []string
+	RequestedPermissions   []types.RequestedPermission
+	InstallPermissions     []types.PackagePermission
+	Users                  []PackageUserState
+	Activities             []Component
+	Services               []Component
+	Receivers              []Component
+	Providers              []Component
+	// Properties contains every key=value pair found in the package section
+	Properties map[string]string
+}
+
+func (p PackageInfo) String() string {
+	return repr.String(p)
+}
+
+// IsSystem returns true if the package has the SYSTEM flag
+func (p PackageInfo) IsSystem() bool {
+	return p.Flags.Has("SYSTEM")
+}
+
+// IsDebuggable returns true if the package has the DEBUGGABLE flag
+func (p PackageInfo) IsDebuggable() bool {
+	return p.Flags.Has("DEBUGGABLE")
+}
+
+// User returns the state of the package for the given user id, if found
+func (p PackageInfo) User(userID types.UserId) *PackageUserState {
+	for i := range p.Users {
+		if p.Users[i].UserID == userID {
+			return &p.Users[i]
+		}
+	}
+	return nil
+}
+
+// ErrPackageNotFound is returned by ParsePackageInfo (and GetPackageInfo) when the package is not installed
+var ErrPackageNotFound = errors.New("package not found")
+
+// ParsePackageInfo parses the output of "dumpsys package <pkg>" (or "pm dump <pkg>")
+func ParsePackageInfo(data string) (*PackageInfo, error) {
+	sections := splitDumpsysSections(strings.Split(data, "\n"))
+
+	// the packages section is not printed for the packages not installed
+	lines, ok := sections["Packages:"]
+	if !ok {
+		return nil, ErrPackageNotFound
+	}
+
+	info, err := parsePackageSection(lines)
+	if err != nil {
+		return nil, err
+	}
+
+	info.Activities = parseResolverTable(sections["Activity Resolver Table:"], info.PackageName)
+	info.Receivers = parseResolverTable(sections["Receiver Resolver Table:"], info.PackageName)
+	info.Services = parseResolverTable(sections["Service Resolver Table:"], info.PackageName)
+	info.Providers = parseProviders(sections["Registered ContentProviders:"], sections["ContentProvider Authorities:"], info.PackageName)
+
+	return info, nil
+}
+
+// endregion PackageInfo
+
+// splitDumpsysSections splits the dumpsys output into its top level sections.
+// A section starts with a non indented line ending with ':'
+func splitDumpsysSections(lines []string) map[string][]string {
+	sections := make(map[string][]string)
+	var current string
+
+	for _, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		if len(line) > 0 && line[0] != ' ' && line[0] != '\t' && strings.HasSuffix(line, ":") {
+			current = line
+			if _, ok := sections[current]; !ok {
+				sections[current] = []string{}
+			}
+			continue
+		}
+		if current != "" && strings.TrimSpace(line) != "" {
+			sections[current] = append(sections[current], line)
+		}
+	}
+	return sections
+}
+
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
+
+func parsePackageSection(lines []string) (*PackageInfo, error) {
+	info := &PackageInfo{
+		Flags:        NewFlagSet(),
+		PrivateFlags: NewFlagSet(),
+		Properties:   make(map[string]string),
+	}
+
+	headerIndent := -1
+	var list string
+	var listIndent int
+	var user *PackageUserState
+
+	for _, line := range lines {
+		indent := indentOf(line)
+		trimmed := strings.TrimSpace(line)
+
+		if headerIndent < 0 {
+			if m := packageHeaderRegexp.FindStringSubmatch(trimmed); m != nil {
+				info.PackageName = m[1]
+				headerIndent = indent
+			}
+			continue
+		}
+
+		// a second package (or the end of the first one) has been reached
+		if indent <= headerIndent {
+			break
+		}
+
+		// nested list items
+		if list != "" && indent > listIndent {
+			switch list {
+			case "requested permissions":
+				name := strings.TrimSpace(strings.SplitN(trimmed, ":", 2)[0])
+				info.RequestedPermissions = append(info.RequestedPermissions, types.RequestedPermission{Permission: types.Permission{Name: name}})
+			case "install permissions":
+				if permission, ok := parsePackagePermission(trimmed); ok {
+					info.InstallPermissions = append(info.InstallPermissions, permission)
+				}
+			case "runtime permissions":
+				if permission, ok := parsePackagePermission(trimmed); ok && user != nil {
+					user.RuntimePermissions = append(user.RuntimePermissions, permission)
+				}
+			}
+			continue
+		}
+		list = ""
+
+		if m := packageUserRegexp.FindStringSubmatch(trimmed); m != nil {
+			info.Users = append(info.Users, parsePackageUserState(types.UserId(m[1]), m[2]))
+			user = &info.Users[len(info.Users)-1]
+			continue
+		}
+
+		// user nested properties
+		if user != nil && indent > headerIndent+2 {
+			switch {
+			case strings.HasSuffix(trimmed, "permissions:"):
+				list = strings.TrimSuffix(trimmed, ":")
+				listIndent = indent
+			case strings.HasPrefix(trimmed, "gids=["):
+				user.Gids = parseIntList(strings.TrimSuffix(strings.TrimPrefix(trimmed, "gids=["), "]"))
+			case strings.HasPrefix(trimmed, "firstInstallTime="):
+				user.FirstInstallTime = parsePackageTime(strings.TrimPrefix(trimmed, "firstInstallTime="))
+			case strings.HasSuffix(trimmed, ":"):
+				list = strings.TrimSuffix(trimmed, ":")
+				listIndent = indent
+			}
+			continue
+		}
+		user = nil
+
+		if strings.HasSuffix(trimmed, ":") {
+			list = strings.TrimSuffix(trimmed, ":")
+			listIndent = indent
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "versionCode=") {
+			for _, kv := range packageKeyValueRegexp.FindAllStringSubmatch(trimmed, -1) {
+				info.Properties[kv[1]] = kv[2]
+			}
+			continue
+		}
+
+		pair := strings.SplitN(trimmed, "=", 2)
+		if len(pair) == 2 {
+			info.Properties[pair[0]] = pair[1]
+		}
+	}
+
+	if headerIndent < 0 {
+		return nil, ErrPackageNotFound
+	}
+
+	info.applyProperties()
+
+	// older android versions report the first install time only at package level,
+	// newer ones only at user level
+	for i := range info.Users {
+		if info.Users[i].FirstInstallTime.IsZero() {
+			info.Users[i].FirstInstallTime = info.FirstInstallTime
+		} else if info.FirstInstallTime.IsZero() || info.Users[i].FirstInstallTime.Before(info.FirstInstallTime) {
+			info.FirstInstallTime = info.Users[i].FirstInstallTime
+		}
+	}
+
+	return info, nil
+}
+
+func (p *PackageInfo) applyProperties() {
+	props := p.Properties
+	p.UserID, _ = strconv.Atoi(props["userId"])
+	p.CodePath = props["codePath"]
+	p.ResourcePath = props["resourcePath"]
+	p.DataDir = props["dataDir"]
+	p.LegacyNativeLibraryDir = props["legacyNativeLibraryDir"]
+	p.PrimaryCpuAbi = nullString(props["primaryCpuAbi"])
+	p.SecondaryCpuAbi = nullString(props["secondaryCpuAbi"])
+	p.VersionName = props["versionName"]
+	p.VersionCode, _ = strconv.ParseInt(props["versionCode"], 10, 64)
+	p.MinSdk, _ = strconv.Atoi(props["minSdk"])
+	p.TargetSdk, _ = strconv.Atoi(props["targetSdk"])
+	p.TimeStamp = parsePackageTime(props["timeStamp"])
+	p.FirstInstallTime = parsePackageTime(props["firstInstallTime"])
+	p.LastUpdateTime = parsePackageTime(props["lastUpdateTime"])
+	p.InstallerPackageName = nullString(props["installerPackageName"])
+	p.ApkSigningVersion, _ = strconv.Atoi(props["apkSigningVersion"])
+
+	if v, ok := props["flags"]; ok {
+		p.Flags = NewFlagSet(parseFlagList(v)...)
+	} else if v, ok := props["pkgFlags"]; ok {
+		p.Flags = NewFlagSet(parseFlagList(v)...)
+	}
+	p.PrivateFlags = NewFlagSet(parseFlagList(props["privateFlags"])...)
+
+	if v, ok := props["splits"]; ok {
+		p.Splits = parseStringList(v)
+	}
+
+	if m := packageSignaturesRegexp.FindStringSubmatch(props["signatures"]); m != nil {
+		p.Signatures = parseStringList(m[1])
+	}
+}
+
+func parsePackageUserState(id types.UserId, text string) PackageUserState {
+	state := PackageUserState{UserID: id, Installed: true}
+	for _, kv := range packageKeyValueRegexp.FindAllStringSubmatch(text, -1) {
+		value, _ := strconv.ParseBool(kv[2])
+		switch kv[1] {
+		case "installed":
+			state.Installed = value
+		case "hidden":
+			state.Hidden = value
+		case "suspended":
+			state.Suspended = value
+		case "stopped":
+			state.Stopped = value
+		case "notLaunched":
+			state.NotLaunched = value
+		case "instant":
+			state.Instant = value
+		case "enabled":
+			enabled, _ := strconv.Atoi(kv[2])
+			state.Enabled = EnabledState(enabled)
+		}
+	}
+	return state
+}
+
+func parsePackagePermission(line string) (types.PackagePermission, bool) {
+	m := packagePermissionRegexp.FindStringSubmatch(line)
+	if m == nil {
+		return types.PackagePermission{}, false
+	}
+	granted, _ := strconv.ParseBool(m[2])
+	var flags []string
+	if strings.TrimSpace(m[3]) != "" {
+		flags = strings.Split(strings.TrimSpace(m[3]), "|")
+	}
+	return types.PackagePermission{
+		Permission: types.Permission{Name: m[1]},
+		Granted:    granted,
+		Flags:      flags,
+	}, true
+}
+
+// parseResolverTable returns the components of the given package found in a resolver table,
+// along with their intent filters
+func parseResolverTable(lines []string, packageName string) []Component {
+	var components []Component
+	index := make(map[string]int)
+	seen := make(map[string]bool)
+
+	var filter *IntentFilter
+	filterIndent := 0
+
+	for _, line := range lines {
+		indent := indentOf(line)
+		trimmed := strings.TrimSpace(line)
+
+		if filter != nil && indent > filterIndent {
+			if m := resolverFilterValueRegexp.FindStringSubmatch(trimmed); m != nil {
+				switch m[1] {
+				case "Action":
+					filter.Actions = append(filter.Actions, m[2])
+				case "Category":
+					filter.Categories = append(filter.Categories, m[2])
+				case "Scheme":
+					filter.Schemes = append(filter.Schemes, m[2])
+				case "Authority":
+					filter.Authorities = append(filter.Authorities, m[2])
+				case "Path":
+					filter.Paths = append(filter.Paths, m[2])
+				case "Type", "StaticType":
+					filter.Types = append(filter.Types, m[2])
+				}
+			} else if m := resolverFilterPriority.FindStringSubmatch(trimmed); m != nil {
+				filter.Priority, _ = strconv.Atoi(m[1])
+			}
+			continue
+		}
+		filter = nil
+
+		m := resolverComponentRegexp.FindStringSubmatch(trimmed)
+		if m == nil || !strings.HasPrefix(m[1], packageName+"/") {
+			continue
+		}
+
+		name := m[1]
+		i, ok := index[name]
+		if !ok {
+			components = append(components, Component{Name: name})
+			i = len(components) - 1
+			index[name] = i
+		}
+
+		// the same filter is listed once for every action/type/scheme
+		if seen[m[2]] {
+			continue
+		}
+		seen[m[2]] = true
+
+		components[i].IntentFilters = append(components[i].IntentFilters, IntentFilter{})
+		filter = &components[i].IntentFilters[len(components[i].IntentFilters)-1]
+		filterIndent = indent
+	}
+	return components
+}
+
+func parseProviders(registered []string, authorities []string, packageName string) []Component {
+	var components []Component
+	index := make(map[string]int)
+
+	add := func(name string) int {
+		if i, ok := index[name]; ok {
+			return i
+		}
+		components = append(components, Component{Name: name})
+		index[name] = len(components) - 1
+		return len(components) - 1
+	}
+
+	for _, line := range registered {
+		if m := providerRegisteredRegexp.FindStringSubmatch(strings.TrimSpace(line)); m != nil && strings.HasPrefix(m[1], packageName+"/") {
+			add(m[1])
+		}
+	}
+
+	var authority string
+	for _, line := range authorities {
+		trimmed := strings.TrimSpace(line)
+		if m := providerAuthorityRegexp.FindStringSubmatch(trimmed); m != nil {
+			authority = m[1]
+		} else if m := providerAuthorityCompRegex.FindStringSubmatch(trimmed); m != nil && authority != "" {
+			if strings.HasPrefix(m[1], packageName+"/") {
+				i := add(m[1])
+				components[i].Authorities = append(components[i].Authorities, authority)
+			}
+			authority = ""
+		}
+	}
+	return components
+}
+
+func parsePackageTime(value string) time.Time {
+	t, err := time.ParseInLocation(packageInfoTimeLayout, strings.TrimSpace(value), time.Local)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// parseFlagList parses a list in the format "[ FLAG1 FLAG2 ]"
+func parseFlagList(value string) []string {
+	value = strings.Trim(strings.TrimSpace(value), "[]")
+	return strings.Fields(value)
+}
+
+// parseStringList parses a list in the format "[a, b, c]"
+func parseStringList(value string) []string {
+	var result []string
+	for _, item := range strings.Split(strings.Trim(strings.TrimSpace(value), "[]"), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+func parseIntList(value string) []int {
+	var result []int
+	for _, item := range parseStringList(value) {
+		if i, err := strconv.Atoi(item); err == nil {
+			result = append(result, i)
+		}
+	}
+	return result
+}
+
+func nullString(value string) string {
+	if value == "null" {
+		return ""
+	}
+	return value
+}
@@ -7,6 +7,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/sephiroth74/go_adb_client/apk"
 	"github.com/sephiroth74/go_adb_client/process"
 
 	"github.com/alecthomas/repr"
@@ -18,29 +19,39 @@ type PackageManager struct {
 	Shell *shell.Shell
 }
 
-func (p PackageManager) Path(packageName string, user string) (string, error) {
-	cmd := p.Shell.NewCommand().WithArgs("pm", "path")
-	if user != "" {
-		cmd.AddArgs("--user", user)
+func (p PackageManager) Path(packageName string, user types.UserId) (string, error) {
+	paths, err := p.Paths(packageName, user)
+	if err != nil {
+		return "", err
 	}
+	return paths[0], nil
+}
+
+// Paths returns all the apk paths of the given package, the base apk first followed by the splits
+func (p PackageManager) Paths(packageName string, user types.UserId) ([]string, error) {
+	cmd := p.Shell.NewCommand().WithArgs("pm", "path")
+	cmd.AddArgs(user.Args()...)
 
 	cmd.AddArgs(packageName)
 
 	result, err := process.SimpleOutput(cmd, p.Shell.Conn.Verbose)
 
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	if result.IsOk() {
-		f := regexp.MustCompile(`package:(.*)`)
-		m := f.FindStringSubmatch(result.Output())
-		if m != nil && len(m) == 2 {
-			return m[1], nil
+		f := regexp.MustCompile(`(?m)^package:(.*)$`)
+		var paths []string
+		for _, m := range f.FindAllStringSubmatch(result.Output(), -1) {
+			paths = append(paths, strings.TrimSpace(m[1]))
+		}
+		if len(paths) == 0 {
+			return nil, errors.New("path not found")
 		}
-		return "", errors.New("path not found")
+		return paths, nil
 	} else {
-		return "", result.NewError()
+		return nil, result.NewError()
 	}
 }
 
@@ -64,7 +75,7 @@ func (p PackageManager) Dump(name string) (process.OutputResult, error) {
 	return process.SimpleOutput(cmd, p.Shell.Conn.Verbose)
 }
 
-func (p PackageManager) IsInstalled(packagename string, user string) (bool, error) {
+func (p PackageManager) IsInstalled(packagename string, user types.UserId) (bool, error) {
 	pkg, err := p.Path(packagename, user)
 	if err != nil {
 		return false, err
@@ -92,20 +103,43 @@ func (p PackageManager) ListPackagesWithFilter(options PackageOptions, filter st
 	//	--apex-only: only show APEX packages
 	//	--uid UID: filter to only show packages with the given UID
 	//	--user USER_ID: only list packages belonging to the given user
-	args := []string{"list", "packages", "-f", "-U", "--show-versioncode"}
+	packages, err := p.listPackages(options, filter)
+	if err != nil {
+		return nil, err
+	}
 
-	if options.ShowOnly3rdParty {
-		args = append(args, "-3")
+	// the enabled state is not part of the output, the disabled packages are listed separately
+	if options.ShowOnlyDisabled || options.ShowOnlyEnabed {
+		for i := range packages {
+			packages[i].Enabled = options.ShowOnlyEnabed
+		}
+		return packages, nil
 	}
-	if options.ShowOnlyDisabled {
-		args = append(args, "-d")
+
+	if !options.WithEnabledState {
+		return packages, nil
 	}
-	if options.ShowOnlyEnabed {
-		args = append(args, "-e")
+
+	disabledOptions := options
+	disabledOptions.ShowOnlyDisabled = true
+	disabled, err := p.listPackages(disabledOptions, filter)
+	if err != nil {
+		return nil, err
 	}
-	if options.ShowOnlySystem {
-		args = append(args, "-s")
+
+	disabledNames := make(map[string]bool)
+	for _, pkg := range disabled {
+		disabledNames[pkg.Name] = true
 	}
+	for i := range packages {
+		packages[i].Enabled = !disabledNames[packages[i].Name]
+	}
+	return packages, nil
+}
+
+func (p PackageManager) listPackages(options PackageOptions, filter string) ([]Package, error) {
+	args := []string{"list", "packages", "-f", "-U", "-i", "--show-versioncode"}
+	args = append(args, options.args()...)
 
 	if filter != "" {
 		args = append(args, filter)
@@ -113,75 +147,93 @@ func (p PackageManager) ListPackagesWithFilter(options PackageOptions, filter st
 
 	cmd := p.Shell.NewCommand().WithArgs("pm").AddArgs(args...)
 	result, err := process.SimpleOutput(cmd, p.Shell.Conn.Verbose)
-	// result, err := p.Shell.ExecuteWithTimeout("pm", 0, args...)
 	if err != nil {
 		return nil, err
 	}
 
-	if result.IsOk() {
-		f := regexp.MustCompile(`package:(.*\.apk)=([^\s]+)\s*(versionCode|uid):([^\s]+)\s+(versionCode|uid):([^\s]+)$`)
-		var packages = []Package{}
-
-		for _, line := range result.OutputLines(false) {
-			m := f.FindStringSubmatch(line)
-			if len(m) == 7 {
-				var versionCode string
-				var uid string
-
-				if m[3] == "uid" {
-					versionCode = m[6]
-					uid = m[4]
-				} else {
-					uid = m[6]
-					versionCode = m[4]
-				}
-
-				packages = append(packages, Package{
-					Filename:    m[1],
-					Name:        m[2],
-					VersionCode: versionCode,
-					UID:         uid,
-				})
-			}
-		}
-
-		return packages, nil
-	} else {
+	if !result.IsOk() {
 		return nil, result.NewError()
 	}
+
+	return ParsePackageList(result.Output()), nil
 }
 
-func (p PackageManager) Install(src string, options *InstallOptions) (process.OutputResult, error) {
-	var args []string
-	if options != nil {
-		if options.RestrictPermissions {
-			args = append(args, "--restrict-permissions")
+// ParsePackageList parses the output of "pm list packages".
+// Each line is in the format package:[PATH=]NAME [versionCode:CODE] [installer=INSTALLER] [uid:UID[,UID...]],
+// APEX packages can be reported with the "apex:" prefix
+func ParsePackageList(data string) []Package {
+	packages := []Package{}
+
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+
+		var pkg Package
+		switch {
+		case strings.HasPrefix(line, "package:"):
+			line = strings.TrimPrefix(line, "package:")
+		case strings.HasPrefix(line, "apex:"):
+			line = strings.TrimPrefix(line, "apex:")
+			pkg.Apex = true
+		default:
+			continue
 		}
-		if options.User != "" {
-			args = append(args, "--user", options.User)
-		}
-		if options.Pkg != "" {
-			args = append(args, "--pkg", options.Pkg)
-		}
-		if options.InstallLocation > 0 {
-			args = append(args, "--install-location", fmt.Sprintf("%d", options.InstallLocation))
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
 		}
-		if options.GrantPermissions {
-			args = append(args, "-g")
+
+		// the path can contain '=' (e.g. /data/app/~~abc==/com.example-xyz==/base.apk)
+		if index := strings.LastIndex(fields[0], "="); index >= 0 {
+			pkg.Filename = fields[0][:index]
+			pkg.Name = fields[0][index+1:]
+		} else {
+			pkg.Name = fields[0]
 		}
-		if options.Force {
-			args = append(args, "-f")
+
+		for _, field := range fields[1:] {
+			switch {
+			case strings.HasPrefix(field, "versionCode:"):
+				pkg.VersionCode, _ = strconv.ParseInt(strings.TrimPrefix(field, "versionCode:"), 10, 64)
+			case strings.HasPrefix(field, "uid:"):
+				for _, value := range strings.Split(strings.TrimPrefix(field, "uid:"), ",") {
+					if uid, err := strconv.Atoi(value); err == nil {
+						pkg.Uids = append(pkg.Uids, uid)
+					}
+				}
+				if len(pkg.Uids) > 0 {
+					pkg.UID = pkg.Uids[0]
+				}
+			case strings.HasPrefix(field, "installer="):
+				if installer := strings.TrimPrefix(field, "installer="); installer != "null" {
+					pkg.Installer = installer
+				}
+			}
 		}
-		if options.AllowVersionCodeDowngrade {
-			args = append(args, "-d")
+
+		if strings.HasPrefix(pkg.Filename, "/apex/") || strings.HasSuffix(pkg.Filename, ".apex") || strings.HasSuffix(pkg.Filename, ".capex") {
+			pkg.Apex = true
 		}
-		if options.ReplaceExistingApplication {
-			args = append(args, "-r")
+
+		packages = append(packages, pkg)
+	}
+	return packages
+}
+
+// Install installs the device apk file src. When options.LocalApk is set, the local copy is compared with the
+// installed package first (see CheckInstall)
+func (p PackageManager) Install(src string, options *InstallOptions) (process.OutputResult, error) {
+	if options != nil && options.LocalApk != "" {
+		local, err := apk.Open(options.LocalApk)
+		if err != nil {
+			return process.OutputResult{}, err
 		}
-		if options.DontKill {
-			args = append(args, "--dont-kill")
+		if err := p.CheckInstall(local, options); err != nil {
+			return process.OutputResult{}, err
 		}
 	}
+
+	args := options.args()
 	args = append(args, src)
 	cmd := p.Shell.NewCommand().WithArgs("cmd package install").AddArgs(args...)
 	return process.SimpleOutput(cmd, p.Shell.Conn.Verbose)
@@ -202,9 +254,7 @@ func (p PackageManager) Uninstall(packageName string, options *UninstallOptions)
 		if options.KeepData {
 			args = append(args, "-k")
 		}
-		if options.User != "" {
-			args = append(args, "--user", options.User)
-		}
+		args = append(args, options.User.Args()...)
 		if options.VersionCode != "" {
 			args = append(args, "--versionCode", options.VersionCode)
 		}
@@ -215,6 +265,8 @@ func (p PackageManager) Uninstall(packageName string, options *UninstallOptions)
 	// return p.Shell.ExecuteWithTimeout("cmd package uninstall", 0, args...)
 }
 
+// RuntimePermissions returns the runtime permissions of the given package
+// Deprecated use GetPermissions instead
 func (p PackageManager) RuntimePermissions(packageName string) ([]types.PackagePermission, error) {
 	result, err := p.Dump(packageName)
 	if err != nil {
@@ -282,6 +334,22 @@ func (p PackageManager) RequestedPermissions(packageName string) ([]types.Reques
 	return parser.RequestedPermissions(), nil
 }
 
+// GetPackageInfo returns the typed information of the given package, parsed from "pm dump <pkg>"
+func (p PackageManager) GetPackageInfo(packageName string) (*PackageInfo, error) {
+	result, err := p.Dump(packageName)
+	if err != nil {
+		return nil, err
+	}
+
+	if !result.IsOk() {
+		return nil, result.NewError()
+	}
+
+	return ParsePackageInfo(result.Output())
+}
+
+// DumpPackage returns a SimplePackageReader for the given package
+// Deprecated use GetPackageInfo instead
 func (p PackageManager) DumpPackage(packageName string) (*SimplePackageReader, error) {
 	result, err := p.Dump(packageName)
 	if err != nil {
@@ -302,8 +370,8 @@ func (p PackageManager) Clear(packageName string) (process.OutputResult, error)
 	return process.SimpleOutput(cmd, p.Shell.Conn.Verbose)
 }
 
-func (p PackageManager) ClearWithUser(packageName string, user string) (process.OutputResult, error) {
-	cmd := p.Shell.NewCommand().WithArgs(fmt.Sprintf("pm clear --user %s %s", user, packageName))
+func (p PackageManager) ClearWithUser(packageName string, user types.UserId) (process.OutputResult, error) {
+	cmd := p.Shell.NewCommand().WithArgs("pm", "clear").AddArgs(user.Args()...).AddArgs(packageName)
 	return process.SimpleOutput(cmd, p.Shell.Conn.Verbose)
 }
 
@@ -320,8 +388,8 @@ func (p PackageManager) Enable(packageName string) (process.OutputResult, error)
 	return process.SimpleOutput(p.Shell.NewCommand().WithArgs(fmt.Sprintf("pm enable %s", packageName)), p.Shell.Conn.Verbose)
 }
 
-func (p PackageManager) EnableWithUser(packageName string, user string) (process.OutputResult, error) {
-	return process.SimpleOutput(p.Shell.NewCommand().WithArgs(fmt.Sprintf("pm enable --user %s %s", user, packageName)), p.Shell.Conn.Verbose)
+func (p PackageManager) EnableWithUser(packageName string, user types.UserId) (process.OutputResult, error) {
+	return process.SimpleOutput(p.Shell.NewCommand().WithArgs("pm", "enable").AddArgs(user.Args()...).AddArgs(packageName), p.Shell.Conn.Verbose)
 }
 
 // Disable disable a package
@@ -329,30 +397,36 @@ func (p PackageManager) Disable(packageName string) (process.OutputResult, error
 	return process.SimpleOutput(p.Shell.NewCommand().WithArgs(fmt.Sprintf("pm disable %s", packageName)), p.Shell.Conn.Verbose)
 }
 
-func (p PackageManager) DisableWithUser(packageName string, user string) (process.OutputResult, error) {
-	return process.SimpleOutput(p.Shell.NewCommand().WithArgs(fmt.Sprintf("pm disable --user %s %s", user, packageName)), p.Shell.Conn.Verbose)
+func (p PackageManager) DisableWithUser(packageName string, user types.UserId) (process.OutputResult, error) {
+	return process.SimpleOutput(p.Shell.NewCommand().WithArgs("pm", "disable").AddArgs(user.Args()...).AddArgs(packageName), p.Shell.Conn.Verbose)
 }
 
 func (p PackageManager) RestoreDefaultState(packageName string) (process.OutputResult, error) {
 	return process.SimpleOutput(p.Shell.NewCommand().WithArgs(fmt.Sprintf("pm default-state %s", packageName)), p.Shell.Conn.Verbose)
 }
 
-func (p PackageManager) RestoreDefaultStateWithUSer(packageName string, user string) (process.OutputResult, error) {
-	return process.SimpleOutput(p.Shell.NewCommand().WithArgs(fmt.Sprintf("pm default-state --user %s %s", user, packageName)), p.Shell.Conn.Verbose)
+func (p PackageManager) RestoreDefaultStateWithUSer(packageName string, user types.UserId) (process.OutputResult, error) {
+	return process.SimpleOutput(p.Shell.NewCommand().WithArgs("pm", "default-state").AddArgs(user.Args()...).AddArgs(packageName), p.Shell.Conn.Verbose)
+}
+
+// execCommand executes the command and returns an error if it fails or its output reports a failure
+func (p PackageManager) execCommand(cmd *process.ADBCommand) error {
+	_, err := process.CheckedOutput(cmd, p.Shell.Conn.Verbose)
+	return err
 }
 
 type UninstallOptions struct {
 	// -k
 	KeepData bool
 	// --user
-	User string
+	User types.UserId
 	// --versionCode
 	VersionCode string
 }
 
 type InstallOptions struct {
 	// --user: install under the given user.
-	User string
+	User types.UserId
 	// --dont-kill: installing a new feature split, don't kill running app
 	DontKill bool
 	// --restrict-permissions: don't whitelist restricted permissions at install
@@ -370,6 +444,40 @@ type InstallOptions struct {
 	ReplaceExistingApplication bool
 	// -d: allow version code downgrade
 	AllowVersionCodeDowngrade bool
+	// LocalApk is the host copy of the apk installed by Install, checked against the installed package before installing
+	LocalApk string
+}
+
+func (o *InstallOptions) args() []string {
+	var args []string
+	if o != nil {
+		if o.RestrictPermissions {
+			args = append(args, "--restrict-permissions")
+		}
+		args = append(args, o.User.Args()...)
+		if o.Pkg != "" {
+			args = append(args, "--pkg", o.Pkg)
+		}
+		if o.InstallLocation > 0 {
+			args = append(args, "--install-location", fmt.Sprintf("%d", o.InstallLocation))
+		}
+		if o.GrantPermissions {
+			args = append(args, "-g")
+		}
+		if o.Force {
+			args = append(args, "-f")
+		}
+		if o.AllowVersionCodeDowngrade {
+			args = append(args, "-d")
+		}
+		if o.ReplaceExistingApplication {
+			args = append(args, "-r")
+		}
+		if o.DontKill {
+			args = append(args, "--dont-kill")
+		}
+	}
+	return args
 }
 
 type PackageOptions struct {
@@ -381,13 +489,65 @@ type PackageOptions struct {
 	ShowOnlySystem bool
 	// -3: filter to only show third party packages
 	ShowOnly3rdParty bool
+	// -a: all known packages (but excluding APEXes)
+	ShowAll bool
+	// -u: also include uninstalled packages
+	IncludeUninstalled bool
+	// --apex-only: only show APEX packages
+	ShowOnlyApex bool
+	// --uid: filter to only show packages with the given UID, ignored if 0
+	Uid int
+	// --user: only list packages belonging to the given user
+	User types.UserId
+	// WithEnabledState sets Package.Enabled, listing the disabled packages with a second query.
+	// It's not needed with ShowOnlyDisabled or ShowOnlyEnabed
+	WithEnabledState bool
+}
+
+func (o PackageOptions) args() []string {
+	var args []string
+	if o.ShowOnly3rdParty {
+		args = append(args, "-3")
+	}
+	if o.ShowOnlyDisabled {
+		args = append(args, "-d")
+	}
+	if o.ShowOnlyEnabed {
+		args = append(args, "-e")
+	}
+	if o.ShowOnlySystem {
+		args = append(args, "-s")
+	}
+	if o.ShowAll {
+		args = append(args, "-a")
+	}
+	if o.IncludeUninstalled {
+		args = append(args, "-u")
+	}
+	if o.ShowOnlyApex {
+		args = append(args, "--apex-only")
+	}
+	if o.Uid > 0 {
+		args = append(args, "--uid", strconv.Itoa(o.Uid))
+	}
+	args = append(args, o.User.Args()...)
+	return args
 }
 
 type Package struct {
 	Filename    string
 	Name        string
-	VersionCode string
-	UID         string
+	VersionCode int64
+	// UID is the first of Uids
+	UID int
+	// Uids are the uids of the package, one for each user
+	Uids []int
+	// Installer is the package name of the installer, empty if unknown
+	Installer string
+	// Enabled is set only when the packages are listed with PackageOptions.WithEnabledState,
+	// ShowOnlyDisabled or ShowOnlyEnabed
+	Enabled bool
+	Apex    bool
 }
 
 func (p Package) String() string {
@@ -401,5 +561,5 @@ func (p Package) String() string {
 func (p Package) MaybeIsSystem() bool {
 	return strings.HasPrefix(p.Filename, "/system/") || strings.HasPrefix(p.Filename, "/product/") || strings.HasPrefix(p.Filename, "/system_ext/") ||
 		strings.HasPrefix(p.Filename, "/vendor/") || strings.HasPrefix(p.Filename, "/apex/") ||
-		p.UID == "1000"
+		p.Apex || p.UID == 1000
 }
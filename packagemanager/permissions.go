@@ -0,0 +1,229 @@
+package packagemanager
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sephiroth74/go_adb_client/types"
+)
+
+// PermissionChange actions
+const (
+	PermissionActionGrant  = "grant"
+	PermissionActionRevoke = "revoke"
+	PermissionActionFlags  = "flags"
+	PermissionActionAppOp  = "appop"
+)
+
+// region PackagePermissions
+
+// PackagePermissions are the runtime, install and app op permissions of a package for a single user
+type PackagePermissions struct {
+	PackageName string
+	UserID      types.UserId
+	Requested   []types.RequestedPermission
+	Install     []types.PackagePermission
+	Runtime     []types.PackagePermission
+	AppOps      []AppOp
+}
+
+// Get returns the runtime or install permission with the given name, nil if not found
+func (p PackagePermissions) Get(name string) *types.PackagePermission {
+	for i := range p.Runtime {
+		if p.Runtime[i].Name == name {
+			return &p.Runtime[i]
+		}
+	}
+	for i := range p.Install {
+		if p.Install[i].Name == name {
+			return &p.Install[i]
+		}
+	}
+	return nil
+}
+
+// IsGranted returns true if the runtime or install permission is granted
+func (p PackagePermissions) IsGranted(name string) bool {
+	permission := p.Get(name)
+	return permission != nil && permission.Granted
+}
+
+// AppOp returns the app op with the given name, nil if not found
+func (p PackagePermissions) AppOp(name string) *AppOp {
+	return findAppOp(p.AppOps, name)
+}
+
+// endregion PackagePermissions
+
+// region PermissionProfile
+
+// PermissionProfile is the desired state of the permissions of a package
+type PermissionProfile struct {
+	// Granted are the runtime permissions to grant
+	Granted []string
+	// Revoked are the runtime permissions to revoke
+	Revoked []string
+	// Flags are the flags to set, by permission name
+	Flags map[string][]types.PermissionFlag
+	// AppOps are the modes to set, by op name
+	AppOps map[string]AppOpMode
+}
+
+// PermissionChange is a change applied by ApplyPermissionProfile
+type PermissionChange struct {
+	Name   string
+	Action string
+	Value  string
+}
+
+func (c PermissionChange) String() string {
+	if c.Value == "" {
+		return fmt.Sprintf("%s %s", c.Action, c.Name)
+	}
+	return fmt.Sprintf("%s %s %s", c.Action, c.Name, c.Value)
+}
+
+// endregion PermissionProfile
+
+// GetPermissions returns the permissions of the given package for the user (the system user if empty)
+func (p PackageManager) GetPermissions(packageName string, user types.UserId) (*PackagePermissions, error) {
+	userID := user
+	if !userID.IsSet() {
+		userID = types.UserSystem
+	}
+	if _, err := userID.Int(); err != nil {
+		return nil, fmt.Errorf("invalid user %s: %w", user, err)
+	}
+
+	info, err := p.GetPackageInfo(packageName)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PackagePermissions{
+		PackageName: packageName,
+		UserID:      userID,
+		Requested:   info.RequestedPermissions,
+		Install:     info.InstallPermissions,
+	}
+
+	if state := info.User(userID); state != nil {
+		result.Runtime = state.RuntimePermissions
+	}
+
+	result.AppOps, err = p.GetAppOps(packageName, user)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GrantPermissions grants the given runtime permissions with "pm grant".
+// All the permissions are processed, the returned error joins the failures
+func (p PackageManager) GrantPermissions(packageName string, user types.UserId, permissions ...string) error {
+	var errs []error
+	for _, permission := range permissions {
+		errs = append(errs, p.permissionCommand("grant", packageName, user, permission))
+	}
+	return errors.Join(errs...)
+}
+
+// RevokePermissions revokes the given runtime permissions with "pm revoke".
+// All the permissions are processed, the returned error joins the failures
+func (p PackageManager) RevokePermissions(packageName string, user types.UserId, permissions ...string) error {
+	var errs []error
+	for _, permission := range permissions {
+		errs = append(errs, p.permissionCommand("revoke", packageName, user, permission))
+	}
+	return errors.Join(errs...)
+}
+
+// SetPermissionFlags sets the flags of a runtime permission with "pm set-permission-flags"
+func (p PackageManager) SetPermissionFlags(packageName string, permission string, user types.UserId, flags ...types.PermissionFlag) error {
+	return p.permissionCommand("set-permission-flags", packageName, user, append([]string{permission}, flagNames(flags)...)...)
+}
+
+// ClearPermissionFlags clears the flags of a runtime permission with "pm clear-permission-flags"
+func (p PackageManager) ClearPermissionFlags(packageName string, permission string, user types.UserId, flags ...types.PermissionFlag) error {
+	return p.permissionCommand("clear-permission-flags", packageName, user, append([]string{permission}, flagNames(flags)...)...)
+}
+
+// ResetPermissions reverts all the runtime permissions of all the packages to their default state with "pm reset-permissions"
+func (p PackageManager) ResetPermissions() error {
+	return p.execCommand(p.Shell.NewCommand().WithArgs("pm", "reset-permissions"))
+}
+
+// ApplyPermissionProfile changes the permissions of the package to match the given profile.
+// Only the permissions which differ from the current state are changed, the applied changes are returned.
+// All the changes are attempted, the returned error joins the failures
+func (p PackageManager) ApplyPermissionProfile(packageName string, profile PermissionProfile, user types.UserId) ([]PermissionChange, error) {
+	current, err := p.GetPermissions(packageName, user)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []PermissionChange
+	var errs []error
+
+	apply := func(change PermissionChange, err error) {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", change, err))
+		} else {
+			changes = append(changes, change)
+		}
+	}
+
+	for _, name := range profile.Granted {
+		if !current.IsGranted(name) {
+			apply(PermissionChange{Name: name, Action: PermissionActionGrant}, p.permissionCommand("grant", packageName, user, name))
+		}
+	}
+
+	for _, name := range profile.Revoked {
+		if permission := current.Get(name); permission != nil && permission.Granted {
+			apply(PermissionChange{Name: name, Action: PermissionActionRevoke}, p.permissionCommand("revoke", packageName, user, name))
+		}
+	}
+
+	for _, name := range types.SortedKeys(profile.Flags) {
+		var missing []types.PermissionFlag
+		permission := current.Get(name)
+		for _, flag := range profile.Flags[name] {
+			if permission == nil || !permission.HasFlag(flag) {
+				missing = append(missing, flag)
+			}
+		}
+		if len(missing) > 0 {
+			change := PermissionChange{Name: name, Action: PermissionActionFlags, Value: strings.Join(flagNames(missing), " ")}
+			apply(change, p.SetPermissionFlags(packageName, name, user, missing...))
+		}
+	}
+
+	for _, name := range types.SortedKeys(profile.AppOps) {
+		mode := profile.AppOps[name]
+		op := current.AppOp(name)
+		if (op == nil && mode != AppOpModeDefault) || (op != nil && op.Mode != mode) {
+			apply(PermissionChange{Name: name, Action: PermissionActionAppOp, Value: string(mode)}, p.SetAppOp(packageName, name, mode, user))
+		}
+	}
+
+	return changes, errors.Join(errs...)
+}
+
+// permissionCommand executes "pm <command> [--user user] <pkg> <args>"
+func (p PackageManager) permissionCommand(command string, packageName string, user types.UserId, args ...string) error {
+	cmd := p.Shell.NewCommand().WithArgs("pm", command)
+	cmd.AddArgs(user.Args()...)
+	cmd.AddArgs(packageName)
+	cmd.AddArgs(args...)
+	return p.execCommand(cmd)
+}
+
+func flagNames(flags []types.PermissionFlag) []string {
+	names := make([]string, len(flags))
+	for i, flag := range flags {
+		names[i] = flag.ShellName()
+	}
+	return names
+}
@@ -9,6 +9,8 @@ import (
 	"strings"
 )
 
+// SimplePackageReader reads single fields from the "Packages:" section of a package dump
+// Deprecated use PackageInfo instead
 type SimplePackageReader struct {
 	Data string
 }
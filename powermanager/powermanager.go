@@ -0,0 +1,418 @@
+package powermanager
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sephiroth74/go_adb_client/process"
+	"github.com/sephiroth74/go_adb_client/shell"
+	"github.com/sephiroth74/go_adb_client/telemetry"
+	"github.com/sephiroth74/go_adb_client/types"
+)
+
+var (
+	deviceIdleFieldRegexp = regexp.MustCompile(`\b(m\w+)=(\S+)`)
+	steppedRegexp         = regexp.MustCompile(`Stepped to (deep|light): (\w+)`)
+	standbyBucketRegexp   = regexp.MustCompile(`^(\S+): (-?\d+)$`)
+	inactiveRegexp        = regexp.MustCompile(`Idle=(true|false)`)
+)
+
+// region PowerSource
+
+// PowerSource is a charger type, as accepted by "dumpsys battery set"
+type PowerSource string
+
+const (
+	PowerSourceAc       PowerSource = "ac"
+	PowerSourceUsb      PowerSource = "usb"
+	PowerSourceWireless PowerSource = "wireless"
+	// PowerSourceDock is available on android 13+
+	PowerSourceDock PowerSource = "dock"
+)
+
+// endregion PowerSource
+
+// region IdleMode
+
+// IdleMode is the doze mode: deep or light
+type IdleMode string
+
+const (
+	IdleModeDeep  IdleMode = "deep"
+	IdleModeLight IdleMode = "light"
+	IdleModeAll   IdleMode = "all"
+)
+
+// DeepIdleState is the state of the deep doze (DeviceIdleController.STATE_*)
+type DeepIdleState string
+
+const (
+	DeepIdleActive          DeepIdleState = "ACTIVE"
+	DeepIdleInactive        DeepIdleState = "INACTIVE"
+	DeepIdlePending         DeepIdleState = "IDLE_PENDING"
+	DeepIdleSensing         DeepIdleState = "SENSING"
+	DeepIdleLocating        DeepIdleState = "LOCATING"
+	DeepIdleIdle            DeepIdleState = "IDLE"
+	DeepIdleIdleMaintenance DeepIdleState = "IDLE_MAINTENANCE"
+	DeepIdleQuickDozeDelay  DeepIdleState = "QUICK_DOZE_DELAY"
+)
+
+// LightIdleState is the state of the light doze (DeviceIdleController.LIGHT_STATE_*)
+type LightIdleState string
+
+const (
+	LightIdleActive            LightIdleState = "ACTIVE"
+	LightIdleInactive          LightIdleState = "INACTIVE"
+	LightIdlePreIdle           LightIdleState = "PRE_IDLE"
+	LightIdleIdle              LightIdleState = "IDLE"
+	LightIdleWaitingForNetwork LightIdleState = "WAITING_FOR_NETWORK"
+	LightIdleIdleMaintenance   LightIdleState = "IDLE_MAINTENANCE"
+	LightIdleOverride          LightIdleState = "OVERRIDE"
+)
+
+// endregion IdleMode
+
+// region DeviceIdleState
+
+// DeviceIdleState is the doze state, parsed from "dumpsys deviceidle"
+type DeviceIdleState struct {
+	DeepEnabled      bool
+	LightEnabled     bool
+	ForceIdle        bool
+	ScreenOn         bool
+	Charging         bool
+	NetworkConnected bool
+	DeepState        DeepIdleState
+	LightState       LightIdleState
+	// SystemWhitelist are the system apps exempted from doze
+	SystemWhitelist []string
+	// SystemExceptIdleWhitelist are the system apps exempted from app standby, but not from doze
+	SystemExceptIdleWhitelist []string
+	// UserWhitelist are the apps exempted from doze by the user (or with Whitelist)
+	UserWhitelist []string
+}
+
+func (s DeviceIdleState) String() string {
+	return fmt.Sprintf("DeviceIdleState{DeepState:%s, LightState:%s, ForceIdle:%t, ScreenOn:%t, Charging:%t}",
+		s.DeepState, s.LightState, s.ForceIdle, s.ScreenOn, s.Charging)
+}
+
+// IsWhitelisted returns true if the package is exempted from doze
+func (s DeviceIdleState) IsWhitelisted(packageName string) bool {
+	for _, list := range [][]string{s.SystemWhitelist, s.UserWhitelist} {
+		for _, name := range list {
+			if name == packageName {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ParseDeviceIdle parses the output of "dumpsys deviceidle"
+func ParseDeviceIdle(data string) DeviceIdleState {
+	state := DeviceIdleState{}
+	var section *[]string
+
+	for _, line := range strings.Split(data, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		// the whitelist is named allowlist on android 12+
+		if strings.HasSuffix(trimmed, ":") && (strings.HasPrefix(trimmed, "Whitelist") || strings.HasPrefix(trimmed, "Allowlist")) {
+			section = nil
+			switch {
+			case strings.Contains(trimmed, "app ids"):
+			case strings.Contains(trimmed, "(except idle) system apps"):
+				section = &state.SystemExceptIdleWhitelist
+			case strings.Contains(trimmed, "system apps") && !strings.Contains(trimmed, "("):
+				section = &state.SystemWhitelist
+			case strings.Contains(trimmed, "user apps") && !strings.Contains(trimmed, "("):
+				section = &state.UserWhitelist
+			}
+			continue
+		}
+
+		// the list items are indented, a less indented line ends the list
+		if section != nil && strings.HasPrefix(line, "    ") && trimmed != "" && !strings.Contains(trimmed, "=") {
+			*section = append(*section, trimmed)
+			continue
+		}
+		section = nil
+
+		for _, m := range deviceIdleFieldRegexp.FindAllStringSubmatch(trimmed, -1) {
+			switch m[1] {
+			case "mDeepEnabled":
+				state.DeepEnabled = m[2] == "true"
+			case "mLightEnabled":
+				state.LightEnabled = m[2] == "true"
+			case "mForceIdle":
+				state.ForceIdle = m[2] == "true"
+			case "mScreenOn":
+				state.ScreenOn = m[2] == "true"
+			case "mCharging":
+				state.Charging = m[2] == "true"
+			case "mNetworkConnected":
+				state.NetworkConnected = m[2] == "true"
+			case "mState":
+				state.DeepState = DeepIdleState(m[2])
+			case "mLightState":
+				state.LightState = LightIdleState(m[2])
+			}
+		}
+	}
+	return state
+}
+
+// endregion DeviceIdleState
+
+// region StandbyBucket
+
+// StandbyBucket is the app standby bucket (UsageStatsManager.STANDBY_BUCKET_*)
+type StandbyBucket int
+
+const (
+	StandbyBucketExempted   StandbyBucket = 5
+	StandbyBucketActive     StandbyBucket = 10
+	StandbyBucketWorkingSet StandbyBucket = 20
+	StandbyBucketFrequent   StandbyBucket = 30
+	StandbyBucketRare       StandbyBucket = 40
+	StandbyBucketRestricted StandbyBucket = 45
+	StandbyBucketNever      StandbyBucket = 50
+)
+
+func (b StandbyBucket) String() string {
+	switch b {
+	case StandbyBucketExempted:
+		return "exempted"
+	case StandbyBucketActive:
+		return "active"
+	case StandbyBucketWorkingSet:
+		return "working_set"
+	case StandbyBucketFrequent:
+		return "frequent"
+	case StandbyBucketRare:
+		return "rare"
+	case StandbyBucketRestricted:
+		return "restricted"
+	case StandbyBucketNever:
+		return "never"
+	}
+	return strconv.Itoa(int(b))
+}
+
+// endregion StandbyBucket
+
+type PowerManager struct {
+	Shell *shell.Shell
+}
+
+// region Battery
+
+// GetBattery returns the battery state, parsed from "dumpsys battery"
+func (p PowerManager) GetBattery() (*telemetry.BatteryInfo, error) {
+	result, err := p.dumpsys("battery")
+	if err != nil {
+		return nil, err
+	}
+	return telemetry.ParseBattery(result.Output())
+}
+
+// SetBatteryLevel simulates the battery level with "dumpsys battery set level"
+func (p PowerManager) SetBatteryLevel(level int) error {
+	_, err := p.dumpsys("battery", "set", "level", strconv.Itoa(level))
+	return err
+}
+
+// SetBatteryStatus simulates the battery status with "dumpsys battery set status"
+func (p PowerManager) SetBatteryStatus(status telemetry.BatteryStatus) error {
+	_, err := p.dumpsys("battery", "set", "status", strconv.Itoa(int(status)))
+	return err
+}
+
+// SetBatteryTemperature simulates the battery temperature, in degrees celsius, with "dumpsys battery set temp"
+func (p PowerManager) SetBatteryTemperature(celsius float64) error {
+	_, err := p.dumpsys("battery", "set", "temp", strconv.Itoa(int(math.Round(celsius*10))))
+	return err
+}
+
+// SetPowered simulates the given charger being plugged or unplugged with "dumpsys battery set"
+func (p PowerManager) SetPowered(source PowerSource, powered bool) error {
+	value := "0"
+	if powered {
+		value = "1"
+	}
+	_, err := p.dumpsys("battery", "set", string(source), value)
+	return err
+}
+
+// UnplugBattery simulates all the chargers being unplugged with "dumpsys battery unplug"
+func (p PowerManager) UnplugBattery() error {
+	_, err := p.dumpsys("battery", "unplug")
+	return err
+}
+
+// ResetBattery stops simulating the battery state with "dumpsys battery reset"
+func (p PowerManager) ResetBattery() error {
+	_, err := p.dumpsys("battery", "reset")
+	return err
+}
+
+// endregion Battery
+
+// region DeviceIdle
+
+// GetDeviceIdleState returns the doze state, parsed from "dumpsys deviceidle"
+func (p PowerManager) GetDeviceIdleState() (*DeviceIdleState, error) {
+	result, err := p.dumpsys("deviceidle")
+	if err != nil {
+		return nil, err
+	}
+	state := ParseDeviceIdle(result.Output())
+	return &state, nil
+}
+
+// ForceIdle forces the device in the given idle mode with "dumpsys deviceidle force-idle".
+// The device must be unplugged (see UnplugBattery) and the screen off
+func (p PowerManager) ForceIdle(mode IdleMode) error {
+	_, err := p.dumpsys("deviceidle", "force-idle", string(mode))
+	return err
+}
+
+// StepIdle moves the given idle mode to its next state with "dumpsys deviceidle step" and returns the new state
+func (p PowerManager) StepIdle(mode IdleMode) (string, error) {
+	result, err := p.dumpsys("deviceidle", "step", string(mode))
+	if err != nil {
+		return "", err
+	}
+
+	m := steppedRegexp.FindStringSubmatch(result.Output())
+	if m == nil {
+		return "", fmt.Errorf("unexpected output: %s", result.Output())
+	}
+	return m[2], nil
+}
+
+// UnforceIdle resumes the normal doze behavior with "dumpsys deviceidle unforce"
+func (p PowerManager) UnforceIdle() error {
+	_, err := p.dumpsys("deviceidle", "unforce")
+	return err
+}
+
+// EnableIdle enables the given idle mode with "dumpsys deviceidle enable"
+func (p PowerManager) EnableIdle(mode IdleMode) error {
+	_, err := p.dumpsys("deviceidle", "enable", string(mode))
+	return err
+}
+
+// DisableIdle disables the given idle mode with "dumpsys deviceidle disable"
+func (p PowerManager) DisableIdle(mode IdleMode) error {
+	_, err := p.dumpsys("deviceidle", "disable", string(mode))
+	return err
+}
+
+// Whitelist exempts the packages from doze with "dumpsys deviceidle whitelist +<package>"
+func (p PowerManager) Whitelist(packages ...string) error {
+	return p.whitelist("+", packages)
+}
+
+// RemoveFromWhitelist removes the packages from the doze whitelist with "dumpsys deviceidle whitelist -<package>"
+func (p PowerManager) RemoveFromWhitelist(packages ...string) error {
+	return p.whitelist("-", packages)
+}
+
+func (p PowerManager) whitelist(prefix string, packages []string) error {
+	args := []string{"whitelist"}
+	for _, name := range packages {
+		args = append(args, prefix+name)
+	}
+	_, err := p.dumpsys("deviceidle", args...)
+	return err
+}
+
+// endregion DeviceIdle
+
+// region AppStandby
+
+// SetInactive marks the package as inactive (or active) for app standby with "am set-inactive"
+func (p PowerManager) SetInactive(packageName string, inactive bool, user types.UserId) error {
+	cmd := p.Shell.NewCommand().WithArgs("am", "set-inactive").AddArgs(user.Args()...).AddArgs(packageName, strconv.FormatBool(inactive))
+	_, err := p.execute(cmd)
+	return err
+}
+
+// IsInactive returns true if the package is inactive for app standby with "am get-inactive"
+func (p PowerManager) IsInactive(packageName string, user types.UserId) (bool, error) {
+	cmd := p.Shell.NewCommand().WithArgs("am", "get-inactive").AddArgs(user.Args()...).AddArgs(packageName)
+	result, err := p.execute(cmd)
+	if err != nil {
+		return false, err
+	}
+
+	m := inactiveRegexp.FindStringSubmatch(result.Output())
+	if m == nil {
+		return false, fmt.Errorf("unexpected output: %s", result.Output())
+	}
+	return m[1] == "true", nil
+}
+
+// GetStandbyBucket returns the standby bucket of the package with "am get-standby-bucket"
+func (p PowerManager) GetStandbyBucket(packageName string, user types.UserId) (StandbyBucket, error) {
+	cmd := p.Shell.NewCommand().WithArgs("am", "get-standby-bucket").AddArgs(user.Args()...).AddArgs(packageName)
+	result, err := p.execute(cmd)
+	if err != nil {
+		return 0, err
+	}
+
+	bucket, err := strconv.Atoi(result.Output())
+	if err != nil {
+		return 0, fmt.Errorf("unexpected output: %s", result.Output())
+	}
+	return StandbyBucket(bucket), nil
+}
+
+// GetStandbyBuckets returns the standby buckets of all the packages with "am get-standby-bucket"
+func (p PowerManager) GetStandbyBuckets(user types.UserId) (map[string]StandbyBucket, error) {
+	cmd := p.Shell.NewCommand().WithArgs("am", "get-standby-bucket").AddArgs(user.Args()...)
+	result, err := p.execute(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	buckets := map[string]StandbyBucket{}
+	for _, line := range result.OutputLines(true) {
+		if m := standbyBucketRegexp.FindStringSubmatch(line); m != nil {
+			bucket, _ := strconv.Atoi(m[2])
+			buckets[m[1]] = StandbyBucket(bucket)
+		}
+	}
+	return buckets, nil
+}
+
+// SetStandbyBucket moves the package to the given standby bucket with "am set-standby-bucket"
+func (p PowerManager) SetStandbyBucket(packageName string, bucket StandbyBucket, user types.UserId) error {
+	cmd := p.Shell.NewCommand().WithArgs("am", "set-standby-bucket").AddArgs(user.Args()...).AddArgs(packageName, bucket.String())
+	_, err := p.execute(cmd)
+	return err
+}
+
+// endregion AppStandby
+
+// SetFixedPerformanceMode enables or disables the fixed performance mode (no thermal throttling and
+// no dynamic frequency scaling) with "cmd power set-fixed-performance-mode-enabled"
+func (p PowerManager) SetFixedPerformanceMode(enabled bool) error {
+	cmd := p.Shell.NewCommand().WithArgs("cmd", "power", "set-fixed-performance-mode-enabled", strconv.FormatBool(enabled))
+	_, err := p.execute(cmd)
+	return err
+}
+
+func (p PowerManager) dumpsys(name string, args ...string) (process.OutputResult, error) {
+	result, err := p.Shell.DumpSys(name, args...)
+	return result, process.Check(result, err)
+}
+
+func (p PowerManager) execute(cmd *process.ADBCommand) (process.OutputResult, error) {
+	return process.CheckedOutput(cmd, p.Shell.Conn.Verbose)
+}
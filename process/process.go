@@ -2,6 +2,7 @@ package process
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -33,6 +34,7 @@ type ADBCommand struct {
 	ADBCommand string
 	Serial     string
 	StdOut     io.Writer
+	StdIn      io.Reader
 	Args       []string
 	Timeout    time.Duration
 }
@@ -76,6 +78,11 @@ func (a *ADBCommand) WithStdOut(writer io.Writer) *ADBCommand {
 	return a
 }
 
+func (a *ADBCommand) WithStdIn(reader io.Reader) *ADBCommand {
+	a.StdIn = reader
+	return a
+}
+
 func (a *ADBCommand) FullArgs() []string {
 	var args = []string{}
 	if a.Serial != "" {
@@ -95,6 +102,9 @@ func (a *ADBCommand) ToCommand() *processbuilder.Command {
 	if a.StdOut != nil {
 		cmd.WithStdOut(a.StdOut)
 	}
+	if a.StdIn != nil {
+		cmd.WithStdIn(a.StdIn)
+	}
 	return cmd
 }
 
@@ -148,6 +158,25 @@ func (o OutputResult) OutputLines(trim bool) []string {
 	}
 }
 
+// ErrorPrefixes are the output prefixes of the device commands (pm, am, cmd, ime...) reporting a failure
+// with a success exit code
+var ErrorPrefixes = []string{"Error", "Exception", "Failure", "Unknown", "Unable", "Invalid"}
+
+// Check returns an error if the exit code is not 0 or the output reports a failure (see ErrorPrefixes)
+func (o OutputResult) Check() error {
+	if !o.IsOk() {
+		return o.NewError()
+	}
+
+	output := o.Output()
+	for _, prefix := range ErrorPrefixes {
+		if strings.HasPrefix(output, prefix) {
+			return errors.New(output)
+		}
+	}
+	return nil
+}
+
 func (o OutputResult) String() string {
 	return fmt.Sprintf("OutputResult(isOk=`%t`, Stdout=`%s`, Stderr=`%s`, ExitCode=%d, ExitStatus=%#v)", o.IsOk(), o.Output(), o.Error(), o.ExitCode, o.ExitStatus)
 }
@@ -169,6 +198,10 @@ func SimpleOutput(command *ADBCommand, verbose bool) (OutputResult, error) {
 		cmd.WithStdOut(command.StdOut)
 	}
 
+	if command.StdIn != nil {
+		cmd.WithStdIn(command.StdIn)
+	}
+
 	sout, serr, code, state, err := processbuilder.Output(
 		option,
 		cmd,
@@ -191,3 +224,17 @@ func SimpleOutput(command *ADBCommand, verbose bool) (OutputResult, error) {
 
 	return result, err
 }
+
+// Check returns the error of the command, or the error reported by its result (see OutputResult.Check)
+func Check(result OutputResult, err error) error {
+	if err != nil {
+		return err
+	}
+	return result.Check()
+}
+
+// CheckedOutput runs the command like SimpleOutput and returns an error if it fails (see OutputResult.Check)
+func CheckedOutput(command *ADBCommand, verbose bool) (OutputResult, error) {
+	result, err := SimpleOutput(command, verbose)
+	return result, Check(result, err)
+}
@@ -0,0 +1,145 @@
+package screencap
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+)
+
+var (
+	diffColor   = color.NRGBA{R: 0xff, A: 0xff}
+	maskedColor = color.NRGBA{R: 0x80, G: 0x80, B: 0x80, A: 0xff}
+)
+
+// DiffOptions are the options of Diff
+type DiffOptions struct {
+	// Tolerance is the maximum difference of a color channel (0-255) for two pixels to be equal
+	Tolerance uint8
+	// Masks are the regions ignored by the comparison (e.g. the clock of the status bar)
+	Masks []image.Rectangle
+	// Image generates DiffResult.Image
+	Image bool
+}
+
+func (o *DiffOptions) masked(p image.Point) bool {
+	if o == nil {
+		return false
+	}
+	for _, mask := range o.Masks {
+		if p.In(mask) {
+			return true
+		}
+	}
+	return false
+}
+
+// DiffResult is the result of Diff
+type DiffResult struct {
+	// Compared is the number of pixels compared, without the masked ones
+	Compared int
+	// Different is the number of different pixels
+	Different int
+	// Bounds is the bounding box of the different pixels, empty if the images are equal
+	Bounds image.Rectangle
+	// Image shows the different pixels in red and the masked pixels in gray over a faded copy
+	// of the first image. Only generated with DiffOptions.Image
+	Image *image.NRGBA
+}
+
+func (r DiffResult) String() string {
+	return fmt.Sprintf("DiffResult{Different:%d/%d, Similarity:%.4f, Bounds:%v}", r.Different, r.Compared, r.Similarity(), r.Bounds)
+}
+
+// Similarity returns the ratio of the equal pixels, between 0 and 1
+func (r DiffResult) Similarity() float64 {
+	if r.Compared == 0 {
+		return 1
+	}
+	return 1 - float64(r.Different)/float64(r.Compared)
+}
+
+// Equal returns true if there are no different pixels
+func (r DiffResult) Equal() bool {
+	return r.Different == 0
+}
+
+// Diff compares the pixels of two images of the same size. The pixel coordinates (and the masks) are
+// relative to the bounds of each image, so that two crops of the same size can be compared
+func Diff(a image.Image, b image.Image, options *DiffOptions) (*DiffResult, error) {
+	boundsA, boundsB := a.Bounds(), b.Bounds()
+	if boundsA.Size() != boundsB.Size() {
+		return nil, fmt.Errorf("different image sizes: %v and %v", boundsA.Size(), boundsB.Size())
+	}
+
+	var tolerance uint8
+	result := &DiffResult{}
+	if options != nil {
+		tolerance = options.Tolerance
+		if options.Image {
+			result.Image = image.NewNRGBA(image.Rectangle{Max: boundsA.Size()})
+		}
+	}
+
+	for y := 0; y < boundsA.Dy(); y++ {
+		for x := 0; x < boundsA.Dx(); x++ {
+			p := image.Pt(x, y)
+			pixelA := nrgbaAt(a, boundsA.Min.Add(p))
+
+			if options.masked(p) {
+				if result.Image != nil {
+					result.Image.SetNRGBA(x, y, maskedColor)
+				}
+				continue
+			}
+
+			result.Compared++
+			pixelB := nrgbaAt(b, boundsB.Min.Add(p))
+			different := channelDiff(pixelA.R, pixelB.R) > tolerance ||
+				channelDiff(pixelA.G, pixelB.G) > tolerance ||
+				channelDiff(pixelA.B, pixelB.B) > tolerance ||
+				channelDiff(pixelA.A, pixelB.A) > tolerance
+
+			if different {
+				result.Different++
+				result.Bounds = result.Bounds.Union(image.Rectangle{Min: p, Max: p.Add(image.Pt(1, 1))})
+			}
+
+			if result.Image != nil {
+				if different {
+					result.Image.SetNRGBA(x, y, diffColor)
+				} else {
+					result.Image.SetNRGBA(x, y, fade(pixelA))
+				}
+			}
+		}
+	}
+	return result, nil
+}
+
+// Similarity returns the ratio of the equal pixels of the two images (see Diff)
+func Similarity(a image.Image, b image.Image, options *DiffOptions) (float64, error) {
+	result, err := Diff(a, b, options)
+	if err != nil {
+		return 0, err
+	}
+	return result.Similarity(), nil
+}
+
+func nrgbaAt(img image.Image, p image.Point) color.NRGBA {
+	if nrgba, ok := img.(*image.NRGBA); ok {
+		return nrgba.NRGBAAt(p.X, p.Y)
+	}
+	return color.NRGBAModel.Convert(img.At(p.X, p.Y)).(color.NRGBA)
+}
+
+func channelDiff(a uint8, b uint8) uint8 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+// fade blends the pixel with white, so that the differences stand out
+func fade(c color.NRGBA) color.NRGBA {
+	return color.NRGBA{R: c.R/4 + 0xbf, G: c.G/4 + 0xbf, B: c.B/4 + 0xbf, A: 0xff}
+}
@@ -0,0 +1,175 @@
+package screencap
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	displayIdRegexp   = regexp.MustCompile(`^Display (\d+) \(HWC display (\d+)\)`)
+	displayNameRegexp = regexp.MustCompile(`displayName="([^"]*)"`)
+)
+
+// PixelFormat is the format of the raw screencap pixels (android.graphics.PixelFormat)
+type PixelFormat uint32
+
+const (
+	RGBA_8888 PixelFormat = 1
+	RGBX_8888 PixelFormat = 2
+	RGB_888   PixelFormat = 3
+	RGB_565   PixelFormat = 4
+	BGRA_8888 PixelFormat = 5
+)
+
+func (f PixelFormat) String() string {
+	switch f {
+	case RGBA_8888:
+		return "RGBA_8888"
+	case RGBX_8888:
+		return "RGBX_8888"
+	case RGB_888:
+		return "RGB_888"
+	case RGB_565:
+		return "RGB_565"
+	case BGRA_8888:
+		return "BGRA_8888"
+	}
+	return fmt.Sprintf("PixelFormat(%d)", uint32(f))
+}
+
+// BytesPerPixel returns the size of a pixel, 0 for the unsupported formats
+func (f PixelFormat) BytesPerPixel() int {
+	switch f {
+	case RGBA_8888, RGBX_8888, BGRA_8888:
+		return 4
+	case RGB_888:
+		return 3
+	case RGB_565:
+		return 2
+	}
+	return 0
+}
+
+// Options are the options of the screen capture
+type Options struct {
+	// DisplayId is the physical display to capture (see Device.GetDisplays). Default the main display
+	DisplayId string
+	// Region is the region of the screen to return. Default the whole screen
+	Region image.Rectangle
+}
+
+// Args returns the arguments of screencap for the options
+func (o *Options) Args() []string {
+	if o == nil || o.DisplayId == "" {
+		return nil
+	}
+	return []string{"-d", o.DisplayId}
+}
+
+// Crop returns the options region of the image, the image itself when no region is set
+func (o *Options) Crop(img image.Image) image.Image {
+	if o == nil || o.Region.Empty() {
+		return img
+	}
+	return Crop(img, o.Region)
+}
+
+// DecodeRaw decodes the output of "screencap" without -p: a header with width, height and pixel format
+// (followed by the color space since android 12) and the pixels
+func DecodeRaw(data []byte) (*image.NRGBA, error) {
+	if len(data) < 12 {
+		return nil, fmt.Errorf("invalid screencap data: %d bytes", len(data))
+	}
+
+	width := int(binary.LittleEndian.Uint32(data[0:4]))
+	height := int(binary.LittleEndian.Uint32(data[4:8]))
+	format := PixelFormat(binary.LittleEndian.Uint32(data[8:12]))
+
+	bpp := format.BytesPerPixel()
+	if bpp == 0 {
+		return nil, fmt.Errorf("unsupported pixel format: %s", format)
+	}
+
+	// the header size depends on the android version
+	size := width * height * bpp
+	header := len(data) - size
+	if header != 12 && header != 16 {
+		return nil, fmt.Errorf("invalid screencap data: %d bytes for %dx%d %s", len(data), width, height, format)
+	}
+
+	pixels := data[header:]
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+
+	switch format {
+	case RGBA_8888:
+		copy(img.Pix, pixels)
+	case RGBX_8888:
+		copy(img.Pix, pixels)
+		for i := 3; i < len(img.Pix); i += 4 {
+			img.Pix[i] = 0xff
+		}
+	case BGRA_8888:
+		for i := 0; i < len(pixels); i += 4 {
+			img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = pixels[i+2], pixels[i+1], pixels[i], pixels[i+3]
+		}
+	case RGB_888:
+		for i, j := 0, 0; i < len(pixels); i, j = i+3, j+4 {
+			img.Pix[j], img.Pix[j+1], img.Pix[j+2], img.Pix[j+3] = pixels[i], pixels[i+1], pixels[i+2], 0xff
+		}
+	case RGB_565:
+		for i, j := 0, 0; i < len(pixels); i, j = i+2, j+4 {
+			value := binary.LittleEndian.Uint16(pixels[i:])
+			r, g, b := uint8(value>>11), uint8(value>>5&0x3f), uint8(value&0x1f)
+			img.Pix[j], img.Pix[j+1], img.Pix[j+2], img.Pix[j+3] = r<<3|r>>2, g<<2|g>>4, b<<3|b>>2, 0xff
+		}
+	}
+	return img, nil
+}
+
+// Crop returns the region of the image. The region is clipped to the image bounds
+func Crop(img image.Image, region image.Rectangle) image.Image {
+	region = region.Intersect(img.Bounds())
+	if sub, ok := img.(interface {
+		SubImage(r image.Rectangle) image.Image
+	}); ok {
+		return sub.SubImage(region)
+	}
+
+	cropped := image.NewNRGBA(region)
+	for y := region.Min.Y; y < region.Max.Y; y++ {
+		for x := region.Min.X; x < region.Max.X; x++ {
+			cropped.Set(x, y, color.NRGBAModel.Convert(img.At(x, y)))
+		}
+	}
+	return cropped
+}
+
+// Display is a physical display, as reported by "dumpsys SurfaceFlinger --display-id"
+type Display struct {
+	// Id is the id used by "screencap -d"
+	Id         string
+	HwcDisplay int
+	Name       string
+}
+
+// ParseDisplays parses the output of "dumpsys SurfaceFlinger --display-id"
+func ParseDisplays(data string) []Display {
+	var displays []Display
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if m := displayIdRegexp.FindStringSubmatch(line); m != nil {
+			display := Display{Id: m[1]}
+			display.HwcDisplay, _ = strconv.Atoi(m[2])
+			if name := displayNameRegexp.FindStringSubmatch(line); name != nil {
+				display.Name = name[1]
+			}
+			displays = append(displays, display)
+		}
+	}
+	return displays
+}
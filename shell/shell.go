@@ -425,12 +425,25 @@ func (s Shell) SendKeyEvents(source input.InputSource, event_type *input.KeyEven
 }
 
 func (s Shell) SendChar(code rune) (process.OutputResult, error) {
-	return process.SimpleOutput(s.NewCommand().WithArgs(fmt.Sprintf("input text %c", code)), s.Conn.Verbose)
+	return s.SendString(string(code))
 }
 
+// SendString types the value with "input text", escaped and split in chunks (see input.TextChunks).
+// Only printable ascii can be typed, see InputManager.SendText for unicode text
 func (s Shell) SendString(value string) (process.OutputResult, error) {
-	cmd := s.NewCommand().WithArgs(fmt.Sprintf("input text '%s'", value))
-	return process.SimpleOutput(cmd, s.Conn.Verbose)
+	if !input.IsTypeable(value) {
+		return process.OutputResult{}, fmt.Errorf("input text cannot type non ascii text: %s", value)
+	}
+
+	var result process.OutputResult
+	for _, chunk := range input.TextChunks(value, input.TextChunkSize) {
+		var err error
+		result, err = process.SimpleOutput(s.NewCommand().WithArgs("input", "text", types.ShellQuote(chunk)), s.Conn.Verbose)
+		if err != nil || !result.IsOk() {
+			return result, err
+		}
+	}
+	return result, nil
 }
 
 // GetEvents Returns a slice of Pairs each one containing the event type and the event name
@@ -535,7 +548,11 @@ func (s Shell) ListDir(dirname string) ([]types.DeviceFile, error) {
 }
 
 func (s Shell) ListSettings(namespace types.SettingsNamespace) (*properties.Properties, error) {
-	cmd := s.NewCommand().WithArgs(fmt.Sprintf("settings list %s", namespace))
+	return s.ListSettingsWithUser(namespace, "")
+}
+
+func (s Shell) ListSettingsWithUser(namespace types.SettingsNamespace, user types.UserId) (*properties.Properties, error) {
+	cmd := s.settingsCommand(user, "list", string(namespace))
 	result, err := process.SimpleOutput(cmd, s.Conn.Verbose)
 	if err != nil {
 		return nil, err
@@ -548,7 +565,11 @@ func (s Shell) ListSettings(namespace types.SettingsNamespace) (*properties.Prop
 }
 
 func (s Shell) GetSetting(key string, namespace types.SettingsNamespace) (*string, error) {
-	cmd := s.NewCommand().WithArgs(fmt.Sprintf("settings get %s %s", namespace, key))
+	return s.GetSettingWithUser(key, namespace, "")
+}
+
+func (s Shell) GetSettingWithUser(key string, namespace types.SettingsNamespace, user types.UserId) (*string, error) {
+	cmd := s.settingsCommand(user, "get", string(namespace), key)
 	result, err := process.SimpleOutput(cmd, s.Conn.Verbose)
 
 	if err != nil {
@@ -567,7 +588,11 @@ func (s Shell) GetSetting(key string, namespace types.SettingsNamespace) (*strin
 }
 
 func (s Shell) PutSetting(key string, value string, namespace types.SettingsNamespace) error {
-	cmd := s.NewCommand().WithArgs(fmt.Sprintf("settings put %s %s %s", namespace, key, value))
+	return s.PutSettingWithUser(key, value, namespace, "")
+}
+
+func (s Shell) PutSettingWithUser(key string, value string, namespace types.SettingsNamespace, user types.UserId) error {
+	cmd := s.settingsCommand(user, "put", string(namespace), key, value)
 	result, err := process.SimpleOutput(cmd, s.Conn.Verbose)
 
 	if err != nil {
@@ -582,7 +607,11 @@ func (s Shell) PutSetting(key string, value string, namespace types.SettingsName
 }
 
 func (s Shell) DeleteSetting(key string, namespace types.SettingsNamespace) error {
-	cmd := s.NewCommand().WithArgs(fmt.Sprintf("settings delete %s %s", namespace, key))
+	return s.DeleteSettingWithUser(key, namespace, "")
+}
+
+func (s Shell) DeleteSettingWithUser(key string, namespace types.SettingsNamespace, user types.UserId) error {
+	cmd := s.settingsCommand(user, "delete", string(namespace), key)
 	result, err := process.SimpleOutput(cmd, s.Conn.Verbose)
 
 	if err != nil {
@@ -596,10 +625,15 @@ func (s Shell) DeleteSetting(key string, namespace types.SettingsNamespace) erro
 	return nil
 }
 
+// settingsCommand returns the command "settings [--user user] <args>"
+func (s Shell) settingsCommand(user types.UserId, args ...string) *process.ADBCommand {
+	return s.NewCommand().WithArgs("settings").AddArgs(user.Args()...).AddArgs(args...)
+}
+
 // DumpSys is a tool that runs on Android devices and provides information about system services.
-// For a complete list of services available use ListDumpSys
-func (s Shell) DumpSys(name string) (process.OutputResult, error) {
-	return process.SimpleOutput(s.NewCommand().WithArgs("dumpsys", name), s.Conn.Verbose)
+// For a complete list of services available use ListDumpSys. The args are passed to the service (e.g. "battery", "set", "level", "5")
+func (s Shell) DumpSys(name string, args ...string) (process.OutputResult, error) {
+	return process.SimpleOutput(s.NewCommand().WithArgs("dumpsys", name).AddArgs(args...), s.Conn.Verbose)
 }
 
 // ListDumpSys return the complete list of system services that can be used with dumpsys
@@ -0,0 +1,256 @@
+package shell
+
+import (
+	"bufio"
+	"errors"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sephiroth74/go-processbuilder"
+	"github.com/sephiroth74/go_adb_client/types"
+)
+
+// inotifyd event mask used when watching a directory:
+// n=create, y=moved to, w=close write, d=delete, m=moved from, D=self delete.
+// c (modify) is left out: it's reported for each write, while w is reported once when the file is closed
+const inotifydMask = "nywdmD"
+
+type WatchEventType int
+
+const (
+	WatchCreate WatchEventType = iota
+	WatchModify
+	WatchDelete
+)
+
+func (d WatchEventType) String() string {
+	switch d {
+	case WatchCreate:
+		return "CREATE"
+	case WatchModify:
+		return "MODIFY"
+	case WatchDelete:
+		return "DELETE"
+	}
+	return "UNKNOWN"
+}
+
+type WatchEvent struct {
+	Type WatchEventType
+	// Dir is the watched directory
+	Dir string
+	// Name is the name of the file inside Dir which triggered the event.
+	// It's empty when the event refers to the watched directory itself
+	Name string
+	Time time.Time
+}
+
+// Path returns the absolute path of the file which triggered the event
+func (e WatchEvent) Path() string {
+	return path.Join(e.Dir, e.Name)
+}
+
+type WatchOptions struct {
+	// ForcePolling don't use inotifyd even if it's available on the device
+	ForcePolling bool
+	// Interval between two ListDir snapshots when polling. Default 1 second
+	Interval time.Duration
+}
+
+func NewWatchOptions() WatchOptions {
+	return WatchOptions{
+		ForcePolling: false,
+		Interval:     time.Duration(1) * time.Second,
+	}
+}
+
+// Watcher publishes the events of a watched directory.
+// Events is closed once the watcher is stopped
+type Watcher struct {
+	Events chan WatchEvent
+	// Polling is true when the watcher is using ListDir snapshots instead of inotifyd
+	Polling bool
+
+	done     chan struct{}
+	stopOnce sync.Once
+	pb       *processbuilder.Processbuilder
+}
+
+// Stop cancels the watcher and closes the Events channel
+func (w *Watcher) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.done)
+		if w.pb != nil {
+			_ = processbuilder.Cancel(w.pb)
+		}
+	})
+}
+
+func (w *Watcher) publish(event WatchEvent) bool {
+	select {
+	case <-w.done:
+		return false
+	case w.Events <- event:
+		return true
+	}
+}
+
+// Watch starts watching the given device directory for create, modify and delete events.
+// It uses inotifyd when available on the device, otherwise falls back to polling ListDir snapshots.
+// Call Stop on the returned Watcher to cancel it
+func (s Shell) Watch(dirname string, options WatchOptions) (*Watcher, error) {
+	if !s.IsDir(dirname) {
+		return nil, errors.New("not a directory: " + dirname)
+	}
+
+	if options.Interval <= 0 {
+		options.Interval = NewWatchOptions().Interval
+	}
+
+	watcher := &Watcher{
+		Events: make(chan WatchEvent),
+		done:   make(chan struct{}),
+	}
+
+	if !options.ForcePolling {
+		if inotifyd, err := s.GetCommand("inotifyd"); err == nil && inotifyd != "" {
+			if err := s.watchInotifyd(watcher, dirname); err == nil {
+				return watcher, nil
+			}
+		}
+	}
+
+	watcher.Polling = true
+	go s.watchPolling(watcher, dirname, options.Interval)
+	return watcher, nil
+}
+
+func (s Shell) watchInotifyd(watcher *Watcher, dirname string) error {
+	cmd := s.NewCommand().WithArgs("inotifyd", "-", dirname+":"+inotifydMask)
+	pb, err := processbuilder.PipeOutput(processbuilder.Option{}, cmd.ToCommand())
+	if err != nil {
+		return err
+	}
+
+	if err := processbuilder.Start(pb); err != nil {
+		return err
+	}
+
+	watcher.pb = pb
+
+	go func() {
+		defer close(watcher.Events)
+		defer watcher.Stop()
+
+		scanner := bufio.NewScanner(pb.StdoutPipe)
+		for scanner.Scan() {
+			event, ok := parseInotifydLine(scanner.Text())
+			if !ok {
+				continue
+			}
+			if !watcher.publish(event) {
+				break
+			}
+		}
+		_, _, _ = processbuilder.Wait(pb)
+	}()
+	return nil
+}
+
+func (s Shell) watchPolling(watcher *Watcher, dirname string, interval time.Duration) {
+	defer close(watcher.Events)
+
+	// a failed snapshot is skipped and the previous one is kept. previous is nil until a snapshot succeeds
+	previous, _ := s.watchSnapshot(dirname)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-watcher.done:
+			return
+		case <-ticker.C:
+		}
+
+		current, err := s.watchSnapshot(dirname)
+		if err != nil {
+			continue
+		}
+		if previous == nil {
+			previous = current
+			continue
+		}
+
+		for _, event := range diffSnapshots(dirname, previous, current) {
+			if !watcher.publish(event) {
+				return
+			}
+		}
+		previous = current
+	}
+}
+
+func (s Shell) watchSnapshot(dirname string) (map[string]types.DeviceFile, error) {
+	files, err := s.ListDir(dirname)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := make(map[string]types.DeviceFile)
+	for _, file := range files {
+		name := strings.TrimSuffix(file.Name, "/")
+		if name == "." || name == ".." {
+			continue
+		}
+		snapshot[name] = file
+	}
+	return snapshot, nil
+}
+
+func diffSnapshots(dirname string, previous map[string]types.DeviceFile, current map[string]types.DeviceFile) []WatchEvent {
+	var events []WatchEvent
+	now := time.Now()
+
+	for name, file := range current {
+		old, found := previous[name]
+		if !found {
+			events = append(events, WatchEvent{Type: WatchCreate, Dir: dirname, Name: name, Time: now})
+		} else if old.Size != file.Size || !old.DateTime.Equal(file.DateTime) {
+			events = append(events, WatchEvent{Type: WatchModify, Dir: dirname, Name: name, Time: now})
+		}
+	}
+
+	for name := range previous {
+		if _, found := current[name]; !found {
+			events = append(events, WatchEvent{Type: WatchDelete, Dir: dirname, Name: name, Time: now})
+		}
+	}
+	return events
+}
+
+// parseInotifydLine parses a line printed by "inotifyd -", in the format EVENT\tFILE[\tSUBFILE]
+func parseInotifydLine(line string) (WatchEvent, bool) {
+	fields := strings.Split(strings.TrimRight(line, "\r"), "\t")
+	if len(fields) < 2 || len(fields[0]) == 0 {
+		return WatchEvent{}, false
+	}
+
+	event := WatchEvent{Dir: fields[1], Time: time.Now()}
+	if len(fields) > 2 {
+		event.Name = fields[2]
+	}
+
+	switch fields[0][0] {
+	case 'n', 'y':
+		event.Type = WatchCreate
+	case 'w':
+		event.Type = WatchModify
+	case 'd', 'm', 'D':
+		event.Type = WatchDelete
+	default:
+		return WatchEvent{}, false
+	}
+	return event, true
+}
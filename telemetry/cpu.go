@@ -0,0 +1,261 @@
+package telemetry
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	cpuLoadRegexp    = regexp.MustCompile(`^Load:\s*([\d.]+)\s*/\s*([\d.]+)\s*/\s*([\d.]+)`)
+	cpuProcessRegexp = regexp.MustCompile(`^\+?([\d.]+)% (\d+)/(.+?): ([\d.]+)% user \+ ([\d.]+)% kernel`)
+	cpuTotalRegexp   = regexp.MustCompile(`^([\d.]+)% TOTAL: (.*)$`)
+	cpuPartRegexp    = regexp.MustCompile(`([\d.]+)% (\w+)`)
+)
+
+// region CpuTimes
+
+// CpuTimes are the cpu times in jiffies of a "cpu" line of /proc/stat
+type CpuTimes struct {
+	User    uint64 `json:"user"`
+	Nice    uint64 `json:"nice"`
+	System  uint64 `json:"system"`
+	Idle    uint64 `json:"idle"`
+	IOWait  uint64 `json:"iowait"`
+	Irq     uint64 `json:"irq"`
+	SoftIrq uint64 `json:"softirq"`
+	Steal   uint64 `json:"steal"`
+}
+
+func (c CpuTimes) Total() uint64 {
+	return c.User + c.Nice + c.System + c.Idle + c.IOWait + c.Irq + c.SoftIrq + c.Steal
+}
+
+// Busy returns the non idle time
+func (c CpuTimes) Busy() uint64 {
+	return c.Total() - c.Idle - c.IOWait
+}
+
+// CpuStat is the content of /proc/stat
+type CpuStat struct {
+	// Total is the aggregate of all the cpus
+	Total CpuTimes
+	// Cores are the times of each cpu (cpu0, cpu1, ...)
+	Cores []CpuTimes
+}
+
+// ParseCpuStat parses the content of /proc/stat
+func ParseCpuStat(data string) (*CpuStat, error) {
+	stat := &CpuStat{}
+	found := false
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 5 || !strings.HasPrefix(fields[0], "cpu") {
+			continue
+		}
+
+		var values [8]uint64
+		for i := 0; i < len(values) && i+1 < len(fields); i++ {
+			values[i], _ = strconv.ParseUint(fields[i+1], 10, 64)
+		}
+		times := CpuTimes{values[0], values[1], values[2], values[3], values[4], values[5], values[6], values[7]}
+
+		if fields[0] == "cpu" {
+			stat.Total = times
+			found = true
+		} else {
+			stat.Cores = append(stat.Cores, times)
+		}
+	}
+
+	if !found {
+		return nil, fmt.Errorf("invalid /proc/stat: %s", data)
+	}
+	return stat, nil
+}
+
+// CpuUsage is the cpu usage between two /proc/stat snapshots, in percent of the total cpu capacity
+type CpuUsage struct {
+	Total  float64 `json:"total"`
+	User   float64 `json:"user"`
+	System float64 `json:"system"`
+	IOWait float64 `json:"iowait"`
+	Irq    float64 `json:"irq"`
+	// Cores is the busy percent of each cpu
+	Cores []float64 `json:"cores,omitempty"`
+}
+
+// NewCpuUsage computes the cpu usage between the two snapshots
+func NewCpuUsage(previous *CpuStat, current *CpuStat) CpuUsage {
+	usage := CpuUsage{}
+	delta := float64(diff(previous.Total.Total(), current.Total.Total()))
+	if delta <= 0 {
+		return usage
+	}
+
+	usage.Total = percent(diff(previous.Total.Busy(), current.Total.Busy()), delta)
+	usage.User = percent(diff(previous.Total.User+previous.Total.Nice, current.Total.User+current.Total.Nice), delta)
+	usage.System = percent(diff(previous.Total.System, current.Total.System), delta)
+	usage.IOWait = percent(diff(previous.Total.IOWait, current.Total.IOWait), delta)
+	usage.Irq = percent(diff(previous.Total.Irq+previous.Total.SoftIrq, current.Total.Irq+current.Total.SoftIrq), delta)
+
+	for i := range current.Cores {
+		if i >= len(previous.Cores) {
+			break
+		}
+		coreDelta := float64(diff(previous.Cores[i].Total(), current.Cores[i].Total()))
+		if coreDelta <= 0 {
+			usage.Cores = append(usage.Cores, 0)
+			continue
+		}
+		usage.Cores = append(usage.Cores, percent(diff(previous.Cores[i].Busy(), current.Cores[i].Busy()), coreDelta))
+	}
+	return usage
+}
+
+// diff returns the growth of a counter, 0 if the counter has been reset (e.g. a cpu went offline)
+func diff(previous uint64, current uint64) uint64 {
+	if current < previous {
+		return 0
+	}
+	return current - previous
+}
+
+func percent(value uint64, total float64) float64 {
+	return float64(value) * 100 / total
+}
+
+// endregion CpuTimes
+
+// region ProcessStat
+
+// ProcessStat is the content of /proc/<pid>/stat
+type ProcessStat struct {
+	Pid   int
+	Name  string
+	State string
+	// UserTime and SystemTime are in jiffies
+	UserTime   uint64
+	SystemTime uint64
+	Threads    int
+	// RssPages is the resident set size, in pages
+	RssPages int64
+}
+
+// ParseProcessStat parses the content of /proc/<pid>/stat
+func ParseProcessStat(data string) (*ProcessStat, error) {
+	data = strings.TrimSpace(data)
+	start := strings.Index(data, "(")
+	end := strings.LastIndex(data, ")")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("invalid process stat: %s", data)
+	}
+
+	// the fields after the name, starting from the state (field 3)
+	fields := strings.Fields(data[end+1:])
+	if len(fields) < 22 {
+		return nil, fmt.Errorf("invalid process stat: %s", data)
+	}
+
+	stat := &ProcessStat{Name: data[start+1 : end], State: fields[0]}
+	stat.Pid, _ = strconv.Atoi(strings.TrimSpace(data[:start]))
+	stat.UserTime, _ = strconv.ParseUint(fields[11], 10, 64)
+	stat.SystemTime, _ = strconv.ParseUint(fields[12], 10, 64)
+	stat.Threads, _ = strconv.Atoi(fields[17])
+	stat.RssPages, _ = strconv.ParseInt(fields[21], 10, 64)
+	return stat, nil
+}
+
+// ProcessCpu is the cpu usage of a process between two samples
+type ProcessCpu struct {
+	Pid  int    `json:"pid"`
+	Name string `json:"name"`
+	// Cpu is the percent of the total cpu capacity used by the process
+	Cpu     float64 `json:"cpu"`
+	User    float64 `json:"user"`
+	System  float64 `json:"system"`
+	Threads int     `json:"threads"`
+}
+
+// NewProcessCpu computes the cpu usage of the process between the two snapshots, totalDelta
+// being the jiffies elapsed on all the cpus (see CpuTimes.Total)
+func NewProcessCpu(previous *ProcessStat, current *ProcessStat, totalDelta uint64) ProcessCpu {
+	usage := ProcessCpu{Pid: current.Pid, Name: current.Name, Threads: current.Threads}
+	if totalDelta == 0 || current.UserTime < previous.UserTime || current.SystemTime < previous.SystemTime {
+		return usage
+	}
+
+	usage.User = percent(current.UserTime-previous.UserTime, float64(totalDelta))
+	usage.System = percent(current.SystemTime-previous.SystemTime, float64(totalDelta))
+	usage.Cpu = usage.User + usage.System
+	return usage
+}
+
+// endregion ProcessStat
+
+// region CpuInfo
+
+// ProcessLoad is the cpu load of a process, as reported by "dumpsys cpuinfo"
+type ProcessLoad struct {
+	Pid    int     `json:"pid"`
+	Name   string  `json:"name"`
+	Total  float64 `json:"total"`
+	User   float64 `json:"user"`
+	Kernel float64 `json:"kernel"`
+}
+
+// CpuInfo is the output of "dumpsys cpuinfo"
+type CpuInfo struct {
+	Load1  float64 `json:"load1"`
+	Load5  float64 `json:"load5"`
+	Load15 float64 `json:"load15"`
+	Total  float64 `json:"total"`
+	// TotalParts are the parts of the total (user, kernel, iowait, irq, softirq)
+	TotalParts map[string]float64 `json:"totalParts,omitempty"`
+	Processes  []ProcessLoad      `json:"processes,omitempty"`
+}
+
+// ParseCpuInfo parses the output of "dumpsys cpuinfo"
+func ParseCpuInfo(data string) (*CpuInfo, error) {
+	info := &CpuInfo{TotalParts: map[string]float64{}}
+	found := false
+
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+
+		if m := cpuLoadRegexp.FindStringSubmatch(line); m != nil {
+			info.Load1, _ = strconv.ParseFloat(m[1], 64)
+			info.Load5, _ = strconv.ParseFloat(m[2], 64)
+			info.Load15, _ = strconv.ParseFloat(m[3], 64)
+			found = true
+			continue
+		}
+
+		if m := cpuTotalRegexp.FindStringSubmatch(line); m != nil {
+			info.Total, _ = strconv.ParseFloat(m[1], 64)
+			for _, part := range cpuPartRegexp.FindAllStringSubmatch(m[2], -1) {
+				info.TotalParts[part[2]], _ = strconv.ParseFloat(part[1], 64)
+			}
+			found = true
+			continue
+		}
+
+		if m := cpuProcessRegexp.FindStringSubmatch(line); m != nil {
+			load := ProcessLoad{Name: m[3]}
+			load.Pid, _ = strconv.Atoi(m[2])
+			load.Total, _ = strconv.ParseFloat(m[1], 64)
+			load.User, _ = strconv.ParseFloat(m[4], 64)
+			load.Kernel, _ = strconv.ParseFloat(m[5], 64)
+			info.Processes = append(info.Processes, load)
+			found = true
+		}
+	}
+
+	if !found {
+		return nil, fmt.Errorf("invalid cpuinfo: %s", data)
+	}
+	return info, nil
+}
+
+// endregion CpuInfo
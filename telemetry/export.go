@@ -0,0 +1,152 @@
+package telemetry
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Metric is a single named value of a sample
+type Metric struct {
+	Name  string
+	Value float64
+}
+
+// Metrics returns the values of the sample as a flat list of metrics, e.g. cpu.total,
+// process.<name>.<pid>.cpu, battery.level, thermal.<name>
+func (s Sample) Metrics() []Metric {
+	var metrics []Metric
+	add := func(name string, value float64) {
+		metrics = append(metrics, Metric{Name: name, Value: value})
+	}
+
+	if s.Cpu != nil {
+		add("cpu.total", s.Cpu.Total)
+		add("cpu.user", s.Cpu.User)
+		add("cpu.system", s.Cpu.System)
+		add("cpu.iowait", s.Cpu.IOWait)
+		add("cpu.irq", s.Cpu.Irq)
+		for i, core := range s.Cpu.Cores {
+			add(fmt.Sprintf("cpu.core%d", i), core)
+		}
+	}
+
+	for _, p := range s.Processes {
+		prefix := fmt.Sprintf("process.%s.%d", p.Name, p.Pid)
+		add(prefix+".cpu", p.Cpu)
+		add(prefix+".user", p.User)
+		add(prefix+".system", p.System)
+		add(prefix+".threads", float64(p.Threads))
+	}
+
+	if s.CpuInfo != nil {
+		add("cpuinfo.load1", s.CpuInfo.Load1)
+		add("cpuinfo.load5", s.CpuInfo.Load5)
+		add("cpuinfo.load15", s.CpuInfo.Load15)
+		add("cpuinfo.total", s.CpuInfo.Total)
+	}
+
+	if s.Battery != nil {
+		add("battery.level", float64(s.Battery.Percent()))
+		add("battery.temperature", s.Battery.Temperature)
+		add("battery.voltage", float64(s.Battery.Voltage))
+		add("battery.status", float64(s.Battery.Status))
+	}
+
+	if s.Thermal != nil {
+		add("thermal.status", float64(s.Thermal.Status))
+		for _, temperature := range s.Thermal.Temperatures {
+			add("thermal."+temperature.Name, temperature.Value)
+		}
+		for _, zone := range s.Thermal.Zones {
+			add("thermal."+zone.Zone+"."+zone.Type, zone.Value)
+		}
+	}
+	return metrics
+}
+
+// region Exporter
+
+// Exporter writes the samples to a destination
+type Exporter interface {
+	Export(sample Sample) error
+	// Flush writes any buffered data
+	Flush() error
+}
+
+// CSVExporter writes the metrics of the samples as csv, one row for each metric: time,metric,value.
+// The long format allows the metrics to change between samples (e.g. a process restarted)
+type CSVExporter struct {
+	writer *csv.Writer
+	header bool
+}
+
+func NewCSVExporter(w io.Writer) *CSVExporter {
+	return &CSVExporter{writer: csv.NewWriter(w)}
+}
+
+func (e *CSVExporter) Export(sample Sample) error {
+	if !e.header {
+		if err := e.writer.Write([]string{"time", "metric", "value"}); err != nil {
+			return err
+		}
+		e.header = true
+	}
+
+	timestamp := sample.Time.Format(time.RFC3339Nano)
+	for _, metric := range sample.Metrics() {
+		if err := e.writer.Write([]string{timestamp, metric.Name, strconv.FormatFloat(metric.Value, 'f', -1, 64)}); err != nil {
+			return err
+		}
+	}
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+func (e *CSVExporter) Flush() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+// JSONExporter writes the samples as json lines, one object for each sample
+type JSONExporter struct {
+	encoder *json.Encoder
+}
+
+func NewJSONExporter(w io.Writer) *JSONExporter {
+	return &JSONExporter{encoder: json.NewEncoder(w)}
+}
+
+func (e *JSONExporter) Export(sample Sample) error {
+	return e.encoder.Encode(sample)
+}
+
+func (e *JSONExporter) Flush() error {
+	return nil
+}
+
+// Export writes the samples to all the exporters until the channel is closed (see Sampler.Stop).
+// The errors of the exporters are returned once the channel is closed
+func Export(samples <-chan Sample, exporters ...Exporter) error {
+	var errs []error
+	for sample := range samples {
+		for _, exporter := range exporters {
+			if err := exporter.Export(sample); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	for _, exporter := range exporters {
+		if err := exporter.Flush(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// endregion Exporter
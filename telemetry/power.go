@@ -0,0 +1,234 @@
+package telemetry
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	temperatureRegexp   = regexp.MustCompile(`Temperature\{mValue=(-?[\d.]+|NaN), mType=(-?\d+), mName=([^,]+), mStatus=(-?\d+)\}`)
+	thermalStatusRegexp = regexp.MustCompile(`^Thermal Status:\s*(\d+)`)
+)
+
+// region Battery
+
+// BatteryStatus is the battery status (BatteryManager.BATTERY_STATUS_*)
+type BatteryStatus int
+
+const (
+	BatteryStatusUnknown     BatteryStatus = 1
+	BatteryStatusCharging    BatteryStatus = 2
+	BatteryStatusDischarging BatteryStatus = 3
+	BatteryStatusNotCharging BatteryStatus = 4
+	BatteryStatusFull        BatteryStatus = 5
+)
+
+func (s BatteryStatus) String() string {
+	switch s {
+	case BatteryStatusCharging:
+		return "charging"
+	case BatteryStatusDischarging:
+		return "discharging"
+	case BatteryStatusNotCharging:
+		return "not-charging"
+	case BatteryStatusFull:
+		return "full"
+	}
+	return "unknown"
+}
+
+// BatteryInfo is the output of "dumpsys battery"
+type BatteryInfo struct {
+	AcPowered       bool          `json:"acPowered"`
+	UsbPowered      bool          `json:"usbPowered"`
+	WirelessPowered bool          `json:"wirelessPowered"`
+	Present         bool          `json:"present"`
+	Status          BatteryStatus `json:"status"`
+	Health          int           `json:"health"`
+	Level           int           `json:"level"`
+	Scale           int           `json:"scale"`
+	// Voltage is in millivolts
+	Voltage int `json:"voltage"`
+	// Temperature is in degrees celsius
+	Temperature float64 `json:"temperature"`
+	Technology  string  `json:"technology"`
+}
+
+func (b BatteryInfo) String() string {
+	return fmt.Sprintf("BatteryInfo{Level:%d%%, Status:%s, Temperature:%.1f, Present:%t}", b.Percent(), b.Status, b.Temperature, b.Present)
+}
+
+// Percent returns the battery level in percent
+func (b BatteryInfo) Percent() int {
+	if b.Scale <= 0 {
+		return b.Level
+	}
+	return b.Level * 100 / b.Scale
+}
+
+// ParseBattery parses the output of "dumpsys battery"
+func ParseBattery(data string) (*BatteryInfo, error) {
+	info := &BatteryInfo{}
+	found := false
+
+	for _, line := range strings.Split(data, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		number, _ := strconv.Atoi(value)
+
+		switch key {
+		case "AC powered":
+			info.AcPowered = value == "true"
+		case "USB powered":
+			info.UsbPowered = value == "true"
+		case "Wireless powered":
+			info.WirelessPowered = value == "true"
+		case "present":
+			info.Present = value == "true"
+		case "status":
+			info.Status = BatteryStatus(number)
+		case "health":
+			info.Health = number
+		case "level":
+			info.Level = number
+			found = true
+		case "scale":
+			info.Scale = number
+		case "voltage":
+			info.Voltage = number
+		case "temperature":
+			// reported in tenths of degree
+			info.Temperature = float64(number) / 10
+		case "technology":
+			info.Technology = value
+		}
+	}
+
+	if !found {
+		return nil, fmt.Errorf("invalid battery state: %s", data)
+	}
+	return info, nil
+}
+
+// endregion Battery
+
+// region Thermal
+
+// Temperature is a temperature reported by the thermal HAL
+type Temperature struct {
+	Name string `json:"name"`
+	// Type is the sensor type (Temperature.TYPE_*: 0 cpu, 1 gpu, 2 battery, 3 skin, ...)
+	Type int `json:"type"`
+	// Value is in degrees celsius
+	Value float64 `json:"value"`
+	// Status is the throttling status of the sensor, see ThermalInfo.Status
+	Status int `json:"status"`
+}
+
+// ThermalZone is a thermal zone of /sys/class/thermal
+type ThermalZone struct {
+	Zone string `json:"zone"`
+	Type string `json:"type"`
+	// Value is in degrees celsius
+	Value float64 `json:"value"`
+}
+
+// ThermalInfo is the thermal state of the device
+type ThermalInfo struct {
+	// Status is the thermal throttling status (PowerManager.THERMAL_STATUS_*: 0 none, 1 light ... 6 shutdown),
+	// -1 if not reported
+	Status       int           `json:"status"`
+	Temperatures []Temperature `json:"temperatures,omitempty"`
+	Zones        []ThermalZone `json:"zones,omitempty"`
+}
+
+// MaxTemperature returns the highest temperature reported, by the HAL or by the thermal zones
+func (t ThermalInfo) MaxTemperature() float64 {
+	var max float64
+	for _, temperature := range t.Temperatures {
+		if temperature.Value > max {
+			max = temperature.Value
+		}
+	}
+	for _, zone := range t.Zones {
+		if zone.Value > max {
+			max = zone.Value
+		}
+	}
+	return max
+}
+
+// ParseThermalService parses the output of "dumpsys thermalservice". The current temperatures
+// from the HAL are returned, or the cached temperatures if the HAL doesn't report them. The unavailable sensors (NaN) are skipped
+func ParseThermalService(data string) *ThermalInfo {
+	info := &ThermalInfo{Status: -1}
+	var cached []Temperature
+	section := ""
+
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+
+		if m := thermalStatusRegexp.FindStringSubmatch(line); m != nil {
+			info.Status, _ = strconv.Atoi(m[1])
+			continue
+		}
+
+		if strings.HasSuffix(line, ":") {
+			section = line
+			continue
+		}
+
+		// the HAL reports NaN for the unavailable sensors
+		m := temperatureRegexp.FindStringSubmatch(line)
+		if m == nil || m[1] == "NaN" {
+			continue
+		}
+
+		temperature := Temperature{Name: m[3]}
+		temperature.Value, _ = strconv.ParseFloat(m[1], 64)
+		temperature.Type, _ = strconv.Atoi(m[2])
+		temperature.Status, _ = strconv.Atoi(m[4])
+
+		switch {
+		case strings.HasPrefix(section, "Current temperatures"):
+			info.Temperatures = append(info.Temperatures, temperature)
+		case strings.HasPrefix(section, "Cached temperatures"):
+			cached = append(cached, temperature)
+		}
+	}
+
+	if len(info.Temperatures) == 0 {
+		info.Temperatures = cached
+	}
+	return info
+}
+
+// ParseThermalZones parses the lines "<zone> <type> <temp>" printed by thermalZonesScript
+func ParseThermalZones(data string) []ThermalZone {
+	var zones []ThermalZone
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+
+		value, err := strconv.ParseFloat(fields[2], 64)
+		if err != nil {
+			continue
+		}
+
+		// the temperature is usually reported in millidegrees
+		if value > 1000 || value < -1000 {
+			value /= 1000
+		}
+		zones = append(zones, ThermalZone{Zone: fields[0][strings.LastIndex(fields[0], "/")+1:], Type: fields[1], Value: value})
+	}
+	return zones
+}
+
+// endregion Thermal
@@ -0,0 +1,299 @@
+package telemetry
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sephiroth74/go_adb_client/process"
+	"github.com/sephiroth74/go_adb_client/shell"
+)
+
+// thermalZonesScript prints the type and the temperature of each thermal zone
+const thermalZonesScript = `for z in /sys/class/thermal/thermal_zone*; do echo "$z $(cat $z/type) $(cat $z/temp)"; done 2>/dev/null`
+
+type Telemetry struct {
+	Shell *shell.Shell
+}
+
+// region Options
+
+type Options struct {
+	// Interval between two samples. Default 1 second
+	Interval time.Duration
+	// Pids are the processes whose cpu usage is sampled
+	Pids []int
+	// Processes are the process names whose cpu usage is sampled, resolved with pidof at each sample
+	Processes []string
+	// Cpu samples /proc/stat
+	Cpu bool
+	// CpuInfo samples "dumpsys cpuinfo", which is more expensive than /proc/stat
+	CpuInfo bool
+	// Battery samples "dumpsys battery"
+	Battery bool
+	// Thermal samples "dumpsys thermalservice"
+	Thermal bool
+	// ThermalZones samples /sys/class/thermal
+	ThermalZones bool
+}
+
+func NewOptions() Options {
+	return Options{
+		Interval:     time.Duration(1) * time.Second,
+		Cpu:          true,
+		Battery:      true,
+		Thermal:      true,
+		ThermalZones: true,
+	}
+}
+
+// endregion Options
+
+// region Sample
+
+// Sample is a telemetry sample. The values not sampled, or which failed, are nil and the failures are listed in Errors.
+// The cpu usages are computed since the previous sample, so they are not reported by the first sample
+type Sample struct {
+	Time      time.Time    `json:"time"`
+	Cpu       *CpuUsage    `json:"cpu,omitempty"`
+	Processes []ProcessCpu `json:"processes,omitempty"`
+	CpuInfo   *CpuInfo     `json:"cpuInfo,omitempty"`
+	Battery   *BatteryInfo `json:"battery,omitempty"`
+	Thermal   *ThermalInfo `json:"thermal,omitempty"`
+	Errors    []string     `json:"errors,omitempty"`
+}
+
+func (s Sample) String() string {
+	return fmt.Sprintf("Sample{Time:%s, Cpu:%v, Processes:%d, Battery:%v, Errors:%d}", s.Time.Format(time.RFC3339), s.Cpu, len(s.Processes), s.Battery, len(s.Errors))
+}
+
+func (s *Sample) addError(err error) {
+	s.Errors = append(s.Errors, err.Error())
+}
+
+// endregion Sample
+
+// region Sampler
+
+// Sampler publishes the telemetry samples.
+// Samples is closed once the sampler is stopped
+type Sampler struct {
+	Samples chan Sample
+
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// Stop cancels the sampler and closes the Samples channel
+func (s *Sampler) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.done)
+	})
+}
+
+// Start samples the telemetry every options.Interval and publishes the samples.
+// Call Stop on the returned sampler to cancel it
+func (t Telemetry) Start(options Options) *Sampler {
+	if options.Interval <= 0 {
+		options.Interval = NewOptions().Interval
+	}
+
+	sampler := &Sampler{
+		Samples: make(chan Sample),
+		done:    make(chan struct{}),
+	}
+
+	go func() {
+		defer close(sampler.Samples)
+
+		ticker := time.NewTicker(options.Interval)
+		defer ticker.Stop()
+
+		state := &sampleState{processes: map[int]*ProcessStat{}}
+		for {
+			sample := t.sample(options, state)
+
+			select {
+			case <-sampler.done:
+				return
+			case sampler.Samples <- sample:
+			}
+
+			select {
+			case <-sampler.done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return sampler
+}
+
+// sampleState holds the previous snapshots, used to compute the cpu usages
+type sampleState struct {
+	cpu       *CpuStat
+	processes map[int]*ProcessStat
+}
+
+func (t Telemetry) sample(options Options, state *sampleState) Sample {
+	sample := Sample{Time: time.Now()}
+
+	var totalDelta uint64
+	if options.Cpu || len(options.Pids) > 0 || len(options.Processes) > 0 {
+		stat, err := t.GetCpuStat()
+		if err != nil {
+			sample.addError(err)
+		} else {
+			if state.cpu != nil {
+				totalDelta = diff(state.cpu.Total.Total(), stat.Total.Total())
+				if options.Cpu {
+					usage := NewCpuUsage(state.cpu, stat)
+					sample.Cpu = &usage
+				}
+			}
+			state.cpu = stat
+		}
+	}
+
+	pids, names := t.resolvePids(options)
+	current := map[int]*ProcessStat{}
+	for _, pid := range pids {
+		stat, err := t.GetProcessStat(pid)
+		if err != nil {
+			sample.addError(err)
+			continue
+		}
+		current[pid] = stat
+
+		if previous, ok := state.processes[pid]; ok && totalDelta > 0 {
+			usage := NewProcessCpu(previous, stat, totalDelta)
+			if name, ok := names[pid]; ok {
+				usage.Name = name
+			}
+			sample.Processes = append(sample.Processes, usage)
+		}
+	}
+	state.processes = current
+
+	if options.CpuInfo {
+		if info, err := t.GetCpuInfo(); err != nil {
+			sample.addError(err)
+		} else {
+			sample.CpuInfo = info
+		}
+	}
+
+	if options.Battery {
+		if info, err := t.GetBattery(); err != nil {
+			sample.addError(err)
+		} else {
+			sample.Battery = info
+		}
+	}
+
+	if options.Thermal || options.ThermalZones {
+		sample.Thermal = &ThermalInfo{Status: -1}
+		if options.Thermal {
+			if info, err := t.GetThermalService(); err != nil {
+				sample.addError(err)
+			} else {
+				sample.Thermal = info
+			}
+		}
+		if options.ThermalZones {
+			if zones, err := t.GetThermalZones(); err != nil {
+				sample.addError(err)
+			} else {
+				sample.Thermal.Zones = zones
+			}
+		}
+	}
+	return sample
+}
+
+// resolvePids returns the pids to sample and the names of the processes resolved by name
+func (t Telemetry) resolvePids(options Options) ([]int, map[int]string) {
+	pids := append([]int{}, options.Pids...)
+	names := map[int]string{}
+	for _, name := range options.Processes {
+		result, err := t.execute("pidof", name)
+		if err != nil {
+			// pidof fails when the process is not running
+			continue
+		}
+		for _, field := range strings.Fields(result) {
+			if pid, err := strconv.Atoi(field); err == nil {
+				pids = append(pids, pid)
+				names[pid] = name
+			}
+		}
+	}
+	return pids, names
+}
+
+// endregion Sampler
+
+// GetCpuStat returns the content of /proc/stat
+func (t Telemetry) GetCpuStat() (*CpuStat, error) {
+	result, err := t.execute("cat", "/proc/stat")
+	if err != nil {
+		return nil, err
+	}
+	return ParseCpuStat(result)
+}
+
+// GetProcessStat returns the content of /proc/<pid>/stat
+func (t Telemetry) GetProcessStat(pid int) (*ProcessStat, error) {
+	result, err := t.execute("cat", fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return nil, err
+	}
+	return ParseProcessStat(result)
+}
+
+// GetCpuInfo returns the cpu load, parsed from "dumpsys cpuinfo"
+func (t Telemetry) GetCpuInfo() (*CpuInfo, error) {
+	result, err := t.execute("dumpsys", "cpuinfo")
+	if err != nil {
+		return nil, err
+	}
+	return ParseCpuInfo(result)
+}
+
+// GetBattery returns the battery state, parsed from "dumpsys battery"
+func (t Telemetry) GetBattery() (*BatteryInfo, error) {
+	result, err := t.execute("dumpsys", "battery")
+	if err != nil {
+		return nil, err
+	}
+	return ParseBattery(result)
+}
+
+// GetThermalService returns the thermal state, parsed from "dumpsys thermalservice" (android 10+)
+func (t Telemetry) GetThermalService() (*ThermalInfo, error) {
+	result, err := t.execute("dumpsys", "thermalservice")
+	if err != nil {
+		return nil, err
+	}
+	return ParseThermalService(result), nil
+}
+
+// GetThermalZones returns the temperatures of /sys/class/thermal
+func (t Telemetry) GetThermalZones() ([]ThermalZone, error) {
+	result, err := t.execute(thermalZonesScript)
+	if err != nil {
+		return nil, err
+	}
+	return ParseThermalZones(result), nil
+}
+
+// execute runs the command and returns its output, or an error if it fails
+func (t Telemetry) execute(args ...string) (string, error) {
+	result, err := t.Shell.Execute(args[0], args[1:]...)
+	if err := process.Check(result, err); err != nil {
+		return "", err
+	}
+	return result.Output(), nil
+}
@@ -0,0 +1,761 @@
+package types
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const ActionView = "android.intent.action.VIEW"
+
+var shellSafeRegexp = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// ShellQuote quotes the argument for the device shell, if needed
+func ShellQuote(arg string) string {
+	if shellSafeRegexp.MatchString(arg) {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+// region IntentFlags
+
+// IntentFlags are the flags of an intent (Intent.FLAG_*), passed to am with -f
+type IntentFlags int32
+
+const (
+	FlagGrantReadUriPermission        IntentFlags = 0x00000001
+	FlagGrantWriteUriPermission       IntentFlags = 0x00000002
+	FlagFromBackground                IntentFlags = 0x00000004
+	FlagDebugLogResolution            IntentFlags = 0x00000008
+	FlagExcludeStoppedPackages        IntentFlags = 0x00000010
+	FlagIncludeStoppedPackages        IntentFlags = 0x00000020
+	FlagGrantPersistableUriPermission IntentFlags = 0x00000040
+	FlagGrantPrefixUriPermission      IntentFlags = 0x00000080
+
+	FlagActivityRequireDefault      IntentFlags = 0x00000200
+	FlagActivityRequireNonBrowser   IntentFlags = 0x00000400
+	FlagActivityMatchExternal       IntentFlags = 0x00000800
+	FlagActivityLaunchAdjacent      IntentFlags = 0x00001000
+	FlagActivityRetainInRecents     IntentFlags = 0x00002000
+	FlagActivityTaskOnHome          IntentFlags = 0x00004000
+	FlagActivityClearTask           IntentFlags = 0x00008000
+	FlagActivityNoAnimation         IntentFlags = 0x00010000
+	FlagActivityReorderToFront      IntentFlags = 0x00020000
+	FlagActivityNoUserAction        IntentFlags = 0x00040000
+	FlagActivityNewDocument         IntentFlags = 0x00080000
+	FlagActivityLaunchedFromHistory IntentFlags = 0x00100000
+	FlagActivityResetTaskIfNeeded   IntentFlags = 0x00200000
+	FlagActivityBroughtToFront      IntentFlags = 0x00400000
+	FlagActivityExcludeFromRecents  IntentFlags = 0x00800000
+	FlagActivityPreviousIsTop       IntentFlags = 0x01000000
+	FlagActivityForwardResult       IntentFlags = 0x02000000
+	FlagActivityClearTop            IntentFlags = 0x04000000
+	FlagActivityMultipleTask        IntentFlags = 0x08000000
+	FlagActivityNewTask             IntentFlags = 0x10000000
+	FlagActivitySingleTop           IntentFlags = 0x20000000
+	FlagActivityNoHistory           IntentFlags = 0x40000000
+	FlagActivityClearWhenTaskReset              = FlagActivityNewDocument
+
+	FlagReceiverNoAbort           IntentFlags = 0x08000000
+	FlagReceiverForeground        IntentFlags = 0x10000000
+	FlagReceiverReplacePending    IntentFlags = 0x20000000
+	FlagReceiverRegisteredOnly    IntentFlags = 0x40000000
+	FlagReceiverIncludeBackground IntentFlags = 0x01000000
+)
+
+// Has returns true if all the given flags are set
+func (f IntentFlags) Has(flags IntentFlags) bool {
+	return f&flags == flags
+}
+
+func (f IntentFlags) String() string {
+	return fmt.Sprintf("0x%08x", uint32(f))
+}
+
+// endregion IntentFlags
+
+// region Intent
+
+type Intent struct {
+	Action     string
+	Data       string
+	MimeType   string
+	Categories []string
+	Component  string
+	Package    string
+	// Selector is the --selector intent, used to resolve the activity instead of this intent
+	Selector           *Intent
+	ReceiverForeground bool
+	Flags              IntentFlags
+	Extra              Extras
+	UserId             UserId
+	Wait               bool
+}
+
+type Extras struct {
+	Es map[string]string
+	// Esn are the keys of the null string extras
+	Esn []string
+	Ez  map[string]bool
+	// Eb are the byte extras
+	Eb map[string]int8
+	Ei map[string]int
+	El map[string]int64
+	Ef map[string]float32
+	// Ed are the double extras
+	Ed  map[string]float64
+	Eu  map[string]string
+	Ecn map[string]string
+	Eia map[string][]int
+	Ela map[string][]int64
+	Efa map[string][]float32
+	Esa map[string][]string
+	// Eial, Elal, Efal and Esal are passed as ArrayList instead of arrays
+	Eial                    map[string][]int
+	Elal                    map[string][]int64
+	Efal                    map[string][]float32
+	Esal                    map[string][]string
+	GrantReadUriPermission  bool
+	GrantWriteUriPermission bool
+	ExcludeStoppedPackages  bool
+	IncludeStoppedPackages  bool
+}
+
+// Args returns the am arguments of the intent. The arguments are not quoted, see ShellArgs
+func (i Intent) Args() []string {
+	args := i.intentArgs()
+
+	if i.Selector != nil {
+		args = append(args, "--selector")
+		args = append(args, i.Selector.intentArgs()...)
+	}
+
+	args = append(args, i.UserId.Args()...)
+
+	if i.Wait {
+		args = append(args, "-W")
+	}
+	return args
+}
+
+// ShellArgs returns the am arguments of the intent, quoted for the device shell
+func (i Intent) ShellArgs() []string {
+	args := i.Args()
+	for index := range args {
+		args[index] = ShellQuote(args[index])
+	}
+	return args
+}
+
+func (i Intent) String() string {
+	return strings.Join(i.ShellArgs(), " ")
+}
+
+// intentArgs returns the arguments of the intent itself, without the selector and the am options
+func (i Intent) intentArgs() []string {
+	var args []string
+	if i.Action != "" {
+		args = append(args, "-a", i.Action)
+	}
+
+	if i.Data != "" {
+		args = append(args, "-d", i.Data)
+	}
+
+	if i.MimeType != "" {
+		args = append(args, "-t", i.MimeType)
+	}
+
+	for _, category := range i.Categories {
+		args = append(args, "-c", category)
+	}
+
+	if i.Component != "" {
+		args = append(args, "-n", i.Component)
+	}
+
+	if i.Package != "" {
+		args = append(args, "-p", i.Package)
+	}
+
+	if i.Flags != 0 {
+		args = append(args, "-f", i.Flags.String())
+	}
+
+	if i.ReceiverForeground {
+		args = append(args, "--receiver-foreground")
+	}
+
+	return append(args, i.Extra.Args()...)
+}
+
+// Args returns the am arguments of the extras, sorted by type and key
+func (e Extras) Args() []string {
+	var args []string
+
+	for _, k := range SortedKeys(e.Es) {
+		args = append(args, "--es", k, e.Es[k])
+	}
+
+	esn := append([]string{}, e.Esn...)
+	sort.Strings(esn)
+	for _, k := range esn {
+		args = append(args, "--esn", k)
+	}
+
+	for _, k := range SortedKeys(e.Ez) {
+		args = append(args, "--ez", k, strconv.FormatBool(e.Ez[k]))
+	}
+
+	for _, k := range SortedKeys(e.Eb) {
+		args = append(args, "--eb", k, strconv.Itoa(int(e.Eb[k])))
+	}
+
+	for _, k := range SortedKeys(e.Ei) {
+		args = append(args, "--ei", k, strconv.Itoa(e.Ei[k]))
+	}
+
+	for _, k := range SortedKeys(e.El) {
+		args = append(args, "--el", k, strconv.FormatInt(e.El[k], 10))
+	}
+
+	for _, k := range SortedKeys(e.Ef) {
+		args = append(args, "--ef", k, formatFloat(float64(e.Ef[k]), 32))
+	}
+
+	for _, k := range SortedKeys(e.Ed) {
+		args = append(args, "--ed", k, formatFloat(e.Ed[k], 64))
+	}
+
+	for _, k := range SortedKeys(e.Eu) {
+		args = append(args, "--eu", k, e.Eu[k])
+	}
+
+	for _, k := range SortedKeys(e.Ecn) {
+		args = append(args, "--ecn", k, e.Ecn[k])
+	}
+
+	args = appendListExtras(args, "--eia", e.Eia, strconv.Itoa)
+	args = appendListExtras(args, "--eial", e.Eial, strconv.Itoa)
+	args = appendListExtras(args, "--ela", e.Ela, formatInt64)
+	args = appendListExtras(args, "--elal", e.Elal, formatInt64)
+	args = appendListExtras(args, "--efa", e.Efa, formatFloat32)
+	args = appendListExtras(args, "--efal", e.Efal, formatFloat32)
+	args = appendListExtras(args, "--esa", e.Esa, escapeListItem)
+	args = appendListExtras(args, "--esal", e.Esal, escapeListItem)
+
+	if e.GrantReadUriPermission {
+		args = append(args, "--grant-read-uri-permission")
+	}
+
+	if e.GrantWriteUriPermission {
+		args = append(args, "--grant-write-uri-permission")
+	}
+
+	if e.ExcludeStoppedPackages {
+		args = append(args, "--exclude-stopped-packages")
+	}
+
+	if e.IncludeStoppedPackages {
+		args = append(args, "--include-stopped-packages")
+	}
+
+	return args
+}
+
+func (e Extras) String() string {
+	args := e.Args()
+	for index := range args {
+		args[index] = ShellQuote(args[index])
+	}
+	return strings.Join(args, " ")
+}
+
+func appendListExtras[T any](args []string, option string, extras map[string][]T, format func(T) string) []string {
+	for _, k := range SortedKeys(extras) {
+		items := make([]string, len(extras[k]))
+		for index, item := range extras[k] {
+			items[index] = format(item)
+		}
+		args = append(args, option, k, strings.Join(items, ","))
+	}
+	return args
+}
+
+// escapeListItem escapes the commas of a string array item, since am splits the items on the unescaped commas
+func escapeListItem(item string) string {
+	return strings.ReplaceAll(item, ",", `\,`)
+}
+
+func formatInt64(value int64) string {
+	return strconv.FormatInt(value, 10)
+}
+
+func formatFloat32(value float32) string {
+	return formatFloat(float64(value), 32)
+}
+
+func formatFloat(value float64, bitSize int) string {
+	return strconv.FormatFloat(value, 'g', -1, bitSize)
+}
+
+func NewIntent() *Intent {
+	return &Intent{
+		Flags: 0,
+		Extra: Extras{
+			Es:   make(map[string]string),
+			Ez:   make(map[string]bool),
+			Eb:   make(map[string]int8),
+			Ei:   make(map[string]int),
+			El:   make(map[string]int64),
+			Ef:   make(map[string]float32),
+			Ed:   make(map[string]float64),
+			Eu:   make(map[string]string),
+			Ecn:  make(map[string]string),
+			Eia:  make(map[string][]int),
+			Ela:  make(map[string][]int64),
+			Efa:  make(map[string][]float32),
+			Esa:  make(map[string][]string),
+			Eial: make(map[string][]int),
+			Elal: make(map[string][]int64),
+			Efal: make(map[string][]float32),
+			Esal: make(map[string][]string),
+		},
+	}
+}
+
+// endregion Intent
+
+// region IntentUri
+
+// ParseIntentUri parses an intent uri, as generated by Intent.toUri(Intent.URI_INTENT_SCHEME):
+// intent://host/path#Intent;scheme=http;action=...;category=...;component=...;S.key=value;end.
+// An uri without the #Intent fragment is returned as a view intent of the given data
+func ParseIntentUri(uri string) (*Intent, error) {
+	intent := NewIntent()
+	intent.Action = ActionView
+
+	index := strings.LastIndex(uri, "#Intent;")
+	if index < 0 {
+		if uri == "" {
+			return nil, fmt.Errorf("empty intent uri")
+		}
+		intent.Data = uri
+		return intent, nil
+	}
+
+	data := uri[:index]
+	isIntentScheme := strings.HasPrefix(data, "intent:")
+	data = strings.TrimPrefix(data, "intent:")
+
+	fragment := uri[index+len("#Intent;"):]
+	if !strings.HasSuffix(fragment, "end") {
+		return nil, fmt.Errorf("intent uri not terminated: %s", uri)
+	}
+
+	var scheme string
+	current := intent
+	for _, part := range strings.Split(strings.TrimSuffix(fragment, "end"), ";") {
+		if part == "" {
+			continue
+		}
+
+		if part == "SEL" {
+			current = NewIntent()
+			intent.Selector = current
+			continue
+		}
+
+		key, value, found := strings.Cut(part, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid intent uri part: %s", part)
+		}
+
+		value, err := url.PathUnescape(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid intent uri part %s: %w", part, err)
+		}
+
+		switch key {
+		case "action":
+			current.Action = value
+		case "category":
+			current.Categories = append(current.Categories, value)
+		case "type":
+			current.MimeType = value
+		case "package":
+			current.Package = value
+		case "component":
+			current.Component = value
+		case "launchFlags":
+			flags, err := strconv.ParseInt(value, 0, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid launchFlags: %s", value)
+			}
+			current.Flags = IntentFlags(int32(flags))
+		case "scheme":
+			if current != intent {
+				current.Data = value + ":"
+			} else {
+				scheme = value
+			}
+		case "sourceBounds":
+			// not supported by am
+		default:
+			if err := current.Extra.putUriExtra(key, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if data != "" {
+		if isIntentScheme && scheme != "" {
+			data = scheme + ":" + data
+		}
+		intent.Data = data
+	}
+
+	return intent, nil
+}
+
+// putUriExtra adds the extra of an intent uri, in the form <type>.<key>=<value>
+func (e *Extras) putUriExtra(key string, value string) error {
+	kind, name, found := strings.Cut(key, ".")
+	if !found || len(kind) != 1 {
+		return fmt.Errorf("invalid intent uri extra: %s", key)
+	}
+
+	name, err := url.PathUnescape(name)
+	if err != nil {
+		return fmt.Errorf("invalid intent uri extra %s: %w", key, err)
+	}
+
+	switch kind {
+	case "S", "c":
+		if e.Es == nil {
+			e.Es = make(map[string]string)
+		}
+		e.Es[name] = value
+	case "B":
+		if e.Ez == nil {
+			e.Ez = make(map[string]bool)
+		}
+		e.Ez[name] = value == "true"
+	case "b":
+		v, err := strconv.ParseInt(value, 10, 8)
+		if err != nil {
+			return fmt.Errorf("invalid byte extra %s: %s", name, value)
+		}
+		if e.Eb == nil {
+			e.Eb = make(map[string]int8)
+		}
+		e.Eb[name] = int8(v)
+	case "i", "s":
+		v, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid int extra %s: %s", name, value)
+		}
+		if e.Ei == nil {
+			e.Ei = make(map[string]int)
+		}
+		e.Ei[name] = v
+	case "l":
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid long extra %s: %s", name, value)
+		}
+		if e.El == nil {
+			e.El = make(map[string]int64)
+		}
+		e.El[name] = v
+	case "f":
+		v, err := strconv.ParseFloat(value, 32)
+		if err != nil {
+			return fmt.Errorf("invalid float extra %s: %s", name, value)
+		}
+		if e.Ef == nil {
+			e.Ef = make(map[string]float32)
+		}
+		e.Ef[name] = float32(v)
+	case "d":
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid double extra %s: %s", name, value)
+		}
+		if e.Ed == nil {
+			e.Ed = make(map[string]float64)
+		}
+		e.Ed[name] = v
+	default:
+		return fmt.Errorf("unsupported intent uri extra: %s", key)
+	}
+	return nil
+}
+
+// ToUri returns the intent uri of the intent, see ParseIntentUri.
+// Only the scalar extras can be represented: the null, uri, component and array extras are omitted
+func (i Intent) ToUri() string {
+	var sb strings.Builder
+	sb.WriteString("intent:")
+
+	var scheme string
+	if i.Data != "" {
+		if index := strings.Index(i.Data, ":"); index > 0 {
+			scheme = i.Data[:index]
+			sb.WriteString(i.Data[index+1:])
+		} else {
+			sb.WriteString(i.Data)
+		}
+	}
+
+	sb.WriteString("#Intent;")
+	if scheme != "" {
+		sb.WriteString("scheme=" + encodeUriComponent(scheme, "") + ";")
+	}
+
+	i.writeUri(&sb)
+
+	if i.Selector != nil {
+		sb.WriteString("SEL;")
+		if index := strings.Index(i.Selector.Data, ":"); index > 0 {
+			sb.WriteString("scheme=" + encodeUriComponent(i.Selector.Data[:index], "") + ";")
+		}
+		i.Selector.writeUri(&sb)
+	}
+
+	sb.WriteString("end")
+	return sb.String()
+}
+
+func (i Intent) writeUri(sb *strings.Builder) {
+	if i.Action != "" {
+		sb.WriteString("action=" + encodeUriComponent(i.Action, "") + ";")
+	}
+	for _, category := range i.Categories {
+		sb.WriteString("category=" + encodeUriComponent(category, "") + ";")
+	}
+	if i.MimeType != "" {
+		sb.WriteString("type=" + encodeUriComponent(i.MimeType, "/") + ";")
+	}
+	if i.Flags != 0 {
+		sb.WriteString(fmt.Sprintf("launchFlags=0x%x;", uint32(i.Flags)))
+	}
+	if i.Package != "" {
+		sb.WriteString("package=" + encodeUriComponent(i.Package, "") + ";")
+	}
+	if i.Component != "" {
+		sb.WriteString("component=" + encodeUriComponent(i.Component, "/") + ";")
+	}
+
+	writeExtra := func(kind string, key string, value string) {
+		sb.WriteString(kind + "." + encodeUriComponent(key, "") + "=" + encodeUriComponent(value, "") + ";")
+	}
+
+	e := i.Extra
+	for _, k := range SortedKeys(e.Es) {
+		writeExtra("S", k, e.Es[k])
+	}
+	for _, k := range SortedKeys(e.Ez) {
+		writeExtra("B", k, strconv.FormatBool(e.Ez[k]))
+	}
+	for _, k := range SortedKeys(e.Eb) {
+		writeExtra("b", k, strconv.Itoa(int(e.Eb[k])))
+	}
+	for _, k := range SortedKeys(e.Ei) {
+		writeExtra("i", k, strconv.Itoa(e.Ei[k]))
+	}
+	for _, k := range SortedKeys(e.El) {
+		writeExtra("l", k, strconv.FormatInt(e.El[k], 10))
+	}
+	for _, k := range SortedKeys(e.Ef) {
+		writeExtra("f", k, formatFloat(float64(e.Ef[k]), 32))
+	}
+	for _, k := range SortedKeys(e.Ed) {
+		writeExtra("d", k, formatFloat(e.Ed[k], 64))
+	}
+}
+
+// encodeUriComponent encodes the value like android Uri.encode, the allowed characters are not encoded
+func encodeUriComponent(value string, allow string) string {
+	var sb strings.Builder
+	for _, b := range []byte(value) {
+		if b < 0x80 && (b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9' ||
+			strings.IndexByte("_-!.~'()*", b) >= 0 || strings.IndexByte(allow, b) >= 0) {
+			sb.WriteByte(b)
+		} else {
+			sb.WriteString(fmt.Sprintf("%%%02X", b))
+		}
+	}
+	return sb.String()
+}
+
+// endregion IntentUri
+
+// region IntentBuilder
+
+type IntentBuilder struct {
+	Intent *Intent
+}
+
+func NewIntentBuilder() *IntentBuilder {
+	return &IntentBuilder{Intent: NewIntent()}
+}
+
+func (b *IntentBuilder) Action(action string) *IntentBuilder {
+	b.Intent.Action = action
+	return b
+}
+
+func (b *IntentBuilder) Data(data string) *IntentBuilder {
+	b.Intent.Data = data
+	return b
+}
+
+func (b *IntentBuilder) MimeType(mimeType string) *IntentBuilder {
+	b.Intent.MimeType = mimeType
+	return b
+}
+
+func (b *IntentBuilder) AddCategory(categories ...string) *IntentBuilder {
+	b.Intent.Categories = append(b.Intent.Categories, categories...)
+	return b
+}
+
+func (b *IntentBuilder) Component(component string) *IntentBuilder {
+	b.Intent.Component = component
+	return b
+}
+
+func (b *IntentBuilder) Package(packageName string) *IntentBuilder {
+	b.Intent.Package = packageName
+	return b
+}
+
+func (b *IntentBuilder) Selector(selector *Intent) *IntentBuilder {
+	b.Intent.Selector = selector
+	return b
+}
+
+// AddFlags adds the flags to the intent flags
+func (b *IntentBuilder) AddFlags(flags ...IntentFlags) *IntentBuilder {
+	for _, flag := range flags {
+		b.Intent.Flags |= flag
+	}
+	return b
+}
+
+func (b *IntentBuilder) ReceiverForeground() *IntentBuilder {
+	b.Intent.ReceiverForeground = true
+	return b
+}
+
+func (b *IntentBuilder) User(user UserId) *IntentBuilder {
+	b.Intent.UserId = user
+	return b
+}
+
+func (b *IntentBuilder) Wait() *IntentBuilder {
+	b.Intent.Wait = true
+	return b
+}
+
+func (b *IntentBuilder) PutString(key string, value string) *IntentBuilder {
+	b.Intent.Extra.Es[key] = value
+	return b
+}
+
+// PutNullString adds a null string extra (--esn)
+func (b *IntentBuilder) PutNullString(key string) *IntentBuilder {
+	b.Intent.Extra.Esn = append(b.Intent.Extra.Esn, key)
+	return b
+}
+
+func (b *IntentBuilder) PutBool(key string, value bool) *IntentBuilder {
+	b.Intent.Extra.Ez[key] = value
+	return b
+}
+
+func (b *IntentBuilder) PutByte(key string, value int8) *IntentBuilder {
+	b.Intent.Extra.Eb[key] = value
+	return b
+}
+
+func (b *IntentBuilder) PutInt(key string, value int) *IntentBuilder {
+	b.Intent.Extra.Ei[key] = value
+	return b
+}
+
+func (b *IntentBuilder) PutLong(key string, value int64) *IntentBuilder {
+	b.Intent.Extra.El[key] = value
+	return b
+}
+
+func (b *IntentBuilder) PutFloat(key string, value float32) *IntentBuilder {
+	b.Intent.Extra.Ef[key] = value
+	return b
+}
+
+func (b *IntentBuilder) PutDouble(key string, value float64) *IntentBuilder {
+	b.Intent.Extra.Ed[key] = value
+	return b
+}
+
+func (b *IntentBuilder) PutUri(key string, value string) *IntentBuilder {
+	b.Intent.Extra.Eu[key] = value
+	return b
+}
+
+func (b *IntentBuilder) PutComponent(key string, value string) *IntentBuilder {
+	b.Intent.Extra.Ecn[key] = value
+	return b
+}
+
+func (b *IntentBuilder) PutIntArray(key string, value ...int) *IntentBuilder {
+	b.Intent.Extra.Eia[key] = value
+	return b
+}
+
+func (b *IntentBuilder) PutIntList(key string, value ...int) *IntentBuilder {
+	b.Intent.Extra.Eial[key] = value
+	return b
+}
+
+func (b *IntentBuilder) PutLongArray(key string, value ...int64) *IntentBuilder {
+	b.Intent.Extra.Ela[key] = value
+	return b
+}
+
+func (b *IntentBuilder) PutLongList(key string, value ...int64) *IntentBuilder {
+	b.Intent.Extra.Elal[key] = value
+	return b
+}
+
+func (b *IntentBuilder) PutFloatArray(key string, value ...float32) *IntentBuilder {
+	b.Intent.Extra.Efa[key] = value
+	return b
+}
+
+func (b *IntentBuilder) PutFloatList(key string, value ...float32) *IntentBuilder {
+	b.Intent.Extra.Efal[key] = value
+	return b
+}
+
+func (b *IntentBuilder) PutStringArray(key string, value ...string) *IntentBuilder {
+	b.Intent.Extra.Esa[key] = value
+	return b
+}
+
+func (b *IntentBuilder) PutStringList(key string, value ...string) *IntentBuilder {
+	b.Intent.Extra.Esal[key] = value
+	return b
+}
+
+func (b *IntentBuilder) Build() *Intent {
+	return b.Intent
+}
+
+// endregion IntentBuilder
@@ -1,6 +1,9 @@
 package types
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 // region Permission
 
@@ -34,4 +37,44 @@ func (r PackagePermission) String() string {
 	return fmt.Sprintf("PackagePermission{Name:%s, Granted:%t, Flags:%s}", r.Name, r.Granted, r.Flags)
 }
 
+// HasFlag returns true if the permission has the given flag set
+func (r PackagePermission) HasFlag(flag PermissionFlag) bool {
+	for _, f := range r.Flags {
+		if f == string(flag) {
+			return true
+		}
+	}
+	return false
+}
+
 // endregion PackagePermission
+
+// region PermissionFlag
+
+// PermissionFlag is a permission flag, as reported by the package dump (flags=[ USER_SET|USER_FIXED ])
+type PermissionFlag string
+
+const (
+	PermissionFlagUserSet                    PermissionFlag = "USER_SET"
+	PermissionFlagUserFixed                  PermissionFlag = "USER_FIXED"
+	PermissionFlagPolicyFixed                PermissionFlag = "POLICY_FIXED"
+	PermissionFlagSystemFixed                PermissionFlag = "SYSTEM_FIXED"
+	PermissionFlagGrantedByDefault           PermissionFlag = "GRANTED_BY_DEFAULT"
+	PermissionFlagReviewRequired             PermissionFlag = "REVIEW_REQUIRED"
+	PermissionFlagRevokeWhenRequested        PermissionFlag = "REVOKE_WHEN_REQUESTED"
+	PermissionFlagRevokedCompat              PermissionFlag = "REVOKED_COMPAT"
+	PermissionFlagUserSensitiveWhenGranted   PermissionFlag = "USER_SENSITIVE_WHEN_GRANTED"
+	PermissionFlagUserSensitiveWhenDenied    PermissionFlag = "USER_SENSITIVE_WHEN_DENIED"
+	PermissionFlagRestrictionInstallerExempt PermissionFlag = "RESTRICTION_INSTALLER_EXEMPT"
+	PermissionFlagRestrictionSystemExempt    PermissionFlag = "RESTRICTION_SYSTEM_EXEMPT"
+	PermissionFlagRestrictionUpgradeExempt   PermissionFlag = "RESTRICTION_UPGRADE_EXEMPT"
+	PermissionFlagOneTime                    PermissionFlag = "ONE_TIME"
+	PermissionFlagAutoRevoked                PermissionFlag = "AUTO_REVOKED"
+)
+
+// ShellName returns the name accepted by "pm set-permission-flags" (e.g. user-fixed)
+func (f PermissionFlag) ShellName() string {
+	return strings.ReplaceAll(strings.ToLower(string(f)), "_", "-")
+}
+
+// endregion PermissionFlag
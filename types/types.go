@@ -3,7 +3,7 @@ package types
 import (
 	"fmt"
 	"net"
-	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -19,6 +19,16 @@ type Pair[K interface{}, V interface{}] struct {
 
 // endregion Pair
 
+// SortedKeys returns the keys of the map in ascending order
+func SortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // region GetSerialAddress
 
 type Serial interface {
@@ -218,199 +228,41 @@ func NewTcpDevice(name *string, macAddress *net.HardwareAddr, addr *string) (*Tc
 
 // endregion MdnsDevice
 
-// region Intent
+// region UserId
 
+// UserId is an android user id, as accepted by the --user option of pm, am and settings.
+// The empty value means that the option is not set and the command default is used
 type UserId string
 
-type Intent struct {
-	Action             string
-	Data               string
-	MimeType           string
-	Category           string
-	Component          string
-	Package            string
-	ReceiverForeground bool
-	Flags              int32
-	Extra              Extras
-	UserId             UserId
-	Wait               bool
-}
-
-type Extras struct {
-	Es                      map[string]string
-	Ez                      map[string]bool
-	Ei                      map[string]int
-	El                      map[string]int64
-	Ef                      map[string]float32
-	Eu                      map[string]string
-	Ecn                     map[string]string
-	Eia                     map[string][]int
-	Ela                     map[string][]int64
-	Efa                     map[string][]float32
-	Esa                     map[string][]string
-	GrantReadUriPermission  bool
-	GrantWriteUriPermission bool
-	ExcludeStoppedPackages  bool
-	IncludeStoppedPackages  bool
-}
-
-func (i Intent) String() string {
-	// sb := strings.Builder{}
-	var sb []string
-	if i.Action != "" {
-		sb = append(sb, fmt.Sprintf("-a %s", i.Action))
-	}
-
-	if i.Data != "" {
-		sb = append(sb, fmt.Sprintf("-d %s", i.Data))
-	}
-
-	if i.MimeType != "" {
-		sb = append(sb, fmt.Sprintf("-t %s", i.MimeType))
-	}
-
-	if i.Category != "" {
-		sb = append(sb, fmt.Sprintf("-c %s", i.Category))
-	}
-
-	if i.Component != "" {
-		sb = append(sb, fmt.Sprintf("-n %s", i.Component))
-	}
-
-	if i.Package != "" {
-		sb = append(sb, fmt.Sprintf("-p %s", i.Package))
-	}
-
-	if i.ReceiverForeground {
-		sb = append(sb, "--receiver-foreground")
-	}
-
-	if !reflect.DeepEqual(Extras{}, i.Extra) {
-		sb = append(sb, i.Extra.String())
-	}
-
-	if i.UserId != "" {
-		sb = append(sb, fmt.Sprintf("--user %s", i.UserId))
-	}
-
-	if i.Wait {
-		sb = append(sb, "-W")
-	}
+const (
+	UserAll     UserId = "all"
+	UserCurrent UserId = "current"
+	UserSystem  UserId = "0"
+)
 
-	return strings.Join(sb, " ")
+func NewUserId(id int) UserId {
+	return UserId(strconv.Itoa(id))
 }
 
-func (e Extras) String() string {
-	var s []string
-	if e.Es != nil && len(e.Es) > 0 {
-		for k, v := range e.Es {
-			s = append(s, fmt.Sprintf("--es %s %s", k, v))
-		}
-	}
-
-	if e.Ez != nil && len(e.Ez) > 0 {
-		for k, v := range e.Ez {
-			s = append(s, fmt.Sprintf("--ez %s %t", k, v))
-		}
-	}
-
-	if e.Ei != nil && len(e.Ei) > 0 {
-		for k, v := range e.Ei {
-			s = append(s, fmt.Sprintf("--ei %s %d", k, v))
-		}
-	}
-
-	if e.El != nil && len(e.El) > 0 {
-		for k, v := range e.El {
-			s = append(s, fmt.Sprintf("--el %s %d", k, v))
-		}
-	}
-
-	if e.Ef != nil && len(e.Ef) > 0 {
-		for k, v := range e.Ef {
-			s = append(s, fmt.Sprintf("--ef %s %f", k, v))
-		}
-	}
-
-	if e.Eu != nil && len(e.Eu) > 0 {
-		for k, v := range e.Eu {
-			s = append(s, fmt.Sprintf("--eu %s %s", k, v))
-		}
-	}
-
-	if e.Ecn != nil && len(e.Ecn) > 0 {
-		for k, v := range e.Ecn {
-			s = append(s, fmt.Sprintf("--ecn %s %s", k, v))
-		}
-	}
-
-	if e.Eia != nil && len(e.Eia) > 0 {
-		for k, v := range e.Eia {
-			s = append(s, fmt.Sprintf("--eia %s %s", k, strings.Trim(strings.Replace(fmt.Sprint(v), " ", ",", -1), "[]")))
-		}
-	}
-
-	if e.Ela != nil && len(e.Ela) > 0 {
-		for k, v := range e.Ela {
-			s = append(s, fmt.Sprintf("--ela %s %s", k, strings.Trim(strings.Replace(fmt.Sprint(v), " ", ",", -1), "[]")))
-		}
-	}
-
-	if e.Efa != nil && len(e.Efa) > 0 {
-		for k, v := range e.Efa {
-			s = append(s, fmt.Sprintf("--efa %s %s", k, strings.Trim(strings.Replace(fmt.Sprint(v), " ", ",", -1), "[]")))
-		}
-	}
-
-	if e.Esa != nil && len(e.Esa) > 0 {
-		for k, v := range e.Esa {
-			s = append(s, fmt.Sprintf("--esa %s %s", k, strings.Trim(strings.Replace(fmt.Sprint(v), " ", ",", -1), "[]")))
-		}
-	}
-
-	if e.GrantReadUriPermission {
-		s = append(s, "--grant-read-uri-permission")
-	}
-
-	if e.GrantWriteUriPermission {
-		s = append(s, "--grant-write-uri-permission")
-	}
-
-	if e.ExcludeStoppedPackages {
-		s = append(s, "--exclude-stopped-packages")
-	}
+// IsSet returns true if the user id is not empty
+func (u UserId) IsSet() bool {
+	return u != ""
+}
 
-	if e.IncludeStoppedPackages {
-		s = append(s, "--include-stopped-packages")
-	}
+// Int returns the numeric user id, an error is returned for the special values (all, current)
+func (u UserId) Int() (int, error) {
+	return strconv.Atoi(string(u))
+}
 
-	return strings.Join(s, " ")
-}
-
-type IntentBuilder struct {
-	Intent *Intent
-}
-
-func NewIntent() *Intent {
-	return &Intent{
-		Flags: 0,
-		Extra: Extras{
-			Es:  make(map[string]string),
-			Ez:  make(map[string]bool),
-			Ei:  make(map[string]int),
-			El:  make(map[string]int64),
-			Ef:  make(map[string]float32),
-			Eu:  make(map[string]string),
-			Ecn: make(map[string]string),
-			Eia: make(map[string][]int),
-			Ela: make(map[string][]int64),
-			Efa: make(map[string][]float32),
-			Esa: make(map[string][]string),
-		},
+// Args returns the "--user <id>" arguments, or nothing if the user id is not set
+func (u UserId) Args() []string {
+	if !u.IsSet() {
+		return nil
 	}
+	return []string{"--user", string(u)}
 }
 
-// endregion Intent
+// endregion UserId
 
 // region Size
 
@@ -0,0 +1,259 @@
+package ui
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var boundsRegexp = regexp.MustCompile(`^\[(-?\d+),(-?\d+)\]\[(-?\d+),(-?\d+)\]$`)
+
+// region Node
+
+// Node is a view of the ui hierarchy dumped by uiautomator
+type Node struct {
+	Index int
+	Text  string
+	// ResourceId is the full resource name (e.g. com.android.settings:id/search_bar)
+	ResourceId    string
+	Class         string
+	Package       string
+	ContentDesc   string
+	Checkable     bool
+	Checked       bool
+	Clickable     bool
+	Enabled       bool
+	Focusable     bool
+	Focused       bool
+	Scrollable    bool
+	LongClickable bool
+	Password      bool
+	Selected      bool
+	Bounds        image.Rectangle
+	// Attributes are all the attributes of the xml node, including the unknown ones
+	Attributes map[string]string
+	Parent     *Node
+	Children   []*Node
+}
+
+func (n Node) String() string {
+	return fmt.Sprintf("Node{Class:%s, ResourceId:%s, Text:%q, Bounds:%v}", n.Class, n.ResourceId, n.Text, n.Bounds)
+}
+
+// Center returns the center of the node bounds, where the node is tapped
+func (n Node) Center() image.Point {
+	return image.Pt((n.Bounds.Min.X+n.Bounds.Max.X)/2, (n.Bounds.Min.Y+n.Bounds.Max.Y)/2)
+}
+
+// Id returns the resource id without the package (e.g. search_bar)
+func (n Node) Id() string {
+	if _, id, ok := strings.Cut(n.ResourceId, ":id/"); ok {
+		return id
+	}
+	return n.ResourceId
+}
+
+// IsVisible returns true if the node has a non empty area on screen
+func (n Node) IsVisible() bool {
+	return !n.Bounds.Empty()
+}
+
+// Attr returns the value of the xml attribute
+func (n Node) Attr(name string) string {
+	return n.Attributes[name]
+}
+
+// Walk calls fn for the node and its descendants, depth first. Returning false stops the walk
+func (n *Node) Walk(fn func(node *Node) bool) bool {
+	if !fn(n) {
+		return false
+	}
+	for _, child := range n.Children {
+		if !child.Walk(fn) {
+			return false
+		}
+	}
+	return true
+}
+
+// Find returns the first descendant (or the node itself) matching the predicate, nil if none matches
+func (n *Node) Find(predicate Predicate) *Node {
+	var found *Node
+	n.Walk(func(node *Node) bool {
+		if predicate(node) {
+			found = node
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+// FindAll returns the descendants (including the node itself) matching the predicate
+func (n *Node) FindAll(predicate Predicate) []*Node {
+	var found []*Node
+	n.Walk(func(node *Node) bool {
+		if predicate(node) {
+			found = append(found, node)
+		}
+		return true
+	})
+	return found
+}
+
+func (n *Node) setAttribute(name string, value string) error {
+	n.Attributes[name] = value
+
+	var err error
+	switch name {
+	case "index":
+		n.Index, err = strconv.Atoi(value)
+	case "text":
+		n.Text = value
+	case "resource-id":
+		n.ResourceId = value
+	case "class":
+		n.Class = value
+	case "package":
+		n.Package = value
+	case "content-desc":
+		n.ContentDesc = value
+	case "checkable":
+		n.Checkable = value == "true"
+	case "checked":
+		n.Checked = value == "true"
+	case "clickable":
+		n.Clickable = value == "true"
+	case "enabled":
+		n.Enabled = value == "true"
+	case "focusable":
+		n.Focusable = value == "true"
+	case "focused":
+		n.Focused = value == "true"
+	case "scrollable":
+		n.Scrollable = value == "true"
+	case "long-clickable":
+		n.LongClickable = value == "true"
+	case "password":
+		n.Password = value == "true"
+	case "selected":
+		n.Selected = value == "true"
+	case "bounds":
+		n.Bounds, err = ParseBounds(value)
+	}
+	return err
+}
+
+// ParseBounds parses the uiautomator bounds, in the format [left,top][right,bottom]
+func ParseBounds(value string) (image.Rectangle, error) {
+	m := boundsRegexp.FindStringSubmatch(value)
+	if m == nil {
+		return image.Rectangle{}, fmt.Errorf("invalid bounds: %s", value)
+	}
+
+	var values [4]int
+	for i := range values {
+		values[i], _ = strconv.Atoi(m[i+1])
+	}
+	return image.Rect(values[0], values[1], values[2], values[3]), nil
+}
+
+// endregion Node
+
+// region Hierarchy
+
+// Hierarchy is the ui hierarchy of the windows on screen
+type Hierarchy struct {
+	Rotation int
+	// Root is the hierarchy element, its children are the root views of the windows
+	Root *Node
+}
+
+// Nodes returns all the nodes, depth first
+func (h Hierarchy) Nodes() []*Node {
+	return h.FindAll(func(node *Node) bool { return true })
+}
+
+// Find returns the first node matching the predicate, nil if none matches
+func (h Hierarchy) Find(predicate Predicate) *Node {
+	for _, child := range h.Root.Children {
+		if found := child.Find(predicate); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// FindAll returns the nodes matching the predicate
+func (h Hierarchy) FindAll(predicate Predicate) []*Node {
+	var found []*Node
+	for _, child := range h.Root.Children {
+		found = append(found, child.FindAll(predicate)...)
+	}
+	return found
+}
+
+// Focused returns the focused node, nil if no node has the focus
+func (h Hierarchy) Focused() *Node {
+	return h.Find(IsFocused())
+}
+
+// ParseHierarchy parses the xml dumped by "uiautomator dump"
+func ParseHierarchy(r io.Reader) (*Hierarchy, error) {
+	decoder := xml.NewDecoder(r)
+	hierarchy := &Hierarchy{}
+	var current *Node
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch element := token.(type) {
+		case xml.StartElement:
+			switch element.Name.Local {
+			case "hierarchy":
+				hierarchy.Root = &Node{Class: "hierarchy", Attributes: map[string]string{}}
+				for _, attr := range element.Attr {
+					hierarchy.Root.Attributes[attr.Name.Local] = attr.Value
+					if attr.Name.Local == "rotation" {
+						hierarchy.Rotation, _ = strconv.Atoi(attr.Value)
+					}
+				}
+				current = hierarchy.Root
+			case "node":
+				if current == nil {
+					return nil, errors.New("node outside of the hierarchy")
+				}
+				node := &Node{Parent: current, Attributes: map[string]string{}}
+				for _, attr := range element.Attr {
+					if err := node.setAttribute(attr.Name.Local, attr.Value); err != nil {
+						return nil, err
+					}
+				}
+				current.Children = append(current.Children, node)
+				current = node
+			}
+		case xml.EndElement:
+			if element.Name.Local == "node" && current != nil {
+				current = current.Parent
+			}
+		}
+	}
+
+	if hierarchy.Root == nil {
+		return nil, errors.New("hierarchy not found")
+	}
+	return hierarchy, nil
+}
+
+// endregion Hierarchy
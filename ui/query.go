@@ -0,0 +1,168 @@
+package ui
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Predicate selects the nodes of the hierarchy
+type Predicate func(node *Node) bool
+
+// ById matches the resource id. The id can be the full resource name (com.example:id/title)
+// or only the id (title)
+func ById(id string) Predicate {
+	return func(node *Node) bool {
+		if strings.Contains(id, ":id/") {
+			return node.ResourceId == id
+		}
+		return node.ResourceId != "" && node.Id() == id
+	}
+}
+
+// ByText matches the exact text
+func ByText(text string) Predicate {
+	return func(node *Node) bool {
+		return node.Text == text
+	}
+}
+
+// ByTextContains matches the nodes whose text contains the value, ignoring the case
+func ByTextContains(value string) Predicate {
+	value = strings.ToLower(value)
+	return func(node *Node) bool {
+		return strings.Contains(strings.ToLower(node.Text), value)
+	}
+}
+
+// ByTextMatches matches the text with the regular expression
+func ByTextMatches(pattern *regexp.Regexp) Predicate {
+	return func(node *Node) bool {
+		return pattern.MatchString(node.Text)
+	}
+}
+
+// ByDescription matches the exact content description
+func ByDescription(description string) Predicate {
+	return func(node *Node) bool {
+		return node.ContentDesc == description
+	}
+}
+
+// ByClass matches the class name. The name can be the full name (android.widget.Button) or
+// the simple name (Button)
+func ByClass(class string) Predicate {
+	return func(node *Node) bool {
+		if strings.Contains(class, ".") {
+			return node.Class == class
+		}
+		return node.Class == class || strings.HasSuffix(node.Class, "."+class)
+	}
+}
+
+// ByPackage matches the package of the node
+func ByPackage(packageName string) Predicate {
+	return func(node *Node) bool {
+		return node.Package == packageName
+	}
+}
+
+func IsClickable() Predicate {
+	return func(node *Node) bool {
+		return node.Clickable
+	}
+}
+
+func IsFocused() Predicate {
+	return func(node *Node) bool {
+		return node.Focused
+	}
+}
+
+func IsScrollable() Predicate {
+	return func(node *Node) bool {
+		return node.Scrollable
+	}
+}
+
+// HasChild matches the nodes with a direct child matching the predicate
+func HasChild(predicate Predicate) Predicate {
+	return func(node *Node) bool {
+		for _, child := range node.Children {
+			if predicate(child) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// HasDescendant matches the nodes with a descendant matching the predicate
+func HasDescendant(predicate Predicate) Predicate {
+	return func(node *Node) bool {
+		for _, child := range node.Children {
+			if child.Find(predicate) != nil {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// And matches the nodes matching all the predicates
+func And(predicates ...Predicate) Predicate {
+	return func(node *Node) bool {
+		for _, predicate := range predicates {
+			if !predicate(node) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Or matches the nodes matching at least one of the predicates
+func Or(predicates ...Predicate) Predicate {
+	return func(node *Node) bool {
+		for _, predicate := range predicates {
+			if predicate(node) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func Not(predicate Predicate) Predicate {
+	return func(node *Node) bool {
+		return !predicate(node)
+	}
+}
+
+// ByXPath matches the nodes selected by the xpath expression (see XPath). The expression is
+// evaluated on the whole hierarchy of the node, so that absolute paths work as expected
+func ByXPath(expression string) (Predicate, error) {
+	path, err := compileXPath(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	// the selected nodes are cached for the last evaluated hierarchy
+	var lastRoot *Node
+	var selected map[*Node]bool
+
+	return func(node *Node) bool {
+		root := node
+		for root.Parent != nil {
+			root = root.Parent
+		}
+
+		if root != lastRoot {
+			lastRoot = root
+			selected = map[*Node]bool{}
+			for _, n := range path.evaluate(root) {
+				selected[n] = true
+			}
+		}
+		return selected[node]
+	}, nil
+}
@@ -0,0 +1,314 @@
+package ui
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"strings"
+	"time"
+
+	"github.com/sephiroth74/go_adb_client/input"
+	"github.com/sephiroth74/go_adb_client/process"
+	"github.com/sephiroth74/go_adb_client/shell"
+	"github.com/sephiroth74/go_adb_client/types"
+)
+
+// DumpFile is the device file used by Dump when the hierarchy cannot be streamed
+const DumpFile = "/sdcard/window_dump.xml"
+
+// pollInterval is the interval between two dumps of WaitForElement
+const pollInterval = 500 * time.Millisecond
+
+// ErrElementNotFound is returned when no node matches the query
+var ErrElementNotFound = errors.New("element not found")
+
+type UiAutomator struct {
+	Shell *shell.Shell
+}
+
+// DumpOptions are the options of "uiautomator dump"
+type DumpOptions struct {
+	// Compressed removes the layout nodes not relevant for the accessibility
+	Compressed bool
+}
+
+func (o *DumpOptions) args() []string {
+	if o == nil || !o.Compressed {
+		return nil
+	}
+	return []string{"--compressed"}
+}
+
+// Dump returns the ui hierarchy. The xml is streamed with "exec-out uiautomator dump /dev/tty", or
+// written to DumpFile and read when the device cannot stream it
+func (u UiAutomator) Dump(options *DumpOptions) (*Hierarchy, error) {
+	cmd := u.Shell.NewCommand().WithCommand("exec-out").WithArgs("uiautomator", "dump").AddArgs(options.args()...).AddArgs("/dev/tty")
+	result, err := process.SimpleOutput(cmd, u.Shell.Conn.Verbose)
+	if err != nil {
+		return nil, err
+	}
+
+	if data, ok := extractHierarchy(result.Output()); ok {
+		return ParseHierarchy(strings.NewReader(data))
+	}
+	return u.DumpToFile(DumpFile, options)
+}
+
+// DumpToFile dumps the ui hierarchy to the device file, then reads it
+func (u UiAutomator) DumpToFile(filename string, options *DumpOptions) (*Hierarchy, error) {
+	cmd := u.Shell.NewCommand().WithArgs("uiautomator", "dump").AddArgs(options.args()...).AddArgs(filename)
+	result, err := process.SimpleOutput(cmd, u.Shell.Conn.Verbose)
+	if err != nil {
+		return nil, err
+	}
+
+	// uiautomator reports the errors with exit code 0
+	if !result.IsOk() || strings.Contains(result.Output(), "ERROR") {
+		return nil, fmt.Errorf("uiautomator dump failed: %s", result.Output())
+	}
+
+	result, err = u.Shell.Cat(filename)
+	if err != nil {
+		return nil, err
+	}
+	if !result.IsOk() {
+		return nil, result.NewError()
+	}
+	return ParseHierarchy(strings.NewReader(result.Output()))
+}
+
+// extractHierarchy returns the xml in the dump output, which is followed by the "UI hierchary dumped to" message
+func extractHierarchy(output string) (string, bool) {
+	start := strings.Index(output, "<?xml")
+	end := strings.LastIndex(output, "</hierarchy>")
+	if start < 0 || end < start {
+		return "", false
+	}
+	return output[start : end+len("</hierarchy>")], true
+}
+
+// Find dumps the hierarchy and returns the first node matching the predicate, ErrElementNotFound if none matches
+func (u UiAutomator) Find(predicate Predicate) (*Node, error) {
+	hierarchy, err := u.Dump(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	node := hierarchy.Find(predicate)
+	if node == nil {
+		return nil, ErrElementNotFound
+	}
+	return node, nil
+}
+
+// FindAll dumps the hierarchy and returns the nodes matching the predicate
+func (u UiAutomator) FindAll(predicate Predicate) ([]*Node, error) {
+	hierarchy, err := u.Dump(nil)
+	if err != nil {
+		return nil, err
+	}
+	return hierarchy.FindAll(predicate), nil
+}
+
+// Tap taps the center of the node
+func (u UiAutomator) Tap(node *Node) error {
+	center := node.Center()
+	return process.Check(u.Shell.Tap(input.TOUCHSCREEEN, types.Pair[int, int]{First: center.X, Second: center.Y}))
+}
+
+// TapElement taps the first node matching the predicate and returns it
+func (u UiAutomator) TapElement(predicate Predicate) (*Node, error) {
+	node, err := u.Find(predicate)
+	if err != nil {
+		return nil, err
+	}
+	return node, u.Tap(node)
+}
+
+// WaitForElement dumps the hierarchy until a node matches the predicate or the timeout expires
+func (u UiAutomator) WaitForElement(predicate Predicate, timeout time.Duration) (*Node, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		node, err := u.Find(predicate)
+		if err == nil {
+			return node, nil
+		}
+		if !errors.Is(err, ErrElementNotFound) {
+			return nil, err
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%w after %s", ErrElementNotFound, timeout)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// WaitForElementGone dumps the hierarchy until no node matches the predicate or the timeout expires
+func (u UiAutomator) WaitForElementGone(predicate Predicate, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		_, err := u.Find(predicate)
+		if errors.Is(err, ErrElementNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("element still present after %s", timeout)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// region Scroll
+
+// Direction is the direction the content is scrolled to
+type Direction int
+
+const (
+	// ScrollDown shows the content below, swiping up
+	ScrollDown Direction = iota
+	ScrollUp
+	ScrollRight
+	ScrollLeft
+)
+
+func (d Direction) String() string {
+	switch d {
+	case ScrollDown:
+		return "down"
+	case ScrollUp:
+		return "up"
+	case ScrollRight:
+		return "right"
+	case ScrollLeft:
+		return "left"
+	}
+	return "unknown"
+}
+
+// swipe returns the start and end of the swipe scrolling the bounds in the direction.
+// The swipe covers the central 60% of the bounds, to avoid the edges of the container
+func (d Direction) swipe(bounds image.Rectangle) (image.Point, image.Point) {
+	center := image.Pt((bounds.Min.X+bounds.Max.X)/2, (bounds.Min.Y+bounds.Max.Y)/2)
+	dx, dy := bounds.Dx()*3/10, bounds.Dy()*3/10
+
+	switch d {
+	case ScrollUp:
+		return image.Pt(center.X, center.Y-dy), image.Pt(center.X, center.Y+dy)
+	case ScrollRight:
+		return image.Pt(center.X+dx, center.Y), image.Pt(center.X-dx, center.Y)
+	case ScrollLeft:
+		return image.Pt(center.X-dx, center.Y), image.Pt(center.X+dx, center.Y)
+	}
+	return image.Pt(center.X, center.Y+dy), image.Pt(center.X, center.Y-dy)
+}
+
+// ScrollOptions are the options of ScrollUntilVisible
+type ScrollOptions struct {
+	// Container is the scrollable node to swipe. Default the largest scrollable node
+	Container Predicate
+	// MaxSwipes is the maximum number of swipes. Default 10
+	MaxSwipes int
+	// Duration of each swipe. Default 400ms
+	Duration time.Duration
+}
+
+// ScrollUntilVisible swipes the container in the direction until a node matches the predicate. It stops with
+// ErrElementNotFound after MaxSwipes swipes or when the content does not change anymore
+func (u UiAutomator) ScrollUntilVisible(predicate Predicate, direction Direction, options *ScrollOptions) (*Node, error) {
+	maxSwipes, duration := 10, 400*time.Millisecond
+	var container Predicate
+	if options != nil {
+		if options.MaxSwipes > 0 {
+			maxSwipes = options.MaxSwipes
+		}
+		if options.Duration > 0 {
+			duration = options.Duration
+		}
+		container = options.Container
+	}
+
+	var previous []byte
+	for swipes := 0; ; swipes++ {
+		hierarchy, err := u.Dump(nil)
+		if err != nil {
+			return nil, err
+		}
+
+		if node := hierarchy.Find(predicate); node != nil {
+			return node, nil
+		}
+
+		// the end of the content is reached when the hierarchy does not change
+		content := hierarchy.signature()
+		if swipes >= maxSwipes || bytes.Equal(content, previous) {
+			return nil, fmt.Errorf("%w after %d swipes %s", ErrElementNotFound, swipes, direction)
+		}
+		previous = content
+
+		bounds, err := hierarchy.scrollBounds(container)
+		if err != nil {
+			return nil, err
+		}
+
+		from, to := direction.swipe(bounds)
+		err = process.Check(u.Shell.Swipe(input.TOUCHSCREEEN, int32(duration.Milliseconds()),
+			types.Pair[int, int]{First: from.X, Second: from.Y},
+			types.Pair[int, int]{First: to.X, Second: to.Y}))
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// scrollBounds returns the bounds of the container, or of the largest scrollable node when container is nil
+func (h Hierarchy) scrollBounds(container Predicate) (image.Rectangle, error) {
+	if container != nil {
+		node := h.Find(container)
+		if node == nil {
+			return image.Rectangle{}, fmt.Errorf("container %w", ErrElementNotFound)
+		}
+		return node.Bounds, nil
+	}
+
+	var largest *Node
+	for _, node := range h.FindAll(IsScrollable()) {
+		if largest == nil || area(node.Bounds) > area(largest.Bounds) {
+			largest = node
+		}
+	}
+
+	if largest != nil {
+		return largest.Bounds, nil
+	}
+
+	// no scrollable node, swipe the whole screen
+	var screen image.Rectangle
+	for _, child := range h.Root.Children {
+		screen = screen.Union(child.Bounds)
+	}
+	if screen.Empty() {
+		return image.Rectangle{}, errors.New("no scrollable node found")
+	}
+	return screen, nil
+}
+
+// signature returns the texts and bounds of the nodes, to compare two dumps
+func (h Hierarchy) signature() []byte {
+	var buffer bytes.Buffer
+	for _, node := range h.Nodes() {
+		buffer.WriteString(fmt.Sprintf("%s|%s|%s|%v\n", node.ResourceId, node.Text, node.ContentDesc, node.Bounds))
+	}
+	return buffer.Bytes()
+}
+
+func area(r image.Rectangle) int {
+	return r.Dx() * r.Dy()
+}
+
+// endregion Scroll
@@ -0,0 +1,506 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// The xpath subset supported by XPath:
+//
+//	/hierarchy/node[1]/node[2]             absolute path, with positions (1 based)
+//	//node[@resource-id='com.example:id/ok'] descendants, attribute comparison (= or !=)
+//	//android.widget.Button[@text="OK"]     the element name matches the node class, * matches any node
+//	//*[contains(@text, 'Wi')]              contains, starts-with and ends-with functions
+//	//node[@clickable='true' and not(@text='')]/..   and, or, not, . and ..
+//	//node[@checked]                        attributes alone match the non empty values
+//	//node[text()='OK']                     text() is the text attribute
+//	node[@focused='true']                   relative paths start from the hierarchy element
+
+// region Lexer
+
+type xpathToken struct {
+	kind  string // one of / // [ ] ( ) , = != @ name string number
+	value string
+}
+
+func lexXPath(expression string) ([]xpathToken, error) {
+	var tokens []xpathToken
+	runes := []rune(expression)
+	isNameRune := func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' || r == '_' || r == '-' || r == ':' || r == '$' || r == '*'
+	}
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '/':
+			if i+1 < len(runes) && runes[i+1] == '/' {
+				tokens = append(tokens, xpathToken{kind: "//"})
+				i += 2
+			} else {
+				tokens = append(tokens, xpathToken{kind: "/"})
+				i++
+			}
+		case strings.ContainsRune("[](),=@", r):
+			tokens = append(tokens, xpathToken{kind: string(r)})
+			i++
+		case r == '!':
+			if i+1 >= len(runes) || runes[i+1] != '=' {
+				return nil, fmt.Errorf("invalid xpath %q: unexpected ! at %d", expression, i)
+			}
+			tokens = append(tokens, xpathToken{kind: "!="})
+			i += 2
+		case r == '\'' || r == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != r {
+				end++
+			}
+			if end >= len(runes) {
+				return nil, fmt.Errorf("invalid xpath %q: unterminated string", expression)
+			}
+			tokens = append(tokens, xpathToken{kind: "string", value: string(runes[i+1 : end])})
+			i = end + 1
+		case isNameRune(r):
+			end := i
+			for end < len(runes) && isNameRune(runes[end]) {
+				end++
+			}
+			value := string(runes[i:end])
+			if _, err := strconv.Atoi(value); err == nil {
+				tokens = append(tokens, xpathToken{kind: "number", value: value})
+			} else {
+				tokens = append(tokens, xpathToken{kind: "name", value: value})
+			}
+			i = end
+		default:
+			return nil, fmt.Errorf("invalid xpath %q: unexpected %c at %d", expression, r, i)
+		}
+	}
+	return tokens, nil
+}
+
+// endregion Lexer
+
+// region Parser
+
+type xpathStep struct {
+	descendant bool
+	// name is the element name, * or node for any node, . and .. for the current and parent node
+	name       string
+	predicates []xpathExpr
+}
+
+type xpathPath struct {
+	// relative paths are evaluated from the hierarchy element instead of the document
+	relative bool
+	steps    []xpathStep
+}
+
+// xpathExpr is a predicate expression. position is the 1 based position of the node among the matched siblings
+type xpathExpr interface {
+	match(node *Node, position int) bool
+}
+
+type xpathAnd []xpathExpr
+type xpathOr []xpathExpr
+type xpathNot struct{ expr xpathExpr }
+type xpathPosition int
+type xpathAttr struct{ name string }
+
+type xpathCompare struct {
+	name     string
+	operator string
+	value    string
+}
+
+type xpathFunction struct {
+	name  string
+	attr  string
+	value string
+}
+
+func (e xpathAnd) match(node *Node, position int) bool {
+	for _, expr := range e {
+		if !expr.match(node, position) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e xpathOr) match(node *Node, position int) bool {
+	for _, expr := range e {
+		if expr.match(node, position) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e xpathNot) match(node *Node, position int) bool {
+	return !e.expr.match(node, position)
+}
+
+func (e xpathPosition) match(_ *Node, position int) bool {
+	return int(e) == position
+}
+
+func (e xpathAttr) match(node *Node, _ int) bool {
+	return node.Attr(e.name) != ""
+}
+
+func (e xpathCompare) match(node *Node, _ int) bool {
+	equal := node.Attr(e.name) == e.value
+	if e.operator == "!=" {
+		return !equal
+	}
+	return equal
+}
+
+func (e xpathFunction) match(node *Node, _ int) bool {
+	value := node.Attr(e.attr)
+	switch e.name {
+	case "contains":
+		return strings.Contains(value, e.value)
+	case "starts-with":
+		return strings.HasPrefix(value, e.value)
+	case "ends-with":
+		return strings.HasSuffix(value, e.value)
+	}
+	return false
+}
+
+type xpathParser struct {
+	expression string
+	tokens     []xpathToken
+	pos        int
+}
+
+func (p *xpathParser) peek() xpathToken {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return xpathToken{kind: "eof"}
+}
+
+func (p *xpathParser) next() xpathToken {
+	token := p.peek()
+	p.pos++
+	return token
+}
+
+func (p *xpathParser) expect(kind string) (xpathToken, error) {
+	token := p.next()
+	if token.kind != kind {
+		return token, p.errorf("expected %s, found %s", kind, token.kind)
+	}
+	return token, nil
+}
+
+func (p *xpathParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("invalid xpath %q: %s", p.expression, fmt.Sprintf(format, args...))
+}
+
+func compileXPath(expression string) (*xpathPath, error) {
+	tokens, err := lexXPath(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &xpathParser{expression: expression, tokens: tokens}
+	path := &xpathPath{}
+	if kind := p.peek().kind; kind != "/" && kind != "//" {
+		path.relative = true
+	}
+
+	for p.peek().kind != "eof" {
+		separator := xpathToken{kind: "/"}
+		if !path.relative || len(path.steps) > 0 {
+			separator = p.next()
+			if separator.kind != "/" && separator.kind != "//" {
+				return nil, p.errorf("expected / or //, found %s", separator.kind)
+			}
+		}
+
+		name, err := p.expect("name")
+		if err != nil {
+			return nil, err
+		}
+
+		step := xpathStep{descendant: separator.kind == "//", name: name.value}
+		for p.peek().kind == "[" {
+			p.next()
+			expr, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			step.predicates = append(step.predicates, expr)
+		}
+		path.steps = append(path.steps, step)
+	}
+
+	if len(path.steps) == 0 {
+		return nil, p.errorf("empty path")
+	}
+	return path, nil
+}
+
+func (p *xpathParser) parseOr() (xpathExpr, error) {
+	expr, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	or := xpathOr{expr}
+	for p.peek().kind == "name" && p.peek().value == "or" {
+		p.next()
+		if expr, err = p.parseAnd(); err != nil {
+			return nil, err
+		}
+		or = append(or, expr)
+	}
+
+	if len(or) == 1 {
+		return or[0], nil
+	}
+	return or, nil
+}
+
+func (p *xpathParser) parseAnd() (xpathExpr, error) {
+	expr, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	and := xpathAnd{expr}
+	for p.peek().kind == "name" && p.peek().value == "and" {
+		p.next()
+		if expr, err = p.parseUnary(); err != nil {
+			return nil, err
+		}
+		and = append(and, expr)
+	}
+
+	if len(and) == 1 {
+		return and[0], nil
+	}
+	return and, nil
+}
+
+func (p *xpathParser) parseUnary() (xpathExpr, error) {
+	token := p.next()
+	switch token.kind {
+	case "number":
+		position, _ := strconv.Atoi(token.value)
+		return xpathPosition(position), nil
+
+	case "(":
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		_, err = p.expect(")")
+		return expr, err
+
+	case "@":
+		name, err := p.expect("name")
+		if err != nil {
+			return nil, err
+		}
+		return p.parseComparison(name.value)
+
+	case "name":
+		if _, err := p.expect("("); err != nil {
+			return nil, err
+		}
+
+		switch token.value {
+		case "text":
+			if _, err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return p.parseComparison("text")
+
+		case "not":
+			expr, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			_, err = p.expect(")")
+			return xpathNot{expr}, err
+
+		case "contains", "starts-with", "ends-with":
+			attr, err := p.parseAttrArgument()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expect(","); err != nil {
+				return nil, err
+			}
+			value, err := p.expect("string")
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return xpathFunction{name: token.value, attr: attr, value: value.value}, nil
+		}
+		return nil, p.errorf("unsupported function: %s", token.value)
+	}
+	return nil, p.errorf("unexpected %s", token.kind)
+}
+
+// parseAttrArgument parses the first argument of the string functions: @name or text()
+func (p *xpathParser) parseAttrArgument() (string, error) {
+	token := p.next()
+	switch {
+	case token.kind == "@":
+		name, err := p.expect("name")
+		return name.value, err
+	case token.kind == "name" && token.value == "text":
+		if _, err := p.expect("("); err != nil {
+			return "", err
+		}
+		_, err := p.expect(")")
+		return "text", err
+	}
+	return "", p.errorf("expected an attribute, found %s", token.kind)
+}
+
+func (p *xpathParser) parseComparison(name string) (xpathExpr, error) {
+	operator := p.peek().kind
+	if operator != "=" && operator != "!=" {
+		return xpathAttr{name}, nil
+	}
+	p.next()
+
+	value := p.next()
+	if value.kind != "string" && value.kind != "number" {
+		return nil, p.errorf("expected a value, found %s", value.kind)
+	}
+	return xpathCompare{name: name, operator: operator, value: value.value}, nil
+}
+
+// endregion Parser
+
+// region Evaluation
+
+func (s xpathStep) matchName(node *Node) bool {
+	switch s.name {
+	case "*":
+		return true
+	case "node":
+		return node.Parent != nil
+	}
+	return node.Class == s.name
+}
+
+// candidates returns the nodes selected by the step axis from the context node, grouped by parent
+// so that the positions are relative to the siblings
+func (s xpathStep) candidates(context *Node) [][]*Node {
+	switch s.name {
+	case ".":
+		return [][]*Node{{context}}
+	case "..":
+		if context.Parent == nil {
+			return nil
+		}
+		return [][]*Node{{context.Parent}}
+	}
+
+	if !s.descendant {
+		return [][]*Node{context.Children}
+	}
+
+	var groups [][]*Node
+	context.Walk(func(node *Node) bool {
+		if len(node.Children) > 0 {
+			groups = append(groups, node.Children)
+		}
+		return true
+	})
+	return groups
+}
+
+func (s xpathStep) evaluate(context *Node) []*Node {
+	var result []*Node
+	for _, group := range s.candidates(context) {
+		var matched []*Node
+		for _, node := range group {
+			if s.name == "." || s.name == ".." || s.matchName(node) {
+				matched = append(matched, node)
+			}
+		}
+
+		for _, predicate := range s.predicates {
+			var filtered []*Node
+			for index, node := range matched {
+				if predicate.match(node, index+1) {
+					filtered = append(filtered, node)
+				}
+			}
+			matched = filtered
+		}
+		result = append(result, matched...)
+	}
+	return result
+}
+
+// evaluate returns the nodes selected from the hierarchy root, in document order
+func (p xpathPath) evaluate(root *Node) []*Node {
+	// the root is the document, its only child is the hierarchy element
+	document := &Node{Children: []*Node{root}}
+	context := []*Node{document}
+	if p.relative {
+		context = []*Node{root}
+	}
+
+	for _, step := range p.steps {
+		var next []*Node
+		seen := map[*Node]bool{}
+		for _, node := range context {
+			for _, selected := range step.evaluate(node) {
+				if !seen[selected] && selected != document {
+					seen[selected] = true
+					next = append(next, selected)
+				}
+			}
+		}
+		context = next
+	}
+
+	selected := map[*Node]bool{}
+	for _, node := range context {
+		selected[node] = true
+	}
+
+	var result []*Node
+	root.Walk(func(node *Node) bool {
+		if selected[node] {
+			result = append(result, node)
+		}
+		return true
+	})
+	return result
+}
+
+// endregion Evaluation
+
+// XPath returns the nodes selected by the xpath expression, in document order.
+// The expression is evaluated on the xml dumped by uiautomator, where the nodes are "node" elements.
+// As a shortcut, the element name can also be the node class (e.g. //android.widget.Button)
+func (h Hierarchy) XPath(expression string) ([]*Node, error) {
+	path, err := compileXPath(expression)
+	if err != nil {
+		return nil, err
+	}
+	return path.evaluate(h.Root), nil
+}
@@ -0,0 +1,302 @@
+package usermanager
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sephiroth74/go_adb_client/process"
+	"github.com/sephiroth74/go_adb_client/shell"
+	"github.com/sephiroth74/go_adb_client/types"
+)
+
+var (
+	userInfoRegexp    = regexp.MustCompile(`UserInfo\{(\d+):(.*):([0-9a-fA-F]+)\}(\s+running)?`)
+	createdUserRegexp = regexp.MustCompile(`created user id (\d+)`)
+	maxUsersRegexp    = regexp.MustCompile(`Maximum supported users:\s*(\d+)`)
+)
+
+// region UserFlags
+
+// UserFlags are the flags of an android user (UserInfo.FLAG_*)
+type UserFlags int
+
+const (
+	UserFlagPrimary           UserFlags = 0x00000001
+	UserFlagAdmin             UserFlags = 0x00000002
+	UserFlagGuest             UserFlags = 0x00000004
+	UserFlagRestricted        UserFlags = 0x00000008
+	UserFlagInitialized       UserFlags = 0x00000010
+	UserFlagManagedProfile    UserFlags = 0x00000020
+	UserFlagDisabled          UserFlags = 0x00000040
+	UserFlagQuietMode         UserFlags = 0x00000080
+	UserFlagEphemeral         UserFlags = 0x00000100
+	UserFlagDemo              UserFlags = 0x00000200
+	UserFlagFull              UserFlags = 0x00000400
+	UserFlagSystem            UserFlags = 0x00000800
+	UserFlagProfile           UserFlags = 0x00001000
+	UserFlagEphemeralOnCreate UserFlags = 0x00002000
+	UserFlagMain              UserFlags = 0x00004000
+	UserFlagForTesting        UserFlags = 0x00008000
+)
+
+var userFlagNames = []struct {
+	flag UserFlags
+	name string
+}{
+	{UserFlagPrimary, "PRIMARY"},
+	{UserFlagAdmin, "ADMIN"},
+	{UserFlagGuest, "GUEST"},
+	{UserFlagRestricted, "RESTRICTED"},
+	{UserFlagInitialized, "INITIALIZED"},
+	{UserFlagManagedProfile, "MANAGED_PROFILE"},
+	{UserFlagDisabled, "DISABLED"},
+	{UserFlagQuietMode, "QUIET_MODE"},
+	{UserFlagEphemeral, "EPHEMERAL"},
+	{UserFlagDemo, "DEMO"},
+	{UserFlagFull, "FULL"},
+	{UserFlagSystem, "SYSTEM"},
+	{UserFlagProfile, "PROFILE"},
+	{UserFlagEphemeralOnCreate, "EPHEMERAL_ON_CREATE"},
+	{UserFlagMain, "MAIN"},
+	{UserFlagForTesting, "FOR_TESTING"},
+}
+
+// Has returns true if all the given flags are set
+func (f UserFlags) Has(flag UserFlags) bool {
+	return f&flag == flag
+}
+
+func (f UserFlags) String() string {
+	var names []string
+	for _, v := range userFlagNames {
+		if f.Has(v.flag) {
+			names = append(names, v.name)
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+// endregion UserFlags
+
+// region UserInfo
+
+// UserInfo is a user of the device, as reported by "pm list users"
+type UserInfo struct {
+	Id      types.UserId
+	Name    string
+	Flags   UserFlags
+	Running bool
+}
+
+func (u UserInfo) String() string {
+	return fmt.Sprintf("UserInfo{Id:%s, Name:%s, Flags:%s, Running:%t}", u.Id, u.Name, u.Flags, u.Running)
+}
+
+func (u UserInfo) IsGuest() bool {
+	return u.Flags.Has(UserFlagGuest)
+}
+
+func (u UserInfo) IsAdmin() bool {
+	return u.Flags.Has(UserFlagAdmin)
+}
+
+func (u UserInfo) IsManagedProfile() bool {
+	return u.Flags.Has(UserFlagManagedProfile)
+}
+
+func (u UserInfo) IsEphemeral() bool {
+	return u.Flags.Has(UserFlagEphemeral)
+}
+
+// ParseUsers parses the output of "pm list users"
+func ParseUsers(data string) []UserInfo {
+	var users []UserInfo
+	for _, m := range userInfoRegexp.FindAllStringSubmatch(data, -1) {
+		flags, _ := strconv.ParseInt(m[3], 16, 64)
+		users = append(users, UserInfo{
+			Id:      types.UserId(m[1]),
+			Name:    m[2],
+			Flags:   UserFlags(flags),
+			Running: m[4] != "",
+		})
+	}
+	return users
+}
+
+// endregion UserInfo
+
+// region CreateUserOptions
+
+type CreateUserOptions struct {
+	// --profileOf: create a profile of the given user
+	ProfileOf types.UserId
+	// --managed: create a managed (work) profile, requires ProfileOf
+	Managed bool
+	// --restricted: create a restricted profile
+	Restricted bool
+	// --guest: create a guest user
+	Guest bool
+	// --demo: create a demo user
+	Demo bool
+	// --ephemeral: the user is removed when it is stopped
+	Ephemeral bool
+	// --for-testing
+	ForTesting bool
+	// --user-type: the user type, e.g. android.os.usertype.full.SECONDARY
+	UserType string
+}
+
+func (o *CreateUserOptions) args() []string {
+	var args []string
+	if o != nil {
+		if o.ProfileOf.IsSet() {
+			args = append(args, "--profileOf", string(o.ProfileOf))
+		}
+		if o.Managed {
+			args = append(args, "--managed")
+		}
+		if o.Restricted {
+			args = append(args, "--restricted")
+		}
+		if o.Guest {
+			args = append(args, "--guest")
+		}
+		if o.Demo {
+			args = append(args, "--demo")
+		}
+		if o.Ephemeral {
+			args = append(args, "--ephemeral")
+		}
+		if o.ForTesting {
+			args = append(args, "--for-testing")
+		}
+		if o.UserType != "" {
+			args = append(args, "--user-type", o.UserType)
+		}
+	}
+	return args
+}
+
+// endregion CreateUserOptions
+
+type UserManager struct {
+	Shell *shell.Shell
+}
+
+// ListUsers returns the users of the device with "pm list users"
+func (u UserManager) ListUsers() ([]UserInfo, error) {
+	result, err := u.execute("pm", "list", "users")
+	if err != nil {
+		return nil, err
+	}
+	return ParseUsers(result.Output()), nil
+}
+
+// GetUser returns the user with the given id, nil if not found
+func (u UserManager) GetUser(user types.UserId) (*UserInfo, error) {
+	users, err := u.ListUsers()
+	if err != nil {
+		return nil, err
+	}
+	for i := range users {
+		if users[i].Id == user {
+			return &users[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// GetCurrentUser returns the foreground user with "am get-current-user"
+func (u UserManager) GetCurrentUser() (types.UserId, error) {
+	result, err := u.execute("am", "get-current-user")
+	if err != nil {
+		return "", err
+	}
+
+	id, err := strconv.Atoi(result.Output())
+	if err != nil {
+		return "", fmt.Errorf("invalid user id: %s", result.Output())
+	}
+	return types.NewUserId(id), nil
+}
+
+// GetMaxUsers returns the maximum number of users supported with "pm get-max-users"
+func (u UserManager) GetMaxUsers() (int, error) {
+	result, err := u.execute("pm", "get-max-users")
+	if err != nil {
+		return 0, err
+	}
+
+	m := maxUsersRegexp.FindStringSubmatch(result.Output())
+	if m == nil {
+		return 0, fmt.Errorf("unexpected output: %s", result.Output())
+	}
+	return strconv.Atoi(m[1])
+}
+
+// CreateUser creates a new user with "pm create-user" and returns its id
+func (u UserManager) CreateUser(name string, options *CreateUserOptions) (types.UserId, error) {
+	args := append([]string{"pm", "create-user"}, options.args()...)
+	result, err := u.execute(append(args, types.ShellQuote(name))...)
+	if err != nil {
+		return "", err
+	}
+
+	m := createdUserRegexp.FindStringSubmatch(result.Output())
+	if m == nil {
+		return "", fmt.Errorf("unable to create user: %s", result.Output())
+	}
+	return types.UserId(m[1]), nil
+}
+
+// CreateWorkProfile creates a managed profile of the given parent user
+func (u UserManager) CreateWorkProfile(name string, parent types.UserId) (types.UserId, error) {
+	return u.CreateUser(name, &CreateUserOptions{ProfileOf: parent, Managed: true})
+}
+
+// CreateGuest creates a guest user
+func (u UserManager) CreateGuest(name string, ephemeral bool) (types.UserId, error) {
+	return u.CreateUser(name, &CreateUserOptions{Guest: true, Ephemeral: ephemeral})
+}
+
+// RemoveUser removes the user with "pm remove-user"
+func (u UserManager) RemoveUser(user types.UserId) error {
+	_, err := u.execute("pm", "remove-user", string(user))
+	return err
+}
+
+// SwitchUser switches to the given user with "am switch-user"
+func (u UserManager) SwitchUser(user types.UserId) error {
+	_, err := u.execute("am", "switch-user", string(user))
+	return err
+}
+
+// StartUser starts the user in background with "am start-user". Profiles must be started to be used
+func (u UserManager) StartUser(user types.UserId, wait bool) error {
+	args := []string{"am", "start-user"}
+	if wait {
+		args = append(args, "-w")
+	}
+	_, err := u.execute(append(args, string(user))...)
+	return err
+}
+
+// StopUser stops the user with "am stop-user". The current user cannot be stopped
+func (u UserManager) StopUser(user types.UserId, wait bool, force bool) error {
+	args := []string{"am", "stop-user"}
+	if wait {
+		args = append(args, "-w")
+	}
+	if force {
+		args = append(args, "-f")
+	}
+	_, err := u.execute(append(args, string(user))...)
+	return err
+}
+
+// execute runs the command and returns an error if it fails or its output reports an error
+func (u UserManager) execute(args ...string) (process.OutputResult, error) {
+	return process.CheckedOutput(u.Shell.NewCommand().WithArgs(args...), u.Shell.Conn.Verbose)
+}